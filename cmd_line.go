@@ -0,0 +1,240 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/config"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/expr"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+// --------------------------------
+type command struct {
+	Family        string
+	Names         []string
+	Registrations []traverser.Registration
+	Opts          traverser.Options
+	Addr          string
+}
+
+var errNoCommand = errors.New("no command given")
+
+// parseArgs parses a command line of the form <family> [flags] <traverser>... where
+// flags may appear anywhere after the family. It returns flag.ErrHelp if help was asked for.
+func parseArgs(args []string) (*command, error) {
+	if len(args) == 0 {
+		return nil, errNoCommand
+	}
+
+	cmd := &command{Family: args[0]}
+	switch cmd.Family {
+	case "help", "-h", "-help", "--help":
+		return nil, flag.ErrHelp
+	case "list":
+		return cmd, nil
+	case "serve":
+		fs := newFlagSet(cmd.Family, &cmd.Opts)
+		fs.StringVar(&cmd.Addr, "addr", DefaultAddr, "the address to serve on")
+		if err := fs.Parse(args[1:]); err != nil {
+			return nil, err
+		}
+		if fs.NArg() > 0 {
+			return nil, fmt.Errorf("serve: unexpected argument %q (traversers are chosen by each request)", fs.Arg(0))
+		}
+		if err := validateOptions(&cmd.Opts); err != nil {
+			return nil, fmt.Errorf("serve: %w", err)
+		}
+		return cmd, nil
+	}
+	if _, ok := traverser.LookupFamily(cmd.Family); !ok {
+		return nil, fmt.Errorf("unknown command %q", cmd.Family)
+	}
+
+	fs := newFlagSet(cmd.Family, &cmd.Opts)
+	rest := args[1:]
+	for {
+		if err := fs.Parse(rest); err != nil {
+			return nil, err
+		}
+		rest = fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		cmd.Names = append(cmd.Names, rest[0])
+		rest = rest[1:]
+	}
+
+	if len(cmd.Names) == 0 {
+		return nil, fmt.Errorf("%s: no traversers given", cmd.Family)
+	}
+	regs, err := traverser.Resolve(cmd.Family, cmd.Names)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Registrations = regs
+
+	if err := validateOptions(&cmd.Opts); err != nil {
+		return nil, fmt.Errorf("%s: %w", cmd.Family, err)
+	}
+	if fam, _ := traverser.LookupFamily(cmd.Family); fam.Consumes == reflect.TypeFor[traverser.Sample]() {
+		switch {
+		case cmd.Opts.Period == "blockly":
+			return nil, fmt.Errorf("%s: samples cannot be bucketed by block (use hourly or longer periods)", cmd.Family)
+		case cmd.Opts.Period != "" && cmd.Opts.Of == "receipts":
+			return nil, fmt.Errorf("%s: receipts have no date to bucket by period", cmd.Family)
+		}
+	}
+	return cmd, nil
+}
+
+func newFlagSet(name string, opts *traverser.Options) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.Period, "period", "", "the period to summarize by (blockly, hourly, daily, weekly, monthly, quarterly, annually), for stats one row per period")
+	fs.StringVar(&opts.Denom, "denom", "", "the denomination of amounts (units, usd, wei)")
+	fs.StringVar(&opts.ConfigPath, "config", "", "the config file to read (default "+config.DefaultPath+" if it exists)")
+	fs.StringVar(&opts.Chain, "chain", "", "the chain of the accounts that do not name one (default mainnet)")
+	fs.StringVar(&opts.Source, "source", "sdk", "where to read records from ("+strings.Join(traverser.Sources, ", ")+")")
+	fs.StringVar(&opts.InputPath, "input", ".", "the folder holding exported records for the json and csv sources")
+	fs.Uint64Var(&opts.Seed, "seed", 1, "the seed of the histories made up by the synthetic source")
+	fs.StringVar(&opts.OutputPath, "output", "", "write results to this file instead of stdout")
+	fs.StringVar(&opts.Format, "format", "", "the format of the results, one of "+strings.Join(report.Formats, ", ")+" (default csv)")
+	fs.StringVar(&opts.AddressesPath, "addresses", "", "without a config file, the file listing the accounts to process (default addresses.csv)")
+	fs.StringVar(&opts.FiltersPath, "filters", "", "without a config file, the file listing address and date filters (default filters.csv)")
+	fs.StringVar(&opts.Where, "where", "", "only process records satisfying this expression (see README.md)")
+	fs.StringVar(&opts.Field, "field", traverser.DefaultField, "for stats, the numeric field or expression to sample (see README.md)")
+	fs.StringVar(&opts.Of, "of", "statements", "for stats, the records to sample ("+strings.Join(traverser.SampledRecords, ", ")+")")
+	fs.BoolVar(&opts.PerTx, "per-tx", false, "for stats, sum the samples of each transaction (with --field 1, count its records)")
+	fs.StringVar(&opts.GroupBy, "group-by", "", "for stats, report each group separately ("+strings.Join(traverser.GroupBys, ", ")+")")
+	fs.IntVar(&opts.Bins, "bins", traverser.DefaultBins, "for stats, the number of bins of a histogram")
+	fs.Func("tags", "comma separated account tags to process (default "+strings.Join(traverser.DefaultTags, ",")+")", func(v string) error {
+		opts.Tags = strings.Split(v, ",")
+		return nil
+	})
+	fs.StringVar(&opts.Checkpoint, "checkpoint", "", "resume from the state saved in this file, and save the state there for the next run")
+	fs.BoolVar(&opts.FailFast, "fail-fast", false, "stop at the first error of a traverser instead of reporting the errors with the results")
+	fs.DurationVar(&opts.Watch, "watch", 0, "keep running, and this often (as in 10m) traverse the new records of the accounts and rewrite the output")
+	fs.BoolVar(&opts.Parallel, "parallel", false, "run each traverser on its own goroutine")
+	fs.Var((*countFlag)(&opts.Verbose), "verbose", "increase the detail of reports (may be repeated)")
+	fs.BoolFunc("nocolor", "turn off colored output", func(string) error {
+		colors.ColorsOff()
+		return nil
+	})
+	return fs
+}
+
+func validateOptions(opts *traverser.Options) error {
+	if opts.Period != "" && !base.IsValidPeriod(opts.Period) {
+		return fmt.Errorf("invalid period %q", opts.Period)
+	}
+	switch opts.Denom {
+	case "", "units", "usd", "wei":
+	default:
+		return fmt.Errorf("invalid denom %q (one of units, usd, wei)", opts.Denom)
+	}
+	if opts.ConfigPath != "" && (opts.AddressesPath != "" || opts.FiltersPath != "") {
+		return errors.New("--addresses and --filters cannot be used with --config (use the import section of the config file)")
+	}
+	if opts.Where != "" {
+		if _, err := expr.Compile(opts.Where, nil); err != nil {
+			return fmt.Errorf("invalid expression %q: %w", opts.Where, err)
+		}
+	}
+	if _, err := expr.Compile(opts.Field, nil); err != nil {
+		return fmt.Errorf("invalid field %q: %w", opts.Field, err)
+	}
+	if !slices.Contains(traverser.SampledRecords, opts.Of) {
+		return fmt.Errorf("invalid --of %q (one of %s)", opts.Of, strings.Join(traverser.SampledRecords, ", "))
+	}
+	if opts.GroupBy != "" && !slices.Contains(traverser.GroupBys, opts.GroupBy) {
+		return fmt.Errorf("invalid --group-by %q (one of %s)", opts.GroupBy, strings.Join(traverser.GroupBys, ", "))
+	}
+	if opts.GroupBy == "account" && opts.Of != "statements" {
+		return fmt.Errorf("%s cannot be grouped by account (only statements can)", opts.Of)
+	}
+	if opts.Watch < 0 {
+		return fmt.Errorf("invalid --watch %s (a positive interval)", opts.Watch)
+	}
+	if opts.Bins < 1 {
+		return fmt.Errorf("invalid --bins %d (at least 1)", opts.Bins)
+	}
+	if !slices.Contains(traverser.Sources, opts.Source) {
+		return fmt.Errorf("invalid source %q (one of %s)", opts.Source, strings.Join(traverser.Sources, ", "))
+	}
+	if opts.Format != "" && !slices.Contains(report.Formats, opts.Format) {
+		return fmt.Errorf("invalid format %q (one of %s)", opts.Format, strings.Join(report.Formats, ", "))
+	}
+	return nil
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  accounting <command> [flags] <traverser|group>...")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, fam := range traverser.Families() {
+		fmt.Fprintf(w, "  %-8s %s\n", fam.Name, fam.Description)
+	}
+	fmt.Fprintf(w, "  %-8s %s\n", "list", "describe every available traverser")
+	fmt.Fprintf(w, "  %-8s %s\n", "serve", "serve the results of traversers over HTTP on --addr (default "+DefaultAddr+")")
+	fmt.Fprintf(w, "  %-8s %s\n", "help", "show this help")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Traversers:")
+	for _, fam := range traverser.Families() {
+		names := []string{}
+		for _, r := range traverser.Registrations(fam.Name) {
+			names = append(names, r.Name)
+		}
+		fmt.Fprintf(w, "  %-8s %s\n", fam.Name, strings.Join(names, ", "))
+		groups, members := traverser.Groups(fam.Name)
+		for _, g := range groups {
+			fmt.Fprintf(w, "  %-8s   %s = %s\n", "", g, strings.Join(members[g], ", "))
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	fs := newFlagSet("help", &traverser.Options{})
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// list describes every registered traverser, one per line.
+func list(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Family\tName\tAliases\tGroups\tConsumes\tDescription")
+	for _, r := range traverser.Registrations("") {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Family, r.Name, strings.Join(r.Aliases, ","), strings.Join(r.Groups, ","), r.Consumes, r.Description)
+	}
+	tw.Flush()
+}
+
+// --------------------------------
+// countFlag is a boolean flag that counts how many times it appears.
+type countFlag int
+
+func (c *countFlag) String() string {
+	if c == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*c))
+}
+
+func (c *countFlag) Set(string) error {
+	*c++
+	return nil
+}
+
+func (c *countFlag) IsBoolFlag() bool {
+	return true
+}
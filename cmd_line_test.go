@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+func TestParseArgs(t *testing.T) {
+	cmd, err := parseArgs([]string{"recons", "by_asset", "--denom", "usd", "senders", "--verbose", "--verbose", "--period=monthly"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Family != "recons" {
+		t.Errorf("Family differs: got %q, want %q", cmd.Family, "recons")
+	}
+	if !slices.Equal(cmd.Names, []string{"by_asset", "senders"}) {
+		t.Errorf("Names differ: got %v", cmd.Names)
+	}
+	if cmd.Opts.Denom != "usd" || cmd.Opts.Period != "monthly" || cmd.Opts.Verbose != 2 {
+		t.Errorf("Options differ: got %q %q %d", cmd.Opts.Denom, cmd.Opts.Period, cmd.Opts.Verbose)
+	}
+	if len(cmd.Opts.Tags) != 0 {
+		t.Errorf("Tags differ: got %v, want none so the config file or defaults apply", cmd.Opts.Tags)
+	}
+}
+
+func TestParseArgsAliasesAndGroups(t *testing.T) {
+	cmd, err := parseArgs([]string{"recons", "accounting.counter", "by_address", "senders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, r := range cmd.Registrations {
+		got = append(got, r.Name)
+	}
+	if want := []string{"counter", "pairings", "recipients", "senders"}; !slices.Equal(got, want) {
+		t.Errorf("Registrations differ: got %v, want %v", got, want)
+	}
+}
+
+func TestParseArgsTags(t *testing.T) {
+	cmd, err := parseArgs([]string{"logs", "counters", "--tags", "00-Active,19-Dead"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cmd.Opts.Tags, []string{"00-Active", "19-Dead"}) {
+		t.Errorf("Tags differ: got %v", cmd.Opts.Tags)
+	}
+}
+
+func TestParseArgsMisuse(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"NoCommand", []string{}, "no command"},
+		{"UnknownCommand", []string{"recon", "by_asset"}, "unknown command"},
+		{"NoTraversers", []string{"stats"}, "no traversers"},
+		{"UnknownTraverser", []string{"recons", "by_assets"}, "unknown recons traverser"},
+		{"WrongFamily", []string{"stats", "by_asset"}, "unknown stats traverser"},
+		{"UnknownFlag", []string{"recons", "by_asset", "--denomination", "usd"}, "flag provided but not defined"},
+		{"BadDenom", []string{"recons", "by_asset", "--denom", "eur"}, "invalid denom"},
+		{"BadPeriod", []string{"recons", "by_asset", "--period", "fortnightly"}, "invalid period"},
+		{"BadSource", []string{"recons", "by_asset", "--source", "ftp"}, "invalid source"},
+		{"BadFormat", []string{"recons", "by_asset", "--format", "xml"}, "invalid format"},
+		{"ConfigAndCsv", []string{"recons", "by_asset", "--config", "a.yaml", "--addresses", "a.csv"}, "cannot be used with --config"},
+		{"BadWhere", []string{"recons", "by_asset", "--where", "symbol = \"USDC\""}, "invalid expression"},
+		{"BadField", []string{"stats", "average", "--field", "usd(amountOut"}, "invalid field"},
+		{"BadOf", []string{"stats", "average", "--of", "traces"}, "invalid --of"},
+		{"BadGroupBy", []string{"stats", "average", "--group-by", "symbol"}, "invalid --group-by"},
+		{"BadBins", []string{"stats", "histogram", "--bins", "0"}, "invalid --bins"},
+		{"BadWatch", []string{"recons", "counter", "--watch", "-1m"}, "invalid --watch"},
+		{"ServeTraverser", []string{"serve", "counter"}, "unexpected argument"},
+		{"BlocklyStats", []string{"stats", "counter", "--period", "blockly"}, "cannot be bucketed by block"},
+		{"ReceiptsByPeriod", []string{"stats", "counter", "--of", "receipts", "--period", "monthly"}, "receipts have no date"},
+		{"LogsByAccount", []string{"stats", "average", "--of", "logs", "--group-by", "account"}, "cannot be grouped by account"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseArgs(tt.args)
+			if err == nil {
+				t.Fatalf("expected an error containing %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Error differs: got %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseArgsHelp(t *testing.T) {
+	for _, args := range [][]string{{"help"}, {"--help"}, {"recons", "-h"}} {
+		if _, err := parseArgs(args); !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("parseArgs(%v) failed: got %v, want flag.ErrHelp", args, err)
+		}
+	}
+}
+
+func TestUsageListsTraversers(t *testing.T) {
+	var buf bytes.Buffer
+	usage(&buf)
+	for _, r := range traverser.Registrations("") {
+		if !strings.Contains(buf.String(), r.Name) {
+			t.Errorf("usage does not mention %s %s", r.Family, r.Name)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+	"github.com/xuri/excelize/v2"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// TestGolden runs every registered traverser, and a few combinations of options, over the
+// records in testdata/input for the accounts of testdata/config.yaml, and compares each
+// report with its golden file. Run `go test -run Golden -update` to rewrite the golden
+// files after an intended change, and review the difference before committing it.
+func TestGolden(t *testing.T) {
+	for _, r := range traverser.Registrations("") {
+		t.Run(r.Family+"/"+r.Name, func(t *testing.T) {
+			golden(t, filepath.Join(r.Family, r.Name), r.Family, r.Name)
+		})
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"recons/statements_usd", []string{"recons", "statements", "--denom", "usd"}},
+		{"recons/profit_and_loss_monthly", []string{"recons", "profit_and_loss", "--period", "monthly"}},
+		{"recons/where", []string{"recons", "by_asset", "pairings", "--where", `usd(amountOut) > 1000 && named(recipient)`}},
+		{"recons/ofx_units", []string{"recons", "ofx", "--denom", "units"}},
+		{"recons/statements_where", []string{"recons", "statements", "by_asset", "--where", `symbol == "USDC"`}},
+		{"recons/active", []string{"recons", "counter", "by_asset", "--tags", "00-Active"}},
+		{"logs/transfers", []string{"logs", "contract_first", "--where", `event == "Transfer"`}},
+		{"stats/usd_out_by_account", []string{"stats", "counter", "total", "max", "--field", "usd(amountOut)", "--where", "amountOut > 0", "--group-by", "account", "--period", "monthly"}},
+		{"stats/usd_out_by_entity", []string{"stats", "counter", "total", "--field", "usd(amountOut)", "--where", "amountOut > 0", "--group-by", "entity"}},
+		{"stats/logs_per_tx", []string{"stats", "average", "max", "--of", "logs", "--field", "1", "--per-tx"}},
+		{"stats/gas_by_asset", []string{"stats", "quantiles", "histogram", "--of", "transactions", "--field", "gasUsed", "--group-by", "asset", "--bins", "3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			golden(t, tt.name, tt.args...)
+		})
+	}
+}
+
+// golden runs the command over the fixtures in a temporary folder and compares its report
+// (followed by the sheets of the workbook, the tables of the database and the OFX file, if
+// written) with testdata/golden/<name>.csv.
+func golden(t *testing.T, name string, args ...string) {
+	t.Helper()
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(testdata, "golden", name+".csv")
+
+	withoutNames(t)
+
+	// Some traversers write files to the working folder
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	cmd, err := parseArgs(append(args,
+		"--config", filepath.Join(testdata, "config.yaml"),
+		"--source", "json",
+		"--input", filepath.Join(testdata, "input"),
+		"--output", "report.csv",
+		"--nocolor",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := processData(cmd); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("report.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sheets, err := workbookCsv("Book1.xlsx"); err != nil {
+		t.Fatal(err)
+	} else {
+		got = append(got, sheets...)
+	}
+	if tables, err := databaseCsv("Ledger.sqlite"); err != nil {
+		t.Fatal(err)
+	} else {
+		got = append(got, tables...)
+	}
+	if ofx, err := os.ReadFile("Ledger.ofx"); err == nil {
+		got = append(got, "\n# Ledger.ofx\n"...)
+		got = append(got, ofx...)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(want), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(want, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(want)
+	if err != nil {
+		t.Fatalf("%v (run with -update to create it)", err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("report differs from %s (run with -update to accept it):\n%s", want, diff(string(expected), string(got)))
+	}
+}
+
+// withoutNames stubs out the names database for the rest of the test. The database
+// differs from one machine to the next, so only the accounts are named.
+func withoutNames(t *testing.T) {
+	database := traverser.NamesDatabase
+	traverser.NamesDatabase = func(chain string) (map[base.Address]types.Name, error) {
+		return map[base.Address]types.Name{}, nil
+	}
+	t.Cleanup(func() { traverser.NamesDatabase = database })
+}
+
+// workbookCsv renders every sheet of a workbook as CSV, in sheet name order, or returns
+// nothing if there is no workbook.
+func workbookCsv(path string) ([]byte, error) {
+	f, err := excelize.OpenFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	for _, sheet := range slices.Sorted(slices.Values(f.GetSheetList())) {
+		rows, err := f.GetRows(sheet)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString("\n# " + sheet + "\n")
+		w := csv.NewWriter(&buf)
+		if err := w.WriteAll(rows); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// databaseCsv renders every table of a database as CSV, in the order of their keys, or
+// returns nothing if there is no database. The times at which runs started and finished
+// are left out.
+func databaseCsv(path string) ([]byte, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var buf bytes.Buffer
+	for _, table := range []struct{ name, query string }{
+		{"runs", "SELECT id, chain, options, statements, logs FROM runs ORDER BY id"},
+		{"accounts", "SELECT * FROM accounts ORDER BY address"},
+		{"assets", "SELECT * FROM assets ORDER BY address"},
+		{"names", "SELECT * FROM names ORDER BY address"},
+		{"statements", "SELECT * FROM statements ORDER BY block_number, transaction_index, log_index, accounted_for, asset"},
+		{"logs", "SELECT * FROM logs ORDER BY block_number, transaction_index, log_index"},
+	} {
+		rows, err := db.Query(table.query)
+		if err != nil {
+			return nil, err
+		}
+		columns, _ := rows.Columns()
+		records := [][]string{columns}
+		for rows.Next() {
+			values := make([]sql.NullString, len(columns))
+			ptrs := make([]any, len(columns))
+			for i := range values {
+				ptrs[i] = &values[i]
+			}
+			if err := rows.Scan(ptrs...); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			record := make([]string, len(columns))
+			for i, v := range values {
+				record[i] = v.String
+			}
+			records = append(records, record)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\n# " + table.name + "\n")
+		w := csv.NewWriter(&buf)
+		if err := w.WriteAll(records); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// diff returns the first differing line of two texts, with its line number.
+func diff(want, got string) string {
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < max(len(wantLines), len(gotLines)); i++ {
+		w, g := "", ""
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return "line " + strconv.Itoa(i+1) + ":\n want " + w + "\n  got " + g
+		}
+	}
+	return ""
+}
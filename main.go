@@ -1,6 +1,53 @@
 package main
 
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+	_ "github.com/TrueBlocks/trueblocks-traversers/pkg/traverser/accounting"
+	_ "github.com/TrueBlocks/trueblocks-traversers/pkg/traverser/appearances"
+	_ "github.com/TrueBlocks/trueblocks-traversers/pkg/traverser/logs"
+	_ "github.com/TrueBlocks/trueblocks-traversers/pkg/traverser/receipts"
+	_ "github.com/TrueBlocks/trueblocks-traversers/pkg/traverser/stats"
+	_ "github.com/TrueBlocks/trueblocks-traversers/pkg/traverser/traces"
+	_ "github.com/TrueBlocks/trueblocks-traversers/pkg/traverser/transactions"
+)
+
 // --------------------------------
 func main() {
-	processData()
+	cmd, err := parseArgs(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		usage(os.Stdout)
+		return
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		fmt.Fprintln(os.Stderr, "Run 'accounting help' for usage.")
+		os.Exit(2)
+	}
+
+	if cmd.Family == "list" {
+		list(os.Stdout)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	switch {
+	case cmd.Family == "serve":
+		err = serve(ctx, cmd)
+	case cmd.Opts.Watch > 0:
+		err = watch(ctx, cmd, realClock{}, traverser.NewSource)
+	default:
+		err = processData(cmd)
+	}
+	if err != nil {
+		log.Println("Error:", err)
+		os.Exit(1)
+	}
 }
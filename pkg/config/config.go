@@ -0,0 +1,472 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/expr"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the version of the config file format understood by this program.
+const Version = 1
+
+// DefaultPath is the config file read from the working folder when none is named.
+const DefaultPath = "traversers.yaml"
+
+// --------------------------------
+// Config is the contents of a config file. See README.md for an example.
+type Config struct {
+	Version  int       `yaml:"version"`
+	Chain    string    `yaml:"chain"`
+	Period   string    `yaml:"period"`
+	Denom    string    `yaml:"denom"`
+	Tags     []string  `yaml:"tags"`
+	Accounts []Account `yaml:"accounts"`
+	Filters  Filters   `yaml:"filters"`
+	Output   Output    `yaml:"output"`
+	Import   Import    `yaml:"import"`
+	Path     string    `yaml:"-"`
+}
+
+// --------------------------------
+// Account is one address of interest. Accounts sharing an entity are reported together
+// by traversers that group by entity. An account is on the config's chain unless it names
+// another, and may be listed once for each chain it is used on.
+type Account struct {
+	Address string `yaml:"address"`
+	Name    string `yaml:"name"`
+	Tags    string `yaml:"tags"`
+	Entity  string `yaml:"entity"`
+	Chain   string `yaml:"chain"`
+	File    string `yaml:"-"`
+	Line    int    `yaml:"-"`
+}
+
+// --------------------------------
+// Filters limits the records to the listed assets, accounts and counterparties, and to
+// the dates and blocks in the given (inclusive) ranges, and to those satisfying the where
+// expression. Empty lists and zero values do not filter.
+type Filters struct {
+	Assets         []string `yaml:"assets"`
+	Accounts       []string `yaml:"accounts"`
+	Counterparties []string `yaml:"counterparties"`
+	From           string   `yaml:"from"`
+	To             string   `yaml:"to"`
+	FirstBlock     uint64   `yaml:"firstBlock"`
+	LastBlock      uint64   `yaml:"lastBlock"`
+	Where          string   `yaml:"where"`
+}
+
+// --------------------------------
+type Output struct {
+	Path   string `yaml:"path"`
+	Format string `yaml:"format"`
+}
+
+// --------------------------------
+// Import names the older addresses.csv and filters.csv files to merge into the config.
+type Import struct {
+	Addresses string `yaml:"addresses"`
+	Filters   string `yaml:"filters"`
+}
+
+// --------------------------------
+// Error is a single problem found in a config or imported file.
+type Error struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+	}
+	return e.File + ": " + e.Msg
+}
+
+// Errors is every problem found while loading a config, in the order they were found.
+type Errors []*Error
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e Errors) orNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Load reads and validates a config file, including any files it imports.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Parse validates the contents of a config file. Every problem is reported, not just the
+// first, each with the file and line it was found on.
+func Parse(path string, data []byte) (*Config, error) {
+	p := parser{file: path, lines: map[string]int{}}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		p.yamlError(err)
+		return nil, p.errs
+	}
+	if len(root.Content) == 0 {
+		p.errorf("", "the file is empty")
+		return nil, p.errs
+	}
+
+	doc := root.Content[0]
+	p.walk(doc, "", reflect.TypeFor[Config]())
+	cfg := &Config{Path: path}
+	if err := doc.Decode(cfg); err != nil {
+		p.yamlError(err)
+	}
+	for i := range cfg.Accounts {
+		cfg.Accounts[i].File = path
+		cfg.Accounts[i].Line = p.lines[fmt.Sprintf("accounts[%d]", i)]
+	}
+
+	dir := filepath.Dir(path)
+	if cfg.Import.Addresses != "" {
+		accounts, errs := ImportAddresses(filepath.Join(dir, cfg.Import.Addresses))
+		cfg.Accounts = append(cfg.Accounts, accounts...)
+		p.errs = append(p.errs, errs...)
+	}
+	if cfg.Import.Filters != "" {
+		filters, errs := ImportFilters(filepath.Join(dir, cfg.Import.Filters))
+		cfg.Filters.merge(filters)
+		p.errs = append(p.errs, errs...)
+	}
+
+	p.validate(cfg)
+	return cfg, p.errs.orNil()
+}
+
+// FromCSV builds a config from an addresses.csv and a filters.csv file as used before
+// config files existed.
+func FromCSV(addressesPath, filtersPath string) (*Config, error) {
+	cfg := &Config{Version: Version, Path: addressesPath}
+	accounts, errs := ImportAddresses(addressesPath)
+	cfg.Accounts = accounts
+	filters, fErrs := ImportFilters(filtersPath)
+	cfg.Filters = filters
+	errs = append(errs, fErrs...)
+
+	p := parser{file: addressesPath, errs: errs}
+	p.validate(cfg)
+	return cfg, p.errs.orNil()
+}
+
+// ImportAddresses reads a file of tags,address,name lines. Lines starting with # are ignored.
+func ImportAddresses(path string) ([]Account, Errors) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, Errors{{File: path, Msg: err.Error()}}
+	}
+
+	ret := []Account{}
+	errs := Errors{}
+	for i, line := range lines {
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Split(line, ",")
+		if len(parts) < 3 {
+			errs = append(errs, &Error{File: path, Line: i + 1, Msg: "expected tags,address,name"})
+			continue
+		}
+		ret = append(ret, Account{
+			Tags:    parts[0],
+			Address: parts[1],
+			Name:    parts[2],
+			File:    path,
+			Line:    i + 1,
+		})
+	}
+	return ret, errs
+}
+
+// ImportFilters reads a file of asset filters (0x...,name) and at most two date filters
+// (date,StartDate or date,EndDate). A single date filter is the last date of interest.
+// Invalid lines are reported and left out.
+func ImportFilters(path string) (Filters, Errors) {
+	lines, err := readLines(path)
+	if err != nil {
+		return Filters{}, Errors{{File: path, Msg: err.Error()}}
+	}
+
+	ret := Filters{}
+	dates := []string{}
+	errs := Errors{}
+	for i, line := range lines {
+		parts := strings.Split(line, ",")
+		switch {
+		case len(line) == 0 || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "0x") && len(parts) == 2:
+			if !base.IsValidAddress(parts[0]) {
+				errs = append(errs, &Error{File: path, Line: i + 1, Msg: fmt.Sprintf("invalid asset address %q", parts[0])})
+				continue
+			}
+			ret.Assets = append(ret.Assets, parts[0])
+		case strings.HasSuffix(line, "Date") && len(parts) == 2:
+			if _, err := parseDate(parts[0], false); err != nil {
+				errs = append(errs, &Error{File: path, Line: i + 1, Msg: fmt.Sprintf("invalid date %q", parts[0])})
+				continue
+			}
+			if len(dates) == 2 {
+				errs = append(errs, &Error{File: path, Line: i + 1, Msg: "at most two date filters are allowed"})
+				continue
+			}
+			dates = append(dates, parts[0])
+		default:
+			errs = append(errs, &Error{File: path, Line: i + 1, Msg: fmt.Sprintf("invalid filter line %q", line)})
+		}
+	}
+
+	switch len(dates) {
+	case 1:
+		ret.To = dates[0]
+	case 2:
+		ret.From, ret.To = dates[0], dates[1]
+	}
+	return ret, errs
+}
+
+func (f *Filters) merge(other Filters) {
+	f.Assets = append(f.Assets, other.Assets...)
+	f.Accounts = append(f.Accounts, other.Accounts...)
+	f.Counterparties = append(f.Counterparties, other.Counterparties...)
+	if f.From == "" {
+		f.From = other.From
+	}
+	if f.To == "" {
+		f.To = other.To
+	}
+}
+
+func readLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errors.New("file not found")
+		}
+		return nil, err
+	}
+	return strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"), nil
+}
+
+// --------------------------------
+type parser struct {
+	file  string
+	lines map[string]int
+	errs  Errors
+}
+
+func (p *parser) errorAt(file string, line int, format string, args ...any) {
+	p.errs = append(p.errs, &Error{File: file, Line: line, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) errorf(path string, format string, args ...any) {
+	p.errorAt(p.file, p.lines[path], format, args...)
+}
+
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// yamlError splits the errors reported by the yaml package into one error per line.
+func (p *parser) yamlError(err error) {
+	msgs := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	}
+	for _, msg := range msgs {
+		if m := yamlLine.FindStringSubmatch(msg); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			p.errorAt(p.file, line, "%s", m[2])
+		} else {
+			p.errorAt(p.file, 0, "%s", strings.TrimPrefix(msg, "yaml: "))
+		}
+	}
+}
+
+// walk checks the shape of the document against the Config struct, reporting unknown
+// keys and misplaced lists or maps, and remembers the line of every value by its path.
+func (p *parser) walk(node *yaml.Node, path string, t reflect.Type) {
+	p.lines[path] = node.Line
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			p.errorAt(p.file, node.Line, "%s: expected a map", describe(path))
+			return
+		}
+		fields := map[string]reflect.Type{}
+		for i := 0; i < t.NumField(); i++ {
+			if tag := t.Field(i).Tag.Get("yaml"); tag != "-" {
+				fields[tag] = t.Field(i).Type
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			ft, ok := fields[key.Value]
+			if !ok {
+				p.errorAt(p.file, key.Line, "unknown key %q in %s", key.Value, describe(path))
+				continue
+			}
+			p.walk(value, join(path, key.Value), ft)
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			p.errorAt(p.file, node.Line, "%s: expected a list", describe(path))
+			return
+		}
+		for i, item := range node.Content {
+			p.walk(item, fmt.Sprintf("%s[%d]", path, i), t.Elem())
+		}
+	default:
+		if node.Kind != yaml.ScalarNode {
+			p.errorAt(p.file, node.Line, "%s: expected a single value", describe(path))
+		}
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func describe(path string) string {
+	if path == "" {
+		return "the top level"
+	}
+	return path
+}
+
+var denoms = []string{"units", "usd", "wei"}
+
+// validate checks the values of a config whose shape is already known to be correct.
+func (p *parser) validate(cfg *Config) {
+	switch {
+	case cfg.Version == 0:
+		p.errorf("", "missing version (the current version is %d)", Version)
+	case cfg.Version != Version:
+		p.errorf("version", "unsupported version %d (the current version is %d)", cfg.Version, Version)
+	}
+	if cfg.Period != "" && !base.IsValidPeriod(cfg.Period) {
+		p.errorf("period", "invalid period %q", cfg.Period)
+	}
+	if cfg.Denom != "" && !slices.Contains(denoms, cfg.Denom) {
+		p.errorf("denom", "invalid denom %q (one of %s)", cfg.Denom, strings.Join(denoms, ", "))
+	}
+	for i, tag := range cfg.Tags {
+		if tag == "" {
+			p.errorf(fmt.Sprintf("tags[%d]", i), "empty tag")
+		}
+	}
+	if cfg.Output.Format != "" && !slices.Contains(report.Formats, cfg.Output.Format) {
+		p.errorf("output.format", "invalid format %q (one of %s)", cfg.Output.Format, strings.Join(report.Formats, ", "))
+	}
+
+	seen := map[string]*Account{}
+	for i := range cfg.Accounts {
+		a := &cfg.Accounts[i]
+		key := strings.ToLower(a.Address) + "@" + cmp.Or(a.Chain, cfg.Chain)
+		switch {
+		case a.Address == "":
+			p.errorAt(a.File, a.Line, "account is missing an address")
+		case !base.IsValidAddress(a.Address):
+			p.errorAt(a.File, a.Line, "invalid address %q", a.Address)
+		case seen[key] != nil:
+			p.errorAt(a.File, a.Line, "duplicate account %s (first listed at %s:%d)", a.Address, seen[key].File, seen[key].Line)
+		default:
+			seen[key] = a
+		}
+		if a.Tags == "" {
+			p.errorAt(a.File, a.Line, "account %s has no tags", a.Address)
+		}
+	}
+
+	p.addresses("filters.assets", "asset", cfg.Filters.Assets)
+	p.addresses("filters.accounts", "account", cfg.Filters.Accounts)
+	p.addresses("filters.counterparties", "counterparty", cfg.Filters.Counterparties)
+	first, firstErr := parseDate(cfg.Filters.From, false)
+	if firstErr != nil {
+		p.errorf("filters.from", "invalid date %q", cfg.Filters.From)
+	}
+	last, lastErr := parseDate(cfg.Filters.To, true)
+	if lastErr != nil {
+		p.errorf("filters.to", "invalid date %q", cfg.Filters.To)
+	}
+	if firstErr == nil && lastErr == nil && first > 0 && last > 0 && last < first {
+		p.errorf("filters.to", "the last date (%s) is before the first date (%s)", cfg.Filters.To, cfg.Filters.From)
+	}
+	if cfg.Filters.LastBlock > 0 && cfg.Filters.LastBlock < cfg.Filters.FirstBlock {
+		p.errorf("filters.lastBlock", "the last block (%d) is before the first block (%d)", cfg.Filters.LastBlock, cfg.Filters.FirstBlock)
+	}
+	if cfg.Filters.Where != "" {
+		if _, err := expr.Compile(cfg.Filters.Where, nil); err != nil {
+			p.errorf("filters.where", "invalid expression: %s", err)
+		}
+	}
+}
+
+func (p *parser) addresses(path, what string, addrs []string) {
+	for i, addr := range addrs {
+		if !base.IsValidAddress(addr) {
+			p.errorf(fmt.Sprintf("%s[%d]", path, i), "invalid %s address %q", what, addr)
+		}
+	}
+}
+
+var dateLayouts = []string{"2006-01-02", "2006-01-02T15:04:05", "2006-01-02 15:04:05"}
+
+// parseDate returns the Unix time of a date, or zero for an empty string. A date without
+// a time is the start of the day, or its last second if it ends a range.
+func parseDate(value string, end bool) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	for i, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			if i == 0 && end {
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t.Unix(), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid date %q", value)
+}
+
+// Range returns the first and last moments (as Unix times) of the date filter, with
+// zero for an open end. Check a config with Load or Parse before calling it.
+func (f *Filters) Range() (first, last int64) {
+	first, _ = parseDate(f.From, false)
+	last, _ = parseDate(f.To, true)
+	return first, last
+}
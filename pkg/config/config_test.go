@@ -0,0 +1,150 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const good = `version: 1
+chain: mainnet
+period: monthly
+denom: usd
+tags: [00-Active]
+accounts:
+  - address: "0x1111111111111111111111111111111111111111"
+    name: Alice
+    tags: 00-Active
+    entity: Family
+  - address: "0x2222222222222222222222222222222222222222"
+    name: Bob
+    tags: 11-Retired
+  - address: "0x1111111111111111111111111111111111111111"
+    name: Alice
+    tags: 00-Active
+    chain: gnosis
+filters:
+  assets: ["0x3333333333333333333333333333333333333333"]
+  from: 2021-01-01
+  to: 2021-12-31
+  lastBlock: 14000000
+output:
+  path: out.md
+  format: md
+`
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse("test.yaml", []byte(good))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Period != "monthly" || cfg.Denom != "usd" || cfg.Output.Format != "md" {
+		t.Errorf("Config differs: got %+v", cfg)
+	}
+	if len(cfg.Accounts) != 3 || cfg.Accounts[0].Entity != "Family" || cfg.Accounts[1].Line != 11 || cfg.Accounts[2].Chain != "gnosis" {
+		t.Errorf("Accounts differ: got %+v", cfg.Accounts)
+	}
+	if cfg.Filters.From != "2021-01-01" || cfg.Filters.To != "2021-12-31" || cfg.Filters.LastBlock != 14000000 {
+		t.Errorf("Filters differ: got %+v", cfg.Filters)
+	}
+	if first, last := cfg.Filters.Range(); first != 1609459200 || last != 1640995199 {
+		t.Errorf("Range differs: got %d-%d, want the whole of 2021", first, last)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	bad := `version: 2
+period: fortnightly
+acounts: []
+accounts:
+  - address: "0x1111111111111111111111111111111111111111"
+    tags: 00-Active
+  - address: "0x1111111111111111111111111111111111111111"
+    tags: 00-Active
+  - address: "0xabc"
+filters:
+  assets: "0x3333333333333333333333333333333333333333"
+  from: 2021-13-01
+  firstBlock: 10
+  lastBlock: 5
+  where: symbol = "USDC"
+output:
+  format: xml
+`
+	_, err := Parse("bad.yaml", []byte(bad))
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Parse error: got %v, want Errors", err)
+	}
+
+	want := []string{
+		`bad.yaml:3: unknown key "acounts" in the top level`,
+		`bad.yaml:11: filters.assets: expected a list`,
+		`bad.yaml:11: cannot unmarshal !!str`,
+		`bad.yaml:1: unsupported version 2`,
+		`bad.yaml:2: invalid period "fortnightly"`,
+		`bad.yaml:17: invalid format "xml"`,
+		`bad.yaml:7: duplicate account 0x1111111111111111111111111111111111111111 (first listed at bad.yaml:5)`,
+		`bad.yaml:9: invalid address "0xabc"`,
+		`bad.yaml:9: account 0xabc has no tags`,
+		`bad.yaml:12: invalid date "2021-13-01"`,
+		`bad.yaml:14: the last block (5) is before the first block (10)`,
+		`bad.yaml:15: invalid expression: column 8: unexpected character '='`,
+	}
+	if len(errs) != len(want) {
+		t.Errorf("Error count differs: got %d, want %d\n%v", len(errs), len(want), err)
+	}
+	for i := 0; i < len(want) && i < len(errs); i++ {
+		if !strings.HasPrefix(errs[i].Error(), want[i]) {
+			t.Errorf("Error %d differs:\n got %s\nwant %s", i, errs[i], want[i])
+		}
+	}
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	_, err := Parse("bad.yaml", []byte("version: 1\naccounts: [\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "bad.yaml:") {
+		t.Errorf("Parse error: got %v, want an error with the file name", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, contents string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("addresses.csv", "# tags,address,name\n00-Active,0x1111111111111111111111111111111111111111,Alice\nnot a line\n")
+	write("filters.csv", "0x3333333333333333333333333333333333333333,TOKEN\n2021-06-30,EndDate\nbogus\n")
+	write("config.yaml", "version: 1\nimport:\n  addresses: addresses.csv\n  filters: filters.csv\n")
+
+	cfg, err := Load(filepath.Join(dir, "config.yaml"))
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("Load error: got %v, want two errors", err)
+	}
+	if !strings.HasSuffix(errs[0].Error(), "addresses.csv:3: expected tags,address,name") {
+		t.Errorf("First error differs: got %s", errs[0])
+	}
+	if !strings.HasSuffix(errs[1].Error(), `filters.csv:3: invalid filter line "bogus"`) {
+		t.Errorf("Second error differs: got %s", errs[1])
+	}
+	if len(cfg.Accounts) != 1 || cfg.Accounts[0].Name != "Alice" {
+		t.Errorf("Accounts differ: got %+v", cfg.Accounts)
+	}
+	if !slices.Equal(cfg.Filters.Assets, []string{"0x3333333333333333333333333333333333333333"}) || cfg.Filters.To != "2021-06-30" || cfg.Filters.From != "" {
+		t.Errorf("Filters differ: got %+v", cfg.Filters)
+	}
+
+	cfg, err = FromCSV(filepath.Join(dir, "addresses.csv"), filepath.Join(dir, "missing.csv"))
+	if err == nil || !strings.Contains(err.Error(), "missing.csv: file not found") {
+		t.Errorf("FromCSV error: got %v, want file not found", err)
+	}
+	if cfg.Version != Version || len(cfg.Accounts) != 1 {
+		t.Errorf("FromCSV differs: got %+v", cfg)
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/xuri/excelize/v2"
 )
 
-func WriteLicenseSheet(f *excelize.File) {
+func WriteLicenseSheet(f *excelize.File) error {
 	f.NewSheet("License")
 	nRows := len(strings.Split(license, "\n"))
 	f.MergeCell("License", "A1", fmt.Sprintf("A%d", nRows))
@@ -25,10 +25,10 @@ func WriteLicenseSheet(f *excelize.File) {
 		},
 	})
 	if err != nil {
-		panic(err)
+		return err
 	}
 	f.SetCellStyle("License", "A1", "A1", style)
-	f.SetColWidth("License", "A", "A", 90)
+	return f.SetColWidth("License", "A", "A", 90)
 }
 
 var license = `BSD 3-Clause License
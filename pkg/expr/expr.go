@@ -0,0 +1,375 @@
+// Package expr implements a small expression language for selecting records. An expression
+// compares the fields of a statement or log with literals, combines the comparisons with
+// boolean logic, and may look up the names and tags of addresses. For example:
+//
+//	symbol == "USDC" && usd(amountOut) > 10000 && !named(recipient) && date >= "2024"
+//
+// An expression may also be a number, such as usd(amountOut), to be computed for each record.
+//
+// See README.md for the fields and functions available.
+package expr
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+)
+
+// Kind is the type of a value in an expression.
+type Kind int
+
+const (
+	Bool Kind = iota
+	Number
+	String
+	Address
+)
+
+var kindNames = map[Kind]string{
+	Bool:    "boolean",
+	Number:  "number",
+	String:  "string",
+	Address: "address",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// an returns the name of the kind with its indefinite article.
+func an(k Kind) string {
+	if k == Address {
+		return "an " + k.String()
+	}
+	return "a " + k.String()
+}
+
+// --------------------------------
+// Expr is a compiled expression.
+type Expr struct {
+	Source string
+	root   node
+	names  map[base.Address]types.Name
+	valid  map[reflect.Type]bool
+}
+
+// Compile parses an expression. Names are used by the name, tags and named functions and
+// may be nil if the expression is only being validated.
+func Compile(src string, names map[base.Address]types.Name) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorAt(t.pos, "unexpected %s", t)
+	}
+	ret := &Expr{Source: src, root: root, names: names, valid: map[reflect.Type]bool{}}
+	for record := range records {
+		ret.valid[record] = ret.Check(record) == nil
+	}
+	return ret, nil
+}
+
+// Check makes sure the expression is a boolean that can be evaluated against records of
+// the given type (for example, *types.Statement), so that Match cannot fail.
+func (e *Expr) Check(record reflect.Type) error {
+	return e.check(record, Bool)
+}
+
+// CheckNumber makes sure the expression is a number that can be evaluated against records
+// of the given type, so that Number cannot fail.
+func (e *Expr) CheckNumber(record reflect.Type) error {
+	return e.check(record, Number)
+}
+
+func (e *Expr) check(record reflect.Type, want Kind) error {
+	fields, ok := records[record]
+	if !ok {
+		return fmt.Errorf("expressions cannot be applied to %s records", record)
+	}
+	kind, err := e.root.check(fields)
+	if err != nil {
+		return err
+	}
+	if kind != want {
+		return errorAt(e.root.pos(), "the expression is %s, not %s", an(kind), an(want))
+	}
+	return nil
+}
+
+// Match returns true if the record satisfies the expression. Records of a type the
+// expression cannot be evaluated against (see Check) do not match.
+func (e *Expr) Match(record any) bool {
+	t := reflect.TypeOf(record)
+	if !e.valid[t] {
+		return false
+	}
+	return e.root.eval(&env{record: record, fields: records[t], names: e.names}).(bool)
+}
+
+// Number evaluates the expression against a record of a type it passed CheckNumber for.
+func (e *Expr) Number(record any) float64 {
+	t := reflect.TypeOf(record)
+	ret, _ := e.root.eval(&env{record: record, fields: records[t], names: e.names}).(*big.Float).Float64()
+	return ret
+}
+
+func (e *Expr) String() string {
+	return e.Source
+}
+
+// --------------------------------
+// env is what an expression is evaluated against.
+type env struct {
+	record any
+	fields map[string]accessor
+	names  map[base.Address]types.Name
+}
+
+// node is an element of the parsed expression. Values are bools, *big.Floats, strings or
+// base.Addresses depending on the node's Kind.
+type node interface {
+	pos() int
+	check(fields map[string]accessor) (Kind, error)
+	eval(e *env) any
+}
+
+// --------------------------------
+type literal struct {
+	at    int
+	kind  Kind
+	value any
+}
+
+func (n *literal) pos() int                                       { return n.at }
+func (n *literal) check(fields map[string]accessor) (Kind, error) { return n.kind, nil }
+func (n *literal) eval(e *env) any                                { return n.value }
+
+// checkAddress makes sure a string literal compared with an address is one.
+func (n *literal) checkAddress() error {
+	if !base.IsValidAddress(n.value.(string)) {
+		return errorAt(n.at, "invalid address %q", n.value)
+	}
+	return nil
+}
+
+// --------------------------------
+type field struct {
+	at   int
+	name string
+}
+
+func (n *field) pos() int { return n.at }
+
+func (n *field) check(fields map[string]accessor) (Kind, error) {
+	f, ok := fields[n.name]
+	if !ok {
+		return 0, errorAt(n.at, "these records have no field %q", n.name)
+	}
+	return f.kind, nil
+}
+
+func (n *field) eval(e *env) any {
+	return e.fields[n.name].get(e.record)
+}
+
+// --------------------------------
+type call struct {
+	at   int
+	name string
+	args []node
+}
+
+func (n *call) pos() int { return n.at }
+
+func (n *call) check(fields map[string]accessor) (Kind, error) {
+	fn := functions[n.name]
+	if len(n.args) != len(fn.args) {
+		return 0, errorAt(n.at, "%s takes %d argument(s), not %d", n.name, len(fn.args), len(n.args))
+	}
+	for i, arg := range n.args {
+		kind, err := arg.check(fields)
+		if err != nil {
+			return 0, err
+		}
+		if kind != fn.args[i] {
+			return 0, errorAt(arg.pos(), "argument %d of %s must be %s, not %s", i+1, n.name, an(fn.args[i]), an(kind))
+		}
+	}
+	for _, name := range fn.needs {
+		if _, ok := fields[name]; !ok {
+			return 0, errorAt(n.at, "%s needs records with a %s field", n.name, name)
+		}
+	}
+	return fn.result, nil
+}
+
+func (n *call) eval(e *env) any {
+	args := make([]any, 0, len(n.args))
+	for _, arg := range n.args {
+		args = append(args, arg.eval(e))
+	}
+	return functions[n.name].call(e, args)
+}
+
+// --------------------------------
+type not struct {
+	at int
+	x  node
+}
+
+func (n *not) pos() int { return n.at }
+
+func (n *not) check(fields map[string]accessor) (Kind, error) {
+	kind, err := n.x.check(fields)
+	if err == nil && kind != Bool {
+		err = errorAt(n.x.pos(), "cannot negate %s", an(kind))
+	}
+	return Bool, err
+}
+
+func (n *not) eval(e *env) any {
+	return !n.x.eval(e).(bool)
+}
+
+// --------------------------------
+type binary struct {
+	at   int
+	op   string
+	x, y node
+}
+
+func (n *binary) pos() int { return n.x.pos() }
+
+func (n *binary) check(fields map[string]accessor) (Kind, error) {
+	xk, err := n.x.check(fields)
+	if err != nil {
+		return 0, err
+	}
+	yk, err := n.y.check(fields)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "&&", "||":
+		if xk != Bool || yk != Bool {
+			return 0, errorAt(n.at, "%s needs booleans, not %s and %s", n.op, an(xk), an(yk))
+		}
+	default:
+		if err := comparable(n.at, n.op, n.x, n.y, xk, yk); err != nil {
+			return 0, err
+		}
+		if n.op != "==" && n.op != "!=" && (xk == Bool || xk == Address || yk == Address) {
+			return 0, errorAt(n.at, "cannot use %s with %s", n.op, an(max(xk, yk)))
+		}
+	}
+	return Bool, nil
+}
+
+// comparable checks that two values may be compared. A string literal may be compared
+// with an address if it is one, and is read as one when evaluated (see compare), so that
+// checking against one type of record does not change the expression for the others.
+func comparable(at int, op string, x, y node, xk, yk Kind) error {
+	switch {
+	case xk == yk:
+		return nil
+	case xk == Address:
+		if lit, ok := y.(*literal); ok && lit.kind == String {
+			return lit.checkAddress()
+		}
+	case yk == Address:
+		if lit, ok := x.(*literal); ok && lit.kind == String {
+			return lit.checkAddress()
+		}
+	}
+	return errorAt(at, "cannot compare %s with %s using %s", an(xk), an(yk), op)
+}
+
+func (n *binary) eval(e *env) any {
+	switch n.op {
+	case "&&":
+		return n.x.eval(e).(bool) && n.y.eval(e).(bool)
+	case "||":
+		return n.x.eval(e).(bool) || n.y.eval(e).(bool)
+	}
+	c := compare(n.x.eval(e), n.y.eval(e))
+	switch n.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+// compare returns the order of two values of the same kind, or of an address and a string
+// holding one. Booleans and addresses are only ever equal (0) or not (1).
+func compare(x, y any) int {
+	switch a := x.(type) {
+	case *big.Float:
+		return a.Cmp(y.(*big.Float))
+	case string:
+		if b, ok := y.(base.Address); ok {
+			return compare(base.HexToAddress(a), b)
+		}
+		return strings.Compare(a, y.(string))
+	case base.Address:
+		if b, ok := y.(string); ok {
+			y = base.HexToAddress(b)
+		}
+	}
+	if x == y {
+		return 0
+	}
+	return 1
+}
+
+// --------------------------------
+type in struct {
+	at   int
+	x    node
+	list []node
+}
+
+func (n *in) pos() int { return n.x.pos() }
+
+func (n *in) check(fields map[string]accessor) (Kind, error) {
+	xk, err := n.x.check(fields)
+	if err != nil {
+		return 0, err
+	}
+	for _, item := range n.list {
+		ik, err := item.check(fields)
+		if err != nil {
+			return 0, err
+		}
+		if err := comparable(item.pos(), "in", n.x, item, xk, ik); err != nil {
+			return 0, err
+		}
+	}
+	return Bool, nil
+}
+
+func (n *in) eval(e *env) any {
+	x := n.x.eval(e)
+	return slices.ContainsFunc(n.list, func(item node) bool {
+		return compare(x, item.eval(e)) == 0
+	})
+}
@@ -0,0 +1,171 @@
+package expr
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+)
+
+var (
+	me     = base.HexToAddress("0xf503017d7baf7fbc0fff7492b751025c6a78179b")
+	other  = base.HexToAddress("0x1111111111111111111111111111111111111111")
+	usdc   = base.HexToAddress("0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48")
+	names  = map[base.Address]types.Name{me: {Address: me, Name: "Treasury", Tags: "00-Active"}}
+	stmtTy = reflect.TypeFor[*types.Statement]()
+	logTy  = reflect.TypeFor[*types.Log]()
+)
+
+func TestMatch(t *testing.T) {
+	stmt := &types.Statement{
+		AccountedFor: me,
+		Sender:       me,
+		Recipient:    other,
+		Asset:        usdc,
+		Symbol:       "USDC",
+		Decimals:     6,
+		AmountOut:    *base.NewWei(12_500_000_000),
+		SpotPrice:    *base.NewFloat(1.0),
+		BlockNumber:  19000000,
+		Timestamp:    1718000000, // 2024-06-10
+	}
+	log := &types.Log{
+		Address:     usdc,
+		BlockNumber: 19000000,
+		Articulated: &types.Function{Name: "Transfer"},
+		Topics:      []base.Hash{base.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")},
+	}
+
+	tests := []struct {
+		src    string
+		record any
+		want   bool
+	}{
+		{`symbol == "USDC" && usd(amountOut) > 10000 && !named(recipient) && date >= "2024" && date < "2025"`, stmt, true},
+		{`usd(amountOut) > 20_000`, stmt, false},
+		{`units(amountOut) == 12500`, stmt, true},
+		{`amountOut == 12500000000`, stmt, true},
+		{`totalOut >= 1.25e10 and blockNumber <= 19000000`, stmt, true},
+		{`name(sender) == "Treasury" && contains(tags(accountedFor), "Active")`, stmt, true},
+		{`sender == 0xf503017d7baf7fbc0fff7492b751025c6a78179b`, stmt, true},
+		{`asset == "0xA0b86991c6218b36c1d19d4A2e9Eb0cE3606eB48"`, stmt, true},
+		{`recipient in ["0x2222222222222222222222222222222222222222", 0x1111111111111111111111111111111111111111]`, stmt, true},
+		{`lower(symbol) in ["dai", "weth"]`, stmt, false},
+		{`not (symbol == "USDC" || amountIn > 0)`, stmt, false},
+		{`event == "Transfer" && address == 0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48`, log, true},
+		{`topic0 == "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef" && topic1 == ""`, log, true},
+		{`symbol == "USDC"`, log, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := Compile(tt.src, names)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.record == stmt {
+				if err := e.Check(stmtTy); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := e.Match(tt.record); got != tt.want {
+				t.Errorf("Match: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLeavesExpression(t *testing.T) {
+	stmt := &types.Statement{Recipient: other}
+	e, err := Compile(`"0x2222222222222222222222222222222222222222" in ["0x3333333333333333333333333333333333333333", recipient, "0x2222222222222222222222222222222222222222"]`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Checking against each type of record leaves the expression as it was parsed
+	for range 2 {
+		for record := range records {
+			e.Check(record)
+			if !e.Match(stmt) {
+				t.Fatalf("Match: got false after checking against %s, want true", record)
+			}
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		src    string
+		record reflect.Type
+		want   string
+	}{
+		{`symbol == "USDC`, nil, `column 11: unterminated string`},
+		{`symbol = "USDC"`, nil, `column 8: unexpected character '='`},
+		{`sybmol == "USDC"`, nil, `column 1: unknown field "sybmol"`},
+		{`usd(amountOut) > 10k`, nil, `column 20: unexpected "k"`},
+		{`price(asset) > 1`, nil, `column 1: unknown function "price"`},
+		{`(symbol == "USDC"`, nil, `column 18: expected ")", found end of expression`},
+		{`sender == 0x123`, nil, `column 11: invalid address "0x123"`},
+		{`symbol`, stmtTy, `column 1: the expression is a string, not a boolean`},
+		{`symbol > 1`, stmtTy, `column 8: cannot compare a string with a number using >`},
+		{`sender < "0x1111111111111111111111111111111111111111"`, stmtTy, `column 8: cannot use < with an address`},
+		{`sender == "bob"`, stmtTy, `column 11: invalid address "bob"`},
+		{`!amountIn`, stmtTy, `column 2: cannot negate a number`},
+		{`named(symbol)`, stmtTy, `column 7: argument 1 of named must be an address, not a string`},
+		{`units(data) > 1`, logTy, `column 7: argument 1 of units must be a number, not a string`},
+		{`symbol == "USDC"`, logTy, `column 1: these records have no field "symbol"`},
+		{`usd(0) > 1`, logTy, `column 1: usd needs records with a decimals field`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := Compile(tt.src, nil)
+			if err == nil {
+				err = e.Check(tt.record)
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("error differs:\n got %v\nwant %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNumber(t *testing.T) {
+	stmt := &types.Statement{
+		Decimals:    6,
+		AmountOut:   *base.NewWei(12_500_000_000),
+		SpotPrice:   *base.NewFloat(2.0),
+		BlockNumber: 19000000,
+	}
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{`blockNumber`, 19000000},
+		{`units(amountOut)`, 12500},
+		{`usd(amountOut)`, 25000},
+		{`1`, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := Compile(tt.src, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := e.CheckNumber(stmtTy); err != nil {
+				t.Fatal(err)
+			}
+			if got := e.Number(stmt); got != tt.want {
+				t.Errorf("Number differs: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	e, _ := Compile(`amountOut > 0`, nil)
+	if err := e.CheckNumber(stmtTy); err == nil || err.Error() != "column 1: the expression is a boolean, not a number" {
+		t.Errorf("CheckNumber: got %v", err)
+	}
+}
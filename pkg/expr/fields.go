@@ -0,0 +1,183 @@
+package expr
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+)
+
+// --------------------------------
+// accessor reads one field from a record.
+type accessor struct {
+	kind Kind
+	get  func(record any) any
+}
+
+// records holds the fields of each type of record, keyed by their JSON names.
+var records = map[reflect.Type]map[string]accessor{
+	reflect.TypeFor[*types.Statement](): fieldsOf[types.Statement](map[string]accessor{
+		"totalIn":   {Number, func(r any) any { return toNumber(r.(*types.Statement).TotalIn()) }},
+		"totalOut":  {Number, func(r any) any { return toNumber(r.(*types.Statement).TotalOut()) }},
+		"amountNet": {Number, func(r any) any { return toNumber(r.(*types.Statement).AmountNet()) }},
+		"date":      {String, func(r any) any { return date(r.(*types.Statement).Timestamp) }},
+	}),
+	reflect.TypeFor[*types.Log](): fieldsOf[types.Log](map[string]accessor{
+		"date":   {String, func(r any) any { return date(r.(*types.Log).Timestamp) }},
+		"event":  {String, func(r any) any { return articulated(r.(*types.Log).Articulated) }},
+		"topic0": {String, func(r any) any { return topic(r.(*types.Log), 0) }},
+		"topic1": {String, func(r any) any { return topic(r.(*types.Log), 1) }},
+		"topic2": {String, func(r any) any { return topic(r.(*types.Log), 2) }},
+		"topic3": {String, func(r any) any { return topic(r.(*types.Log), 3) }},
+	}),
+	reflect.TypeFor[*types.Transaction](): fieldsOf[types.Transaction](map[string]accessor{
+		"date":     {String, func(r any) any { return date(r.(*types.Transaction).Timestamp) }},
+		"function": {String, func(r any) any { return articulated(r.(*types.Transaction).ArticulatedTx) }},
+	}),
+	reflect.TypeFor[*types.Trace](): fieldsOf[types.Trace](map[string]accessor{
+		"date":     {String, func(r any) any { return date(r.(*types.Trace).Timestamp) }},
+		"function": {String, func(r any) any { return articulated(r.(*types.Trace).ArticulatedTrace) }},
+		"from":     {Address, func(r any) any { return action(r).From }},
+		"to":       {Address, func(r any) any { return action(r).To }},
+		"value":    {Number, func(r any) any { return toNumber(&action(r).Value) }},
+		"callType": {String, func(r any) any { return action(r).CallType }},
+	}),
+	reflect.TypeFor[*types.Receipt](): fieldsOf[types.Receipt](map[string]accessor{}),
+	reflect.TypeFor[*types.Appearance](): fieldsOf[types.Appearance](map[string]accessor{
+		"date": {String, func(r any) any { return date(r.(*types.Appearance).Timestamp) }},
+	}),
+}
+
+var (
+	addressType = reflect.TypeFor[base.Address]()
+	hashType    = reflect.TypeFor[base.Hash]()
+	weiType     = reflect.TypeFor[base.Wei]()
+	floatType   = reflect.TypeFor[base.Float]()
+)
+
+// fieldsOf returns the derived fields along with every field of T whose type has a Kind.
+func fieldsOf[T any](derived map[string]accessor) map[string]accessor {
+	ret := derived
+	t := reflect.TypeFor[T]()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		index := f.Index
+		value := func(r any) reflect.Value {
+			return reflect.ValueOf(r).Elem().FieldByIndex(index)
+		}
+		switch {
+		case f.Type == addressType:
+			ret[name] = accessor{Address, func(r any) any { return value(r).Interface().(base.Address) }}
+		case f.Type == hashType:
+			ret[name] = accessor{String, func(r any) any { return value(r).Interface().(base.Hash).Hex() }}
+		case f.Type == weiType:
+			ret[name] = accessor{Number, func(r any) any { return toNumber(value(r).Addr().Interface().(*base.Wei)) }}
+		case f.Type == floatType:
+			ret[name] = accessor{Number, func(r any) any { return toNumber(value(r).Addr().Interface().(*base.Float)) }}
+		case f.Type.Kind() >= reflect.Int && f.Type.Kind() <= reflect.Uint64:
+			ret[name] = accessor{Number, func(r any) any {
+				v := value(r)
+				if v.CanInt() {
+					return newNumber().SetInt64(v.Int())
+				}
+				return newNumber().SetUint64(v.Uint())
+			}}
+		case f.Type.Kind() == reflect.String:
+			ret[name] = accessor{String, func(r any) any { return value(r).String() }}
+		case f.Type.Kind() == reflect.Bool:
+			ret[name] = accessor{Bool, func(r any) any { return value(r).Bool() }}
+		}
+	}
+	return ret
+}
+
+// isField returns true if the name is a field of any type of record.
+func isField(name string) bool {
+	for _, fields := range records {
+		if _, ok := fields[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func toNumber(v interface{ String() string }) *big.Float {
+	ret, _ := newNumber().SetString(v.String())
+	if ret == nil {
+		return newNumber()
+	}
+	return ret
+}
+
+func date(ts base.Timestamp) string {
+	return base.NewDateTimeTs(ts).String()
+}
+
+func articulated(f *types.Function) string {
+	if f == nil {
+		return ""
+	}
+	return f.Name
+}
+
+// action returns the action of a trace, or an empty one if it has none.
+func action(r any) *types.TraceAction {
+	if a := r.(*types.Trace).Action; a != nil {
+		return a
+	}
+	return &types.TraceAction{}
+}
+
+func topic(l *types.Log, i int) string {
+	if i < len(l.Topics) {
+		return l.Topics[i].Hex()
+	}
+	return ""
+}
+
+// --------------------------------
+// function is a built in function. Needs lists the record fields it reads.
+type function struct {
+	args   []Kind
+	result Kind
+	needs  []string
+	call   func(e *env, args []any) any
+}
+
+var functions = map[string]function{
+	"name": {[]Kind{Address}, String, nil, func(e *env, args []any) any {
+		return e.names[args[0].(base.Address)].Name
+	}},
+	"tags": {[]Kind{Address}, String, nil, func(e *env, args []any) any {
+		return e.names[args[0].(base.Address)].Tags
+	}},
+	"named": {[]Kind{Address}, Bool, nil, func(e *env, args []any) any {
+		return e.names[args[0].(base.Address)].Name != ""
+	}},
+	"contains": {[]Kind{String, String}, Bool, nil, func(e *env, args []any) any {
+		return strings.Contains(args[0].(string), args[1].(string))
+	}},
+	"lower": {[]Kind{String}, String, nil, func(e *env, args []any) any {
+		return strings.ToLower(args[0].(string))
+	}},
+	"units": {[]Kind{Number}, Number, []string{"decimals"}, func(e *env, args []any) any {
+		return units(e, args[0].(*big.Float))
+	}},
+	"usd": {[]Kind{Number}, Number, []string{"decimals", "spotPrice"}, func(e *env, args []any) any {
+		price := e.fields["spotPrice"].get(e.record).(*big.Float)
+		return newNumber().Mul(units(e, args[0].(*big.Float)), price)
+	}},
+}
+
+// units divides an amount by ten to the power of the record's decimals.
+func units(e *env, amount *big.Float) *big.Float {
+	decimals, _ := e.fields["decimals"].get(e.record).(*big.Float).Uint64()
+	divisor := newNumber().SetInt(new(big.Int).Exp(big.NewInt(10), new(big.Int).SetUint64(decimals), nil))
+	return newNumber().Quo(amount, divisor)
+}
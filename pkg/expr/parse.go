@@ -0,0 +1,300 @@
+package expr
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+)
+
+// --------------------------------
+// Error is a problem found in an expression, at the given (one-based) column.
+type Error struct {
+	Column int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Msg)
+}
+
+func errorAt(pos int, format string, args ...any) *Error {
+	return &Error{Column: pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// --------------------------------
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokAddress
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","}
+
+// lex splits an expression into tokens.
+func lex(src string) ([]token, error) {
+	ret := []token{}
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(src[i+1:], c)
+			if end < 0 {
+				return nil, errorAt(i, "unterminated string")
+			}
+			ret = append(ret, token{kind: tokString, text: src[i+1 : i+1+end], pos: i})
+			i += end + 2
+
+		case c == '0' && i+1 < len(src) && (src[i+1] == 'x' || src[i+1] == 'X'):
+			j := i + 2
+			for j < len(src) && strings.IndexByte("0123456789abcdefABCDEF", src[j]) >= 0 {
+				j++
+			}
+			if !base.IsValidAddress(src[i:j]) {
+				return nil, errorAt(i, "invalid address %q", src[i:j])
+			}
+			ret = append(ret, token{kind: tokAddress, text: src[i:j], pos: i})
+			i = j
+
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || strings.IndexByte("._eE", src[j]) >= 0 ||
+				((src[j] == '-' || src[j] == '+') && (src[j-1] == 'e' || src[j-1] == 'E'))) {
+				j++
+			}
+			ret = append(ret, token{kind: tokNumber, text: src[i:j], pos: i})
+			i = j
+
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			ret = append(ret, token{kind: tokIdent, text: src[i:j], pos: i})
+			i = j
+
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, errorAt(i, "unexpected character %q", c)
+			}
+			ret = append(ret, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(ret, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// --------------------------------
+// parser is a recursive descent parser for the grammar:
+//
+//	or      = and { ("||" | "or") and }
+//	and     = not { ("&&" | "and") not }
+//	not     = ("!" | "not") not | compare
+//	compare = operand [ ("==" | "!=" | "<" | "<=" | ">" | ">=") operand | "in" list ]
+//	list    = "[" operand { "," operand } "]"
+//	operand = number | string | address | "true" | "false" | field | func "(" [ or { "," or } ] ")" | "(" or ")"
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokEOF {
+		p.next++
+	}
+	return t
+}
+
+// accept takes the next token if it is one of the given operators or keywords.
+func (p *parser) accept(texts ...string) (token, bool) {
+	t := p.peek()
+	if t.kind != tokOp && t.kind != tokIdent {
+		return t, false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			return p.take(), true
+		}
+	}
+	return t, false
+}
+
+func (p *parser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		return errorAt(p.peek().pos, "expected %q, found %s", text, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	x, err := p.parseAnd()
+	for err == nil {
+		op, ok := p.accept("||", "or")
+		if !ok {
+			break
+		}
+		var y node
+		if y, err = p.parseAnd(); err == nil {
+			x = &binary{at: op.pos, op: "||", x: x, y: y}
+		}
+	}
+	return x, err
+}
+
+func (p *parser) parseAnd() (node, error) {
+	x, err := p.parseNot()
+	for err == nil {
+		op, ok := p.accept("&&", "and")
+		if !ok {
+			break
+		}
+		var y node
+		if y, err = p.parseNot(); err == nil {
+			x = &binary{at: op.pos, op: "&&", x: x, y: y}
+		}
+	}
+	return x, err
+}
+
+func (p *parser) parseNot() (node, error) {
+	if op, ok := p.accept("!", "not"); ok {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &not{at: op.pos, x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	x, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.accept("==", "!=", "<", "<=", ">", ">="); ok {
+		y, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &binary{at: op.pos, op: op.text, x: x, y: y}, nil
+	}
+	if op, ok := p.accept("in"); ok {
+		if err := p.expect("["); err != nil {
+			return nil, err
+		}
+		ret := &in{at: op.pos, x: x}
+		for {
+			item, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			ret.list = append(ret.list, item)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		return ret, p.expect("]")
+	}
+	return x, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.take()
+	switch t.kind {
+	case tokNumber:
+		n, ok := newNumber().SetString(strings.ReplaceAll(t.text, "_", ""))
+		if !ok {
+			return nil, errorAt(t.pos, "invalid number %q", t.text)
+		}
+		return &literal{at: t.pos, kind: Number, value: n}, nil
+
+	case tokString:
+		return &literal{at: t.pos, kind: String, value: t.text}, nil
+
+	case tokAddress:
+		return &literal{at: t.pos, kind: Address, value: base.HexToAddress(t.text)}, nil
+
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return &literal{at: t.pos, kind: Bool, value: t.text == "true"}, nil
+		case "and", "or", "not", "in":
+			return nil, errorAt(t.pos, "unexpected %s", t)
+		}
+		if _, ok := p.accept("("); !ok {
+			if !isField(t.text) {
+				return nil, errorAt(t.pos, "unknown field %q", t.text)
+			}
+			return &field{at: t.pos, name: t.text}, nil
+		}
+		if _, ok := functions[t.text]; !ok {
+			return nil, errorAt(t.pos, "unknown function %q", t.text)
+		}
+		ret := &call{at: t.pos, name: t.text}
+		if _, ok := p.accept(")"); ok {
+			return ret, nil
+		}
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			ret.args = append(ret.args, arg)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		return ret, p.expect(")")
+
+	case tokOp:
+		switch t.text {
+		case "(":
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		}
+	}
+	return nil, errorAt(t.pos, "unexpected %s", t)
+}
+
+func newNumber() *big.Float {
+	return new(big.Float).SetPrec(256)
+}
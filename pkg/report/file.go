@@ -0,0 +1,26 @@
+package report
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile writes a file by way of a temporary file in the same folder, which replaces
+// it only once completely written, so that readers never see a partial file and a failed
+// write leaves the previous file in place.
+func WriteFile(path string, write func(w io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
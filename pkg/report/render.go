@@ -0,0 +1,315 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var Formats = []string{"csv", "tsv", "json", "ndjson", "md", "txt"}
+
+// Render writes the tables to w in the given format.
+func Render(w io.Writer, format string, tables ...*Table) error {
+	switch format {
+	case "", "csv":
+		return renderDelimited(w, ',', tables)
+	case "tsv":
+		return renderDelimited(w, '\t', tables)
+	case "json":
+		return renderJson(w, tables)
+	case "ndjson":
+		return renderNdjson(w, tables)
+	case "md":
+		return renderMarkdown(w, tables)
+	case "txt":
+		return renderText(w, tables)
+	default:
+		return fmt.Errorf("unknown format %q (one of %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+func renderDelimited(w io.Writer, comma rune, tables []*Table) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	for i, t := range tables {
+		if i > 0 {
+			cw.Write([]string{})
+		}
+		cw.Write([]string{t.Name})
+		for _, p := range t.Summary {
+			cw.Write([]string{p.Name + ": " + Format(p.Value)})
+		}
+		for _, s := range t.Sections {
+			cw.Write([]string{})
+			if s.Name != "" {
+				cw.Write([]string{s.Name})
+			}
+			for _, p := range s.Summary {
+				cw.Write([]string{p.Name + ": " + Format(p.Value)})
+			}
+			cw.Write(s.names())
+			for _, row := range s.Rows {
+				cw.Write(formatRow(row))
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func renderJson(w io.Writer, tables []*Table) error {
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i, t := range tables {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  {\"name\": ")
+		writeJsonValue(&buf, t.Name, String)
+		buf.WriteString(", \"summary\": ")
+		writeJsonPairs(&buf, t.Summary)
+		buf.WriteString(", \"sections\": [")
+		for j, s := range t.Sections {
+			if j > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n    {\"name\": ")
+			writeJsonValue(&buf, s.Name, String)
+			buf.WriteString(", \"summary\": ")
+			writeJsonPairs(&buf, s.Summary)
+			buf.WriteString(", \"columns\": [")
+			for k, c := range s.Columns {
+				if k > 0 {
+					buf.WriteString(", ")
+				}
+				fmt.Fprintf(&buf, "{\"name\": %q, \"key\": %q, \"kind\": %q}", c.Name, c.Key(), c.Kind)
+			}
+			buf.WriteString("], \"rows\": [")
+			for k, row := range s.Rows {
+				if k > 0 {
+					buf.WriteString(",")
+				}
+				buf.WriteString("\n      ")
+				writeJsonRow(&buf, nil, s.Columns, row)
+			}
+			buf.WriteString("]}")
+		}
+		buf.WriteString("]}")
+	}
+	buf.WriteString("\n]\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// renderNdjson writes one object per line: a summary object for each table (if it has
+// a summary) followed by one object per row, each naming its table and section.
+func renderNdjson(w io.Writer, tables []*Table) error {
+	var buf bytes.Buffer
+	for _, t := range tables {
+		if len(t.Summary) > 0 {
+			buf.WriteString("{\"type\": \"summary\", \"table\": ")
+			writeJsonValue(&buf, t.Name, String)
+			buf.WriteString(", \"summary\": ")
+			writeJsonPairs(&buf, t.Summary)
+			buf.WriteString("}\n")
+		}
+		for _, s := range t.Sections {
+			for _, row := range s.Rows {
+				prefix := []Pair{{"type", "row"}, {"table", t.Name}, {"section", s.Name}}
+				writeJsonRow(&buf, prefix, s.Columns, row)
+				buf.WriteString("\n")
+			}
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func renderMarkdown(w io.Writer, tables []*Table) error {
+	var buf bytes.Buffer
+	for i, t := range tables {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "## %s\n", t.Name)
+		if len(t.Summary) > 0 {
+			buf.WriteString("\n")
+			for _, p := range t.Summary {
+				fmt.Fprintf(&buf, "- **%s**: %s\n", p.Name, escapeMarkdown(Format(p.Value)))
+			}
+		}
+		for _, s := range t.Sections {
+			buf.WriteString("\n")
+			if s.Name != "" {
+				fmt.Fprintf(&buf, "### %s\n\n", s.Name)
+			}
+			for _, p := range s.Summary {
+				fmt.Fprintf(&buf, "- **%s**: %s\n", p.Name, escapeMarkdown(Format(p.Value)))
+			}
+			if len(s.Summary) > 0 {
+				buf.WriteString("\n")
+			}
+			buf.WriteString("|")
+			for _, c := range s.Columns {
+				fmt.Fprintf(&buf, " %s |", escapeMarkdown(c.Name))
+			}
+			buf.WriteString("\n|")
+			for _, c := range s.Columns {
+				if c.Kind.numeric() {
+					buf.WriteString(" ---: |")
+				} else {
+					buf.WriteString(" --- |")
+				}
+			}
+			buf.WriteString("\n")
+			for _, row := range s.Rows {
+				buf.WriteString("|")
+				for _, v := range formatRow(row) {
+					fmt.Fprintf(&buf, " %s |", escapeMarkdown(v))
+				}
+				buf.WriteString("\n")
+			}
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func renderText(w io.Writer, tables []*Table) error {
+	var buf bytes.Buffer
+	for i, t := range tables {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(t.Name + "\n")
+		for _, p := range t.Summary {
+			fmt.Fprintf(&buf, "  %s: %s\n", p.Name, Format(p.Value))
+		}
+		for _, s := range t.Sections {
+			buf.WriteString("\n")
+			if s.Name != "" {
+				buf.WriteString(s.Name + "\n")
+			}
+			for _, p := range s.Summary {
+				fmt.Fprintf(&buf, "  %s: %s\n", p.Name, Format(p.Value))
+			}
+			cells := [][]string{s.names()}
+			for _, row := range s.Rows {
+				cells = append(cells, formatRow(row))
+			}
+			widths := make([]int, len(s.Columns))
+			for j := range widths {
+				widths[j] = width(cells, j)
+			}
+			for _, row := range cells {
+				line := ""
+				for j, v := range row {
+					if j >= len(widths) {
+						break
+					}
+					if j > 0 {
+						line += "  "
+					}
+					pad := strings.Repeat(" ", widths[j]-len(v))
+					if s.Columns[j].Kind.numeric() {
+						line += pad + v
+					} else {
+						line += v + pad
+					}
+				}
+				buf.WriteString(strings.TrimRight(line, " ") + "\n")
+			}
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func width(cells [][]string, col int) int {
+	ret := 0
+	for _, row := range cells {
+		if col < len(row) && len(row[col]) > ret {
+			ret = len(row[col])
+		}
+	}
+	return ret
+}
+
+func (s *Section) names() []string {
+	ret := make([]string, 0, len(s.Columns))
+	for _, c := range s.Columns {
+		ret = append(ret, c.Name)
+	}
+	return ret
+}
+
+func formatRow(row []any) []string {
+	ret := make([]string, 0, len(row))
+	for _, v := range row {
+		ret = append(ret, Format(v))
+	}
+	return ret
+}
+
+func escapeMarkdown(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+func writeJsonPairs(buf *bytes.Buffer, pairs []Pair) {
+	buf.WriteString("{")
+	for i, p := range pairs {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		writeJsonValue(buf, p.Name, String)
+		buf.WriteString(": ")
+		writeJsonValue(buf, p.Value, kindOf(p.Value))
+	}
+	buf.WriteString("}")
+}
+
+func writeJsonRow(buf *bytes.Buffer, prefix []Pair, columns []Column, row []any) {
+	buf.WriteString("{")
+	for i, p := range prefix {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		writeJsonValue(buf, p.Name, String)
+		buf.WriteString(": ")
+		writeJsonValue(buf, p.Value, String)
+	}
+	for i, c := range columns {
+		if i > 0 || len(prefix) > 0 {
+			buf.WriteString(", ")
+		}
+		writeJsonValue(buf, c.Key(), String)
+		buf.WriteString(": ")
+		var v any
+		if i < len(row) {
+			v = row[i]
+		}
+		writeJsonValue(buf, v, c.Kind)
+	}
+	buf.WriteString("}")
+}
+
+// writeJsonValue writes numbers and booleans as JSON literals and everything else,
+// including large amounts, as strings.
+func writeJsonValue(buf *bytes.Buffer, v any, kind Kind) {
+	if v == nil {
+		buf.WriteString("null")
+		return
+	}
+	s := Format(v)
+	if kind.numeric() || kind == Bool {
+		if json.Valid([]byte(s)) {
+			buf.WriteString(s)
+			return
+		}
+	}
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
@@ -0,0 +1,188 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+type address string
+
+func (a address) Hex() string {
+	return string(a)
+}
+
+func testTable() *Table {
+	t := NewTable("accounting.CountByAsset")
+	t.AddSummary("Number of Assets", 2).AddSummary("Number of Transfers", 5)
+	s := t.AddSection("Priced Assets", "Count", "Asset", "Sender Name", "Amount")
+	s.AddSummary("Transfers", 5)
+	s.Append(uint64(3), address("0x0000000000000000000000000000000000000001"), "Rush, Jay", big.NewInt(1000))
+	s.Append(uint64(2), address("0x0000000000000000000000000000000000000002"), "", nil)
+	return t
+}
+
+func TestColumnKinds(t *testing.T) {
+	s := testTable().Sections[0]
+	want := []Kind{Int, Address, String, Amount}
+	for i, c := range s.Columns {
+		if c.Kind != want[i] {
+			t.Errorf("Column %s kind differs: got %s, want %s", c.Name, c.Kind, want[i])
+		}
+	}
+	if got := s.Columns[2].Key(); got != "senderName" {
+		t.Errorf("Key differs: got %q, want %q", got, "senderName")
+	}
+}
+
+func TestRenderCsv(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, "csv", testTable()); err != nil {
+		t.Fatal(err)
+	}
+	want := `accounting.CountByAsset
+Number of Assets: 2
+Number of Transfers: 5
+
+Priced Assets
+Transfers: 5
+Count,Asset,Sender Name,Amount
+3,0x0000000000000000000000000000000000000001,"Rush, Jay",1000
+2,0x0000000000000000000000000000000000000002,,
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Render differs:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderTsv(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, "tsv", testTable()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "3\t0x0000000000000000000000000000000000000001\tRush, Jay\t1000\n") {
+		t.Errorf("Render differs: got\n%s", buf.String())
+	}
+}
+
+func TestRenderJson(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, "json", testTable(), NewTable("empty")); err != nil {
+		t.Fatal(err)
+	}
+	var got []struct {
+		Name     string         `json:"name"`
+		Summary  map[string]any `json:"summary"`
+		Sections []struct {
+			Name string           `json:"name"`
+			Rows []map[string]any `json:"rows"`
+		} `json:"sections"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, buf.String())
+	}
+	if len(got) != 2 || got[0].Summary["Number of Assets"] != 2.0 {
+		t.Fatalf("Render differs: got %+v", got)
+	}
+	row := got[0].Sections[0].Rows[0]
+	if row["count"] != 3.0 || row["senderName"] != "Rush, Jay" || row["amount"] != "1000" {
+		t.Errorf("Row differs: got %v", row)
+	}
+	if got[0].Sections[0].Rows[1]["amount"] != nil {
+		t.Errorf("Missing value should be null: got %v", got[0].Sections[0].Rows[1]["amount"])
+	}
+}
+
+func TestRenderNdjson(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, "ndjson", testTable()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Render differs: got %d lines, want 3", len(lines))
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("invalid json line: %s", line)
+		}
+	}
+	if !strings.HasPrefix(lines[1], `{"type": "row", "table": "accounting.CountByAsset", "section": "Priced Assets", "count": 3,`) {
+		t.Errorf("Row differs: got %s", lines[1])
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, "md", testTable()); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"## accounting.CountByAsset\n", "- **Number of Assets**: 2\n", "### Priced Assets\n", "| Count | Asset | Sender Name | Amount |\n| ---: | --- | --- | --- |\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Render does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, "txt", testTable()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	var header, first string
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "Count") {
+			header, first = line, lines[i+1]
+		}
+	}
+	if strings.Index(header, "Asset") != strings.Index(first, "0x") {
+		t.Errorf("Columns are not aligned:\n%s\n%s", header, first)
+	}
+	if !strings.HasPrefix(first, "    3") {
+		t.Errorf("Numbers should align right: got %q", first)
+	}
+}
+
+func TestRenderUnknown(t *testing.T) {
+	if err := Render(&bytes.Buffer{}, "xml", testTable()); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	gnosis := NewTable("accounting.CountByAsset")
+	gnosis.AddSummary("Number of Assets", 1)
+	gnosis.AddSection("Priced Assets", "Count", "Asset", "Sender Name", "Amount").
+		Append(uint64(7), address("0x0000000000000000000000000000000000000003"), "", big.NewInt(10))
+
+	var buf bytes.Buffer
+	merged := Merge("Chain", []string{"gnosis", "mainnet", "sepolia"}, []*Table{gnosis, testTable(), nil})
+	if err := Render(&buf, "csv", merged); err != nil {
+		t.Fatal(err)
+	}
+	want := `accounting.CountByAsset
+Number of Assets (gnosis): 1
+Number of Assets (mainnet): 2
+Number of Transfers (mainnet): 5
+
+Priced Assets
+Transfers (mainnet): 5
+Chain,Count,Asset,Sender Name,Amount
+gnosis,7,0x0000000000000000000000000000000000000003,,10
+mainnet,3,0x0000000000000000000000000000000000000001,"Rush, Jay",1000
+mainnet,2,0x0000000000000000000000000000000000000002,,
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Render differs:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	if merged := Merge("Chain", []string{"mainnet"}, []*Table{testTable()}); merged.Summary[0].Name != "Number of Assets" {
+		t.Errorf("Summary of a single table renamed to %q", merged.Summary[0].Name)
+	}
+	if merged := Merge("Chain", []string{"mainnet"}, []*Table{nil}); merged != nil {
+		t.Errorf("Merge of failed tables: got %v, want nil", merged)
+	}
+}
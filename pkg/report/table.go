@@ -0,0 +1,258 @@
+package report
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Kind describes how the values of a column are rendered.
+type Kind int
+
+const (
+	Auto Kind = iota
+	String
+	Int
+	Float
+	Bool
+	Address
+	Hash
+	Amount
+	Date
+)
+
+var kindNames = map[Kind]string{
+	Auto:    "auto",
+	String:  "string",
+	Int:     "int",
+	Float:   "float",
+	Bool:    "bool",
+	Address: "address",
+	Hash:    "hash",
+	Amount:  "amount",
+	Date:    "date",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// numeric returns true for kinds that are right aligned and emitted as JSON numbers.
+func (k Kind) numeric() bool {
+	return k == Int || k == Float
+}
+
+// --------------------------------
+type Column struct {
+	Name string
+	Kind Kind
+}
+
+// Key is the name of the column as used in JSON output (e.g. "Sender Name" is senderName).
+func (c Column) Key() string {
+	words := strings.FieldsFunc(c.Name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	ret := ""
+	for i, w := range words {
+		if i == 0 {
+			ret += strings.ToLower(w[:1]) + w[1:]
+		} else {
+			ret += strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return ret
+}
+
+// --------------------------------
+type Pair struct {
+	Name  string
+	Value any
+}
+
+// --------------------------------
+// Section is a named block of rows sharing the same columns.
+type Section struct {
+	Name    string
+	Summary []Pair
+	Columns []Column
+	Rows    [][]any
+}
+
+// AddSummary records a named value shown above the rows of the section.
+func (s *Section) AddSummary(name string, value any) *Section {
+	s.Summary = append(s.Summary, Pair{Name: name, Value: value})
+	return s
+}
+
+// Append adds a row. Columns created without a kind take the kind of the first non-nil
+// value appended to them.
+func (s *Section) Append(values ...any) {
+	for i, v := range values {
+		if i < len(s.Columns) && s.Columns[i].Kind == Auto && v != nil {
+			s.Columns[i].Kind = kindOf(v)
+		}
+	}
+	s.Rows = append(s.Rows, values)
+}
+
+// --------------------------------
+// Table is the result of a traverser: a set of summary values followed by sections.
+type Table struct {
+	Name     string
+	Summary  []Pair
+	Sections []*Section
+}
+
+func NewTable(name string) *Table {
+	return &Table{Name: name}
+}
+
+func (t *Table) AddSummary(name string, value any) *Table {
+	t.Summary = append(t.Summary, Pair{Name: name, Value: value})
+	return t
+}
+
+// AddSection adds a section whose columns take their kinds from the first row.
+func (t *Table) AddSection(name string, columns ...string) *Section {
+	cols := make([]Column, 0, len(columns))
+	for _, c := range columns {
+		cols = append(cols, Column{Name: c})
+	}
+	return t.AddTypedSection(name, cols...)
+}
+
+func (t *Table) AddTypedSection(name string, columns ...Column) *Section {
+	s := &Section{Name: name, Columns: columns}
+	t.Sections = append(t.Sections, s)
+	return s
+}
+
+// Merge combines the tables produced for each of several keys (for example, the same
+// report for each chain) into one, adding a column holding the key in front of every
+// section. Sections are matched by name. With more than one table, the key is added to
+// the names of the summary values. Nil tables are skipped, and if all are nil so is the
+// result.
+func Merge(column string, keys []string, tables []*Table) *Table {
+	var ret *Table
+	n := 0
+	for _, t := range tables {
+		if t != nil {
+			n++
+		}
+	}
+	sections := map[string]*Section{}
+	for i, t := range tables {
+		if t == nil {
+			continue
+		}
+		if ret == nil {
+			ret = NewTable(t.Name)
+		}
+		ret.Summary = append(ret.Summary, keyed(t.Summary, keys[i], n)...)
+		seen := map[string]int{}
+		for _, s := range t.Sections {
+			id := fmt.Sprintf("%s/%d", s.Name, seen[s.Name])
+			seen[s.Name]++
+			merged := sections[id]
+			if merged == nil {
+				merged = ret.AddTypedSection(s.Name, append([]Column{{Name: column, Kind: String}}, s.Columns...)...)
+				sections[id] = merged
+			}
+			for j, c := range s.Columns {
+				if j+1 < len(merged.Columns) && merged.Columns[j+1].Kind == Auto {
+					merged.Columns[j+1].Kind = c.Kind
+				}
+			}
+			merged.Summary = append(merged.Summary, keyed(s.Summary, keys[i], n)...)
+			for _, row := range s.Rows {
+				merged.Rows = append(merged.Rows, append([]any{keys[i]}, row...))
+			}
+		}
+	}
+	return ret
+}
+
+func keyed(pairs []Pair, key string, n int) []Pair {
+	if n < 2 {
+		return pairs
+	}
+	ret := make([]Pair, 0, len(pairs))
+	for _, p := range pairs {
+		ret = append(ret, Pair{Name: p.Name + " (" + key + ")", Value: p.Value})
+	}
+	return ret
+}
+
+type hexer interface {
+	Hex() string
+}
+
+type texter interface {
+	Text(base int) string
+}
+
+type formatter interface {
+	Format(layout string) string
+}
+
+func kindOf(v any) Kind {
+	switch x := v.(type) {
+	case string:
+		return String
+	case bool:
+		return Bool
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return Int
+	case float32, float64:
+		return Float
+	case *big.Int, *big.Float, texter:
+		return Amount
+	case formatter:
+		return Date
+	case hexer:
+		if len(x.Hex()) == 66 {
+			return Hash
+		}
+		return Address
+	}
+	return String
+}
+
+// Format renders a single value as text.
+func Format(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case bool:
+		return strconv.FormatBool(x)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case *big.Float:
+		return x.Text('f', -1)
+	case texter:
+		return x.Text(10)
+	case hexer:
+		return x.Hex()
+	case formatter:
+		return x.Format("2006-01-02 15:04:05")
+	case interface{ String() string }:
+		return x.String()
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10)
+	}
+	return ""
+}
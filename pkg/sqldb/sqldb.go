@@ -0,0 +1,346 @@
+// Package sqldb keeps the records of the traversers in a SQLite database, through the pure
+// Go driver of modernc.org/sqlite (so no C compiler is needed). The database holds:
+//
+//	runs        one row for each run that wrote to it: when it started and finished, the
+//	            chain, the options that shaped its records (as JSON) and what it wrote
+//	accounts    the accounts of interest: address, name and tags
+//	assets      the assets of the statements: address, symbol and decimals
+//	names       the names of the addresses the statements refer to
+//	statements  the statements, one for each (block, tx, log, accountedFor, asset)
+//	logs        the logs, one for each (block, tx, log)
+//
+// Addresses and hashes are lower case hex. Amounts are decimal text, as they overflow the
+// integers of SQLite. Times are Unix seconds along with the date as text. Statements and
+// logs refer to the run that last wrote them.
+//
+// Writing a record already in the database replaces it, so that resumed, watched or
+// repeated runs over the same records leave one copy of each. Records are written in
+// batches, each in a transaction of its own. A batch that fails is written again one
+// record at a time, so that only the records that cannot be written are lost.
+package sqldb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	_ "modernc.org/sqlite"
+)
+
+// Schema creates the tables of the database, unless they exist.
+const Schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	started    TEXT NOT NULL,
+	finished   TEXT,
+	chain      TEXT NOT NULL,
+	options    TEXT NOT NULL,
+	statements INTEGER NOT NULL DEFAULT 0,
+	logs       INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS accounts (
+	address TEXT PRIMARY KEY,
+	name    TEXT NOT NULL,
+	tags    TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS assets (
+	address  TEXT PRIMARY KEY,
+	symbol   TEXT NOT NULL,
+	decimals INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS names (
+	address TEXT PRIMARY KEY,
+	name    TEXT NOT NULL,
+	symbol  TEXT NOT NULL,
+	tags    TEXT NOT NULL,
+	source  TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS statements (
+	block_number      INTEGER NOT NULL,
+	transaction_index INTEGER NOT NULL,
+	log_index         INTEGER NOT NULL,
+	accounted_for     TEXT NOT NULL,
+	asset             TEXT NOT NULL,
+	transaction_hash  TEXT NOT NULL,
+	timestamp         INTEGER NOT NULL,
+	date              TEXT NOT NULL,
+	sender            TEXT NOT NULL,
+	recipient         TEXT NOT NULL,
+	symbol            TEXT NOT NULL,
+	decimals          INTEGER NOT NULL,
+	beg_bal           TEXT NOT NULL,
+	amount_in         TEXT NOT NULL,
+	internal_in       TEXT NOT NULL,
+	amount_out        TEXT NOT NULL,
+	internal_out      TEXT NOT NULL,
+	gas_out           TEXT NOT NULL,
+	end_bal           TEXT NOT NULL,
+	spot_price        REAL NOT NULL,
+	price_source      TEXT NOT NULL,
+	reconciled        INTEGER NOT NULL,
+	run_id            INTEGER NOT NULL REFERENCES runs(id),
+	PRIMARY KEY (block_number, transaction_index, log_index, accounted_for, asset)
+);
+CREATE INDEX IF NOT EXISTS statements_by_account ON statements (accounted_for, asset, block_number);
+CREATE TABLE IF NOT EXISTS logs (
+	block_number      INTEGER NOT NULL,
+	transaction_index INTEGER NOT NULL,
+	log_index         INTEGER NOT NULL,
+	address           TEXT NOT NULL,
+	topic0            TEXT NOT NULL,
+	topic1            TEXT NOT NULL,
+	topic2            TEXT NOT NULL,
+	topic3            TEXT NOT NULL,
+	data              TEXT NOT NULL,
+	transaction_hash  TEXT NOT NULL,
+	timestamp         INTEGER NOT NULL,
+	date              TEXT NOT NULL,
+	run_id            INTEGER NOT NULL REFERENCES runs(id),
+	PRIMARY KEY (block_number, transaction_index, log_index)
+);
+`
+
+const (
+	upsertAccount = `INSERT INTO accounts (address, name, tags) VALUES (?, ?, ?)
+	ON CONFLICT (address) DO UPDATE SET name = excluded.name, tags = excluded.tags`
+
+	upsertAsset = `INSERT INTO assets (address, symbol, decimals) VALUES (?, ?, ?)
+	ON CONFLICT (address) DO UPDATE SET symbol = excluded.symbol, decimals = excluded.decimals`
+
+	upsertName = `INSERT INTO names (address, name, symbol, tags, source) VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT (address) DO UPDATE SET name = excluded.name, symbol = excluded.symbol, tags = excluded.tags, source = excluded.source`
+
+	upsertStatement = `INSERT INTO statements (block_number, transaction_index, log_index, accounted_for, asset,
+		transaction_hash, timestamp, date, sender, recipient, symbol, decimals,
+		beg_bal, amount_in, internal_in, amount_out, internal_out, gas_out, end_bal,
+		spot_price, price_source, reconciled, run_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (block_number, transaction_index, log_index, accounted_for, asset) DO UPDATE SET
+		transaction_hash = excluded.transaction_hash, timestamp = excluded.timestamp, date = excluded.date,
+		sender = excluded.sender, recipient = excluded.recipient, symbol = excluded.symbol, decimals = excluded.decimals,
+		beg_bal = excluded.beg_bal, amount_in = excluded.amount_in, internal_in = excluded.internal_in,
+		amount_out = excluded.amount_out, internal_out = excluded.internal_out, gas_out = excluded.gas_out,
+		end_bal = excluded.end_bal, spot_price = excluded.spot_price, price_source = excluded.price_source,
+		reconciled = excluded.reconciled, run_id = excluded.run_id`
+
+	upsertLog = `INSERT INTO logs (block_number, transaction_index, log_index, address,
+		topic0, topic1, topic2, topic3, data, transaction_hash, timestamp, date, run_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (block_number, transaction_index, log_index) DO UPDATE SET
+		address = excluded.address, topic0 = excluded.topic0, topic1 = excluded.topic1,
+		topic2 = excluded.topic2, topic3 = excluded.topic3, data = excluded.data,
+		transaction_hash = excluded.transaction_hash, timestamp = excluded.timestamp,
+		date = excluded.date, run_id = excluded.run_id`
+)
+
+// BatchSize is the number of records written in each transaction.
+const BatchSize = 500
+
+// --------------------------------
+// DB is a database opened for a run. Records added to it are written once a batch is
+// full, and when the database is flushed or closed. Statements and Logs count those
+// written.
+type DB struct {
+	Path       string
+	Run        int64
+	Statements int
+	Logs       int
+	db         *sql.DB
+	pending    []upsert
+	assets     map[base.Address]bool
+	names      map[base.Address]bool
+}
+
+// upsert is a record waiting to be written: what it is, for errors, its query and
+// arguments, and the count to add it to once written.
+type upsert struct {
+	what  string
+	query string
+	args  []any
+	count *int
+}
+
+// Open opens (or creates) the database and records the start of a run over the chain
+// with the given options, which are saved as JSON.
+func Open(path, chain string, options any) (*DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	// One connection, so that every statement sees the same database
+	db.SetMaxOpenConns(1)
+
+	ret := &DB{Path: path, db: db, assets: map[base.Address]bool{}, names: map[base.Address]bool{}}
+	if err := ret.start(chain, options); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ret, nil
+}
+
+func (d *DB) start(chain string, options any) error {
+	for _, pragma := range []string{"PRAGMA foreign_keys = ON", "PRAGMA busy_timeout = 5000"} {
+		if _, err := d.db.Exec(pragma); err != nil {
+			return err
+		}
+	}
+	if _, err := d.db.Exec(Schema); err != nil {
+		return err
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	res, err := d.db.Exec(`INSERT INTO runs (started, chain, options) VALUES (?, ?, ?)`, now(), chain, string(data))
+	if err != nil {
+		return err
+	}
+	d.Run, err = res.LastInsertId()
+	return err
+}
+
+// AddAccount writes an account of interest.
+func (d *DB) AddAccount(account types.Name) error {
+	return d.add(upsert{what: "account " + hex(account.Address), query: upsertAccount, args: []any{hex(account.Address), account.Name, account.Tags}})
+}
+
+// AddName writes the name of an address, once per run.
+func (d *DB) AddName(name types.Name) error {
+	if d.names[name.Address] {
+		return nil
+	}
+	d.names[name.Address] = true
+	return d.add(upsert{what: "name of " + hex(name.Address), query: upsertName, args: []any{hex(name.Address), name.Name, name.Symbol, name.Tags, name.Source}})
+}
+
+// AddStatement writes a statement, and its asset the first time the run sees it.
+func (d *DB) AddStatement(s *types.Statement) error {
+	if !d.assets[s.Asset] {
+		d.assets[s.Asset] = true
+		if err := d.add(upsert{what: "asset " + hex(s.Asset), query: upsertAsset, args: []any{hex(s.Asset), s.Symbol, int64(s.Decimals)}}); err != nil {
+			return err
+		}
+	}
+	return d.add(upsert{
+		what:  fmt.Sprintf("statement of %s for %s at %d.%d.%d", hex(s.AccountedFor), hex(s.Asset), s.BlockNumber, s.TransactionIndex, s.LogIndex),
+		query: upsertStatement,
+		args: []any{
+			int64(s.BlockNumber), int64(s.TransactionIndex), int64(s.LogIndex), hex(s.AccountedFor), hex(s.Asset),
+			s.TransactionHash.Hex(), int64(s.Timestamp), date(s.Timestamp), hex(s.Sender), hex(s.Recipient), s.Symbol, int64(s.Decimals),
+			s.BegBal.String(), s.AmountIn.String(), s.InternalIn.String(), s.AmountOut.String(), s.InternalOut.String(), s.GasOut.String(), s.EndBal.String(),
+			s.SpotPrice.Float64(), s.PriceSource, s.Reconciled(), d.Run,
+		},
+		count: &d.Statements,
+	})
+}
+
+// AddLog writes a log.
+func (d *DB) AddLog(l *types.Log) error {
+	topics := make([]string, 4)
+	for i := 0; i < len(topics) && i < len(l.Topics); i++ {
+		topics[i] = l.Topics[i].Hex()
+	}
+	return d.add(upsert{
+		what:  fmt.Sprintf("log of %s at %d.%d.%d", hex(l.Address), l.BlockNumber, l.TransactionIndex, l.LogIndex),
+		query: upsertLog,
+		args: []any{
+			int64(l.BlockNumber), int64(l.TransactionIndex), int64(l.LogIndex), hex(l.Address),
+			topics[0], topics[1], topics[2], topics[3], l.Data, l.TransactionHash.Hex(), int64(l.Timestamp), date(l.Timestamp), d.Run,
+		},
+		count: &d.Logs,
+	})
+}
+
+func (d *DB) add(u upsert) error {
+	d.pending = append(d.pending, u)
+	if len(d.pending) < BatchSize {
+		return nil
+	}
+	return d.Flush()
+}
+
+// Flush writes the records added since the last batch, in one transaction. If that fails,
+// the records are written again one at a time, and the error names each that failed.
+// Either way, the batch is done with: records that cannot be written are not retried.
+func (d *DB) Flush() error {
+	batch := d.pending
+	d.pending = nil
+	if len(batch) == 0 {
+		return nil
+	}
+	if d.write(batch) == nil {
+		return nil
+	}
+	errs := []error{}
+	for _, u := range batch {
+		if err := d.write([]upsert{u}); err != nil {
+			errs = append(errs, fmt.Errorf("writing the %s: %w", u.what, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// write writes the records in one transaction, and counts them once it is committed.
+func (d *DB) write(batch []upsert) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	prepared := map[string]*sql.Stmt{}
+	for _, u := range batch {
+		stmt, ok := prepared[u.query]
+		if !ok {
+			if stmt, err = tx.Prepare(u.query); err != nil {
+				tx.Rollback()
+				return err
+			}
+			defer stmt.Close()
+			prepared[u.query] = stmt
+		}
+		if _, err := stmt.Exec(u.args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, u := range batch {
+		if u.count != nil {
+			*u.count++
+		}
+	}
+	return nil
+}
+
+// Close writes what is left to write, records the end of the run and closes the database.
+func (d *DB) Close() error {
+	err := d.Flush()
+	if err == nil {
+		_, err = d.db.Exec(`UPDATE runs SET finished = ?, statements = ?, logs = ? WHERE id = ?`, now(), d.Statements, d.Logs, d.Run)
+	}
+	if cerr := d.db.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", d.Path, err)
+	}
+	return nil
+}
+
+func hex(a base.Address) string {
+	return strings.ToLower(a.Hex())
+}
+
+func date(ts base.Timestamp) string {
+	return time.Unix(int64(ts), 0).UTC().Format(time.RFC3339)
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,143 @@
+package sqldb
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/synth"
+)
+
+var account = types.Name{Address: base.HexToAddress("0xf503017d7baf7fbc0fff7492b751025c6a78179b"), Name: "Treasury", Tags: "00-Active"}
+
+// ledger returns more statements than a batch holds, and the logs, of a synthetic history.
+func ledger(t *testing.T) ([]*types.Statement, []*types.Log) {
+	t.Helper()
+	l := synth.Generate(synth.Config{Seed: 1, Accounts: []base.Address{account.Address}, Txs: 500})
+	statements, logs := l.Statements[account.Address], l.Logs[account.Address]
+	if len(statements) <= BatchSize {
+		t.Fatalf("got %d statements, want more than %d", len(statements), BatchSize)
+	}
+	return statements, logs
+}
+
+// query checks the answers of the database to each query.
+func query(t *testing.T, path string, tests []struct{ query, want string }) {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, tt := range tests {
+		var got string
+		if err := db.QueryRow(tt.query).Scan(&got); err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestUpserts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Ledger.sqlite")
+	statements, logs := ledger(t)
+	assets := map[base.Address]bool{}
+	for _, s := range statements {
+		assets[s.Asset] = true
+	}
+	// The second run changes a statement already written
+	changed := *statements[0]
+	changed.AmountIn = *base.NewWei(1)
+
+	// Two runs write the same records over
+	for run := int64(1); run <= 2; run++ {
+		db, err := Open(path, "mainnet", map[string]any{"period": "monthly"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if db.Run != run {
+			t.Errorf("got run %d, want %d", db.Run, run)
+		}
+		if err := db.AddAccount(account); err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range statements {
+			if err := db.AddStatement(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, l := range logs {
+			if err := db.AddLog(l); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if run == 2 {
+			if err := db.AddStatement(&changed); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := fmt.Sprintf("block_number = %d AND transaction_index = %d AND log_index = %d AND asset = '%s'",
+		changed.BlockNumber, changed.TransactionIndex, changed.LogIndex, hex(changed.Asset))
+	query(t, path, []struct{ query, want string }{
+		{"SELECT count(*) FROM statements", fmt.Sprint(len(statements))},
+		{"SELECT count(*) FROM statements WHERE run_id = 2", fmt.Sprint(len(statements))},
+		{"SELECT amount_in FROM statements WHERE " + first, "1"},
+		{"SELECT count(*) FROM logs", fmt.Sprint(len(logs))},
+		{"SELECT count(*) FROM assets", fmt.Sprint(len(assets))},
+		{"SELECT count(*) FROM accounts", "1"},
+		{"SELECT count(*) FROM runs WHERE finished IS NOT NULL", "2"},
+		{"SELECT statements FROM runs WHERE id = 2", fmt.Sprint(len(statements) + 1)},
+		{"SELECT logs FROM runs WHERE id = 2", fmt.Sprint(len(logs))},
+		{"SELECT options FROM runs WHERE id = 1", `{"period":"monthly"}`},
+	})
+}
+
+func TestFlushFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Ledger.sqlite")
+	statements, _ := ledger(t)
+	db, err := Open(path, "mainnet", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One statement of the first batch refers to a run that does not exist
+	bad := statements[10]
+	errs := []error{}
+	for _, s := range statements {
+		if s == bad {
+			db.Run = 99
+		}
+		if err := db.AddStatement(s); err != nil {
+			errs = append(errs, err)
+		}
+		db.Run = 1
+	}
+	want := fmt.Sprintf("writing the statement of %s for %s at %d.%d.%d: ", hex(bad.AccountedFor), hex(bad.Asset), bad.BlockNumber, bad.TransactionIndex, bad.LogIndex)
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), want) {
+		t.Errorf("got errors %v, want one starting %q", errs, want)
+	}
+	// The rest of the batch is written, and the bad statement not tried again
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if db.Statements != len(statements)-1 {
+		t.Errorf("got %d statements written, want %d", db.Statements, len(statements)-1)
+	}
+
+	query(t, path, []struct{ query, want string }{
+		{"SELECT count(*) FROM statements", fmt.Sprint(len(statements) - 1)},
+		{"SELECT count(*) FROM statements WHERE run_id = 99", "0"},
+		{"SELECT statements FROM runs WHERE id = 1", fmt.Sprint(len(statements) - 1)},
+	})
+}
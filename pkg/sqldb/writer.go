@@ -0,0 +1,73 @@
+package sqldb
+
+import (
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+// --------------------------------
+// Writer is the database of a traverser writing its records to Ledger.sqlite. The
+// database is opened for a run, with the accounts of interest written, when first asked
+// for, and Report closes the run. A watch feeds more records after the result of each
+// pass, so each pass is a run of its own. Traversers embed a Writer and only add their
+// records to its DB.
+type Writer struct {
+	opts    traverser.Options
+	records string
+	db      *DB
+}
+
+// NewWriter returns the writer of a traverser. Records names what it writes, as in
+// "Statements", in its report.
+func NewWriter(opts traverser.Options, records string) *Writer {
+	return &Writer{opts: opts, records: records}
+}
+
+// Init opens the database, so that a database that cannot be written fails the run
+// before any record is read.
+func (w *Writer) Init() error {
+	_, err := w.DB()
+	return err
+}
+
+// DB returns the database of the current run, opening it if need be.
+func (w *Writer) DB() (*DB, error) {
+	if w.db != nil {
+		return w.db, nil
+	}
+	db, err := Open(w.opts.ChainFile("Ledger.sqlite"), w.opts.Chain, w.opts.RunOptions())
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range w.opts.AccountsOfInterest() {
+		if err := db.AddAccount(account); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	w.db = db
+	return db, nil
+}
+
+// Report closes the run and reports the file, the run and the number of records written
+// (a traverser writes either statements or logs) in a table of the given name.
+func (w *Writer) Report(name string) (*report.Table, error) {
+	db, err := w.DB()
+	if err != nil {
+		return nil, err
+	}
+	w.db = nil
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+
+	t := report.NewTable(name)
+	t.AddSection("", "File", "Run", w.records).Append(db.Path, db.Run, db.Statements+db.Logs)
+	return t, nil
+}
+
+// State is empty: writing a record again replaces it, so a resumed run needs nothing to
+// write on from where it was.
+func (w *Writer) State() any {
+	return &struct{}{}
+}
@@ -0,0 +1,243 @@
+package synth
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+)
+
+// selectors are the four byte selectors of the functions transactions call.
+var selectors = map[string]string{
+	"transfer":              "a9059cbb",
+	"swapExactTokensForETH": "18cbafe5",
+	"airdrop":               "8f1a2a5c",
+}
+
+// record turns the transaction into records for each of the accounts it concerns,
+// updating their balances.
+func (g *generator) record(t *tx) {
+	blockHash := base.HexToHash(fmt.Sprintf("0x%064x", t.block))
+	ts := base.Timestamp(t.time.Unix())
+
+	trans := &types.Transaction{
+		BlockHash:        blockHash,
+		BlockNumber:      t.block,
+		TransactionIndex: t.index,
+		Timestamp:        ts,
+		Hash:             t.hash,
+		From:             t.from,
+		To:               t.to,
+		Value:            *wei(t.value()),
+		Gas:              t.gasUsed * 3 / 2,
+		GasPrice:         t.gasPrice,
+		GasUsed:          t.gasUsed,
+		Input:            "0x",
+		Nonce:            g.nonces[t.from],
+	}
+	g.nonces[t.from]++
+	if t.function != "" {
+		trans.ArticulatedTx = &types.Function{Name: t.function, Encoding: "0x" + selectors[t.function]}
+		trans.Input += selectors[t.function]
+	}
+
+	receipt := &types.Receipt{
+		BlockHash:         blockHash,
+		BlockNumber:       t.block,
+		TransactionIndex:  t.index,
+		TransactionHash:   t.hash,
+		From:              t.from,
+		To:                t.to,
+		GasUsed:           t.gasUsed,
+		CumulativeGasUsed: t.gasUsed,
+		EffectiveGasPrice: t.gasPrice,
+		Status:            1,
+		Logs:              []types.Log{},
+	}
+	trans.Receipt = receipt
+
+	traces := []*types.Trace{{
+		BlockHash:        blockHash,
+		BlockNumber:      t.block,
+		TransactionIndex: t.index,
+		TransactionHash:  t.hash,
+		Timestamp:        ts,
+		TraceAddress:     []uint64{},
+		TraceType:        "call",
+		Action:           &types.TraceAction{CallType: "call", From: t.from, To: t.to, Gas: trans.Gas, Input: trans.Input, Value: *wei(t.value())},
+		Result:           &types.TraceResult{GasUsed: t.gasUsed, Output: "0x"},
+	}}
+
+	logs := []*types.Log{}
+	for _, tr := range t.transfers {
+		switch {
+		case tr.internal:
+			traces[0].Subtraces++
+			traces = append(traces, &types.Trace{
+				BlockHash:        blockHash,
+				BlockNumber:      t.block,
+				TransactionIndex: t.index,
+				TransactionHash:  t.hash,
+				Timestamp:        ts,
+				TraceAddress:     []uint64{traces[0].Subtraces - 1},
+				TraceType:        "call",
+				Action:           &types.TraceAction{CallType: "call", From: tr.from, To: tr.to, Gas: 2300, Input: "0x", Value: *wei(tr.amount)},
+				Result:           &types.TraceResult{Output: "0x"},
+			})
+		case tr.asset != eth:
+			trans.HasToken = true
+			log := types.Log{
+				Address:          tr.asset.Address,
+				BlockHash:        blockHash,
+				BlockNumber:      t.block,
+				TransactionIndex: t.index,
+				TransactionHash:  t.hash,
+				LogIndex:         tr.logIndex,
+				Timestamp:        ts,
+				Topics:           []base.Hash{transferTopic, base.HexToHash(tr.from.Hex()), base.HexToHash(tr.to.Hex())},
+				Data:             fmt.Sprintf("0x%064x", tr.amount),
+				Articulated:      &types.Function{Name: "Transfer", Encoding: "0xddf252ad"},
+			}
+			receipt.Logs = append(receipt.Logs, log)
+			logs = append(logs, &log)
+		}
+	}
+
+	for _, account := range g.cfg.Accounts {
+		reason := g.reason(t, account)
+		if reason == "" {
+			continue
+		}
+		g.ledger.Appearances[account] = append(g.ledger.Appearances[account], &types.Appearance{
+			Address:          account,
+			BlockNumber:      uint32(t.block),
+			TransactionIndex: uint32(t.index),
+			Timestamp:        ts,
+			Reason:           reason,
+		})
+		g.ledger.Transactions[account] = append(g.ledger.Transactions[account], trans)
+		g.ledger.Receipts[account] = append(g.ledger.Receipts[account], receipt)
+		g.ledger.Traces[account] = append(g.ledger.Traces[account], traces...)
+		g.ledger.Logs[account] = append(g.ledger.Logs[account], logs...)
+		g.ledger.Statements[account] = append(g.ledger.Statements[account], g.statements(t, account)...)
+	}
+}
+
+// reason returns why the account appears in the transaction, or an empty string if it
+// does not.
+func (g *generator) reason(t *tx, account base.Address) string {
+	switch {
+	case t.from == account:
+		return "from"
+	case t.to == account:
+		return "to"
+	}
+	for _, tr := range t.transfers {
+		if tr.from == account || tr.to == account {
+			if tr.internal {
+				return "trace"
+			}
+			return "log"
+		}
+	}
+	return ""
+}
+
+// statements returns the account's statements for the transaction: one for ether if it
+// sent the transaction or moved ether, then one for each token transfer, in log order.
+func (g *generator) statements(t *tx, account base.Address) []*types.Statement {
+	ret := []*types.Statement{}
+
+	var in, out, internalIn, internalOut, gas big.Int
+	moved := false
+	for _, tr := range t.transfers {
+		if tr.asset != eth || (tr.from != account && tr.to != account) {
+			continue
+		}
+		moved = true
+		switch {
+		case tr.to == account && tr.internal:
+			internalIn.Add(&internalIn, tr.amount)
+		case tr.to == account:
+			in.Add(&in, tr.amount)
+		case tr.internal:
+			internalOut.Add(&internalOut, tr.amount)
+		default:
+			out.Add(&out, tr.amount)
+		}
+	}
+	if t.from == account {
+		gas.Set(t.gas())
+	}
+	if moved || t.from == account {
+		s := g.statement(t, account, eth, t.from, t.to, 0)
+		s.AmountIn, s.AmountOut = *wei(&in), *wei(&out)
+		s.InternalIn, s.InternalOut = *wei(&internalIn), *wei(&internalOut)
+		s.GasOut = *wei(&gas)
+		g.reconcile(s, new(big.Int).Add(&in, &internalIn), new(big.Int).Add(new(big.Int).Add(&out, &internalOut), &gas))
+		ret = append(ret, s)
+	}
+
+	for _, tr := range t.transfers {
+		if tr.asset == eth || (tr.from != account && tr.to != account) {
+			continue
+		}
+		s := g.statement(t, account, tr.asset, tr.from, tr.to, tr.logIndex)
+		if tr.to == account {
+			s.AmountIn = *wei(tr.amount)
+			g.reconcile(s, tr.amount, new(big.Int))
+		} else {
+			s.AmountOut = *wei(tr.amount)
+			g.reconcile(s, new(big.Int), tr.amount)
+		}
+		ret = append(ret, s)
+	}
+	return slices.Clip(ret)
+}
+
+// statement returns the account's statement of the asset in the transaction, without
+// amounts or balances.
+func (g *generator) statement(t *tx, account base.Address, a asset, sender, recipient base.Address, logIndex base.Lognum) *types.Statement {
+	s := &types.Statement{
+		AccountedFor:     account,
+		Asset:            a.Address,
+		Symbol:           a.Symbol,
+		Decimals:         base.Value(a.Decimals),
+		BlockNumber:      t.block,
+		TransactionIndex: t.index,
+		TransactionHash:  t.hash,
+		LogIndex:         logIndex,
+		Timestamp:        base.Timestamp(t.time.Unix()),
+		Sender:           sender,
+		Recipient:        recipient,
+		PriceSource:      "not-priced",
+	}
+	if price := g.spotPrice(a, t.time); price != 0 {
+		s.SpotPrice = *base.NewFloat(price)
+		s.PriceSource = "uniswap"
+		if a != eth {
+			s.PriceSource = "stable-coin"
+		}
+	}
+	return s
+}
+
+// reconcile sets the statement's balances from the account's balance of the asset, which
+// grows by what came in and shrinks by what went out.
+func (g *generator) reconcile(s *types.Statement, in, out *big.Int) {
+	beg, ok := g.balances[s.AccountedFor][s.Asset]
+	if !ok {
+		beg = new(big.Int)
+	}
+	end := new(big.Int).Add(beg, in)
+	end.Sub(end, out)
+	g.balances[s.AccountedFor][s.Asset] = end
+	s.BegBal, s.PrevBal, s.EndBal = *wei(beg), *wei(beg), *wei(end)
+}
+
+// wei returns a copy of the amount as a base.Wei.
+func wei(n *big.Int) *base.Wei {
+	return (*base.Wei)(new(big.Int).Set(n))
+}
@@ -0,0 +1,410 @@
+// Package synth generates made up but consistent histories for a set of accounts: every
+// statement reconciles (its beginning balance is the previous statement's ending balance,
+// and its ending balance is the beginning balance plus what came in less what went out,
+// gas included), and the logs, transactions, traces, receipts and appearances describe the
+// same transactions as the statements. The same seed always generates the same history.
+//
+// The accounts receive and send ether and priced stablecoins, pay gas on what they send,
+// move funds between each other, swap tokens for ether (which comes back as an internal
+// transfer), receive an unpriced governance token and are airdropped spam tokens. Some
+// transactions fall on the last second of a month and the first second of the next, so
+// that reports by period (and by year, as the default span crosses a new year) have
+// boundaries to get right.
+package synth
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+)
+
+// DefaultStart is when histories start if the config does not say.
+var DefaultStart = time.Date(2023, time.November, 15, 0, 0, 0, 0, time.UTC)
+
+const (
+	// DefaultMonths is how many months histories span if the config does not say.
+	DefaultMonths = 4
+	// DefaultTxs is how many transactions each account makes or receives, on average,
+	// if the config does not say.
+	DefaultTxs = 30
+)
+
+// --------------------------------
+// Config chooses the history to generate.
+type Config struct {
+	Seed     uint64
+	Accounts []base.Address
+	Start    time.Time
+	Months   int
+	Txs      int
+}
+
+// --------------------------------
+// Ledger is a generated history. Each account's records are in chronological order.
+type Ledger struct {
+	Statements   map[base.Address][]*types.Statement
+	Logs         map[base.Address][]*types.Log
+	Transactions map[base.Address][]*types.Transaction
+	Traces       map[base.Address][]*types.Trace
+	Receipts     map[base.Address][]*types.Receipt
+	Appearances  map[base.Address][]*types.Appearance
+}
+
+// --------------------------------
+// asset is a currency the accounts hold. Ether is the only one that is not a token.
+type asset struct {
+	Address  base.Address
+	Symbol   string
+	Name     string
+	Decimals uint64
+	Priced   bool
+}
+
+var (
+	eth  = asset{base.HexToAddress("0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"), "WEI", "Ether", 18, true}
+	usdc = asset{base.HexToAddress("0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"), "USDC", "USD Coin", 6, true}
+	dai  = asset{base.HexToAddress("0x6b175474e89094c44da98b954eedeac495271d0f"), "DAI", "Dai Stablecoin", 18, true}
+	gov  = asset{base.HexToAddress("0x5e00000000000000000000000000000000000001"), "SYNG", "Synthetic Governance", 18, false}
+	spam = []asset{
+		{base.HexToAddress("0x5a00000000000000000000000000000000000001"), "Visit claim-synth.io", "Visit claim-synth.io", 0, false},
+		{base.HexToAddress("0x5a00000000000000000000000000000000000002"), "$ SYNTH Airdrop", "$ SYNTH Airdrop", 18, false},
+	}
+	stables = []asset{usdc, dai}
+)
+
+var (
+	exchange = base.HexToAddress("0xe000000000000000000000000000000000000001")
+	router   = base.HexToAddress("0xe000000000000000000000000000000000000002")
+	treasury = base.HexToAddress("0xe000000000000000000000000000000000000003")
+	vendors  = []base.Address{
+		base.HexToAddress("0xe000000000000000000000000000000000000011"),
+		base.HexToAddress("0xe000000000000000000000000000000000000012"),
+		base.HexToAddress("0xe000000000000000000000000000000000000013"),
+	}
+)
+
+var transferTopic = base.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+
+// --------------------------------
+// transfer is one movement of an asset within a transaction. Ether moves either as the
+// transaction's value or, if internal, in a trace; tokens move in a Transfer log.
+type transfer struct {
+	asset    asset
+	from, to base.Address
+	amount   *big.Int
+	internal bool
+	logIndex base.Lognum
+}
+
+// tx is a generated transaction before it is turned into records.
+type tx struct {
+	hash      base.Hash
+	block     base.Blknum
+	index     base.Txnum
+	time      time.Time
+	from, to  base.Address
+	function  string
+	gasUsed   base.Gas
+	gasPrice  base.Gas
+	transfers []transfer
+}
+
+// gas returns what the sender paid for the transaction.
+func (t *tx) gas() *big.Int {
+	return new(big.Int).Mul(big.NewInt(int64(t.gasUsed)), big.NewInt(int64(t.gasPrice)))
+}
+
+// value returns the ether sent with the transaction.
+func (t *tx) value() *big.Int {
+	for _, tr := range t.transfers {
+		if tr.asset == eth && !tr.internal {
+			return tr.amount
+		}
+	}
+	return new(big.Int)
+}
+
+// --------------------------------
+// generator holds the state of the history being generated.
+type generator struct {
+	cfg      Config
+	rng      *rand.Rand
+	own      map[base.Address]bool
+	balances map[base.Address]map[base.Address]*big.Int
+	prices   []float64
+	nonces   map[base.Address]base.Value
+	block    base.Blknum
+	index    base.Txnum
+	ledger   *Ledger
+}
+
+// Generate generates the history of the accounts chosen by the config.
+func Generate(cfg Config) *Ledger {
+	if cfg.Start.IsZero() {
+		cfg.Start = DefaultStart
+	}
+	if cfg.Months <= 0 {
+		cfg.Months = DefaultMonths
+	}
+	if cfg.Txs <= 0 {
+		cfg.Txs = DefaultTxs
+	}
+	cfg.Start = cfg.Start.UTC()
+	cfg.Accounts = slices.SortedFunc(slices.Values(cfg.Accounts), func(a, b base.Address) int {
+		return cmp.Compare(a.Hex(), b.Hex())
+	})
+	cfg.Accounts = slices.Compact(cfg.Accounts)
+
+	g := &generator{
+		cfg:      cfg,
+		rng:      rand.New(rand.NewPCG(cfg.Seed, 0)),
+		own:      map[base.Address]bool{},
+		balances: map[base.Address]map[base.Address]*big.Int{},
+		nonces:   map[base.Address]base.Value{},
+		ledger: &Ledger{
+			Statements:   map[base.Address][]*types.Statement{},
+			Logs:         map[base.Address][]*types.Log{},
+			Transactions: map[base.Address][]*types.Transaction{},
+			Traces:       map[base.Address][]*types.Trace{},
+			Receipts:     map[base.Address][]*types.Receipt{},
+			Appearances:  map[base.Address][]*types.Appearance{},
+		},
+	}
+	for _, addr := range cfg.Accounts {
+		g.own[addr] = true
+		g.balances[addr] = map[base.Address]*big.Int{}
+	}
+	if len(cfg.Accounts) == 0 {
+		return g.ledger
+	}
+
+	end := cfg.Start.AddDate(0, cfg.Months, 0)
+	g.walkPrices(end)
+	for _, at := range g.schedule(end) {
+		g.record(g.next(at.time, at.account))
+	}
+	return g.ledger
+}
+
+// Names returns the names of the assets and counterparties of every history, so that
+// reports can show them.
+func Names() map[base.Address]types.Name {
+	ret := map[base.Address]types.Name{
+		exchange: {Address: exchange, Name: "Synthetic Exchange", Tags: "31-Exchanges"},
+		router:   {Address: router, Name: "Synthetic Router", Tags: "32-Contracts", IsContract: true},
+		treasury: {Address: treasury, Name: "Synthetic Treasury", Tags: "33-Treasuries"},
+	}
+	for i, v := range vendors {
+		ret[v] = types.Name{Address: v, Name: fmt.Sprintf("Synthetic Vendor %d", i+1), Tags: "34-Vendors"}
+	}
+	for _, a := range append([]asset{usdc, dai, gov}, spam...) {
+		ret[a.Address] = types.Name{Address: a.Address, Name: a.Name, Symbol: a.Symbol, Decimals: a.Decimals, IsContract: true, IsErc20: true, Tags: "50-Tokens"}
+	}
+	return ret
+}
+
+// walkPrices sets the daily price of ether from the start to the end of the history as
+// a random walk of up to 4% a day, in cents.
+func (g *generator) walkPrices(end time.Time) {
+	days := int(end.Sub(g.cfg.Start).Hours()/24) + 1
+	price := 2000.0
+	g.prices = make([]float64, days)
+	for i := range g.prices {
+		g.prices[i] = math.Round(price*100) / 100
+		price *= 1 + (g.rng.Float64()-0.5)*0.08
+	}
+}
+
+// spotPrice returns the price of the asset on the day of the given time, or zero if the
+// asset is not priced.
+func (g *generator) spotPrice(a asset, at time.Time) float64 {
+	switch {
+	case !a.Priced:
+		return 0
+	case a != eth:
+		return 1
+	}
+	day := int(at.Sub(g.cfg.Start).Hours() / 24)
+	return g.prices[min(max(day, 0), len(g.prices)-1)]
+}
+
+// --------------------------------
+// slot is when an account makes or receives a transaction.
+type slot struct {
+	time    time.Time
+	account base.Address
+}
+
+// schedule returns when each transaction happens and which account it concerns, in
+// chronological order. Each account is funded first, and a transaction falls on either
+// side of every month boundary.
+func (g *generator) schedule(end time.Time) []slot {
+	ret := []slot{}
+	for i, addr := range g.cfg.Accounts {
+		ret = append(ret, slot{g.cfg.Start.Add(time.Duration(i+1) * time.Hour), addr})
+	}
+	span := end.Sub(g.cfg.Start) - 24*time.Hour
+	for range g.cfg.Txs * len(g.cfg.Accounts) {
+		at := g.cfg.Start.Add(24*time.Hour + time.Duration(g.rng.Int64N(int64(span)))).Truncate(time.Second)
+		ret = append(ret, slot{at, g.pick(g.cfg.Accounts)})
+	}
+	for m := time.Date(g.cfg.Start.Year(), g.cfg.Start.Month()+1, 1, 0, 0, 0, 0, time.UTC); m.Before(end); m = m.AddDate(0, 1, 0) {
+		ret = append(ret, slot{m.Add(-time.Second), g.pick(g.cfg.Accounts)}, slot{m, g.pick(g.cfg.Accounts)})
+	}
+	slices.SortStableFunc(ret, func(a, b slot) int {
+		return a.time.Compare(b.time)
+	})
+	return ret
+}
+
+// pick returns one of the items at random.
+func pick[T any](rng *rand.Rand, items []T) T {
+	return items[rng.IntN(len(items))]
+}
+
+func (g *generator) pick(addrs []base.Address) base.Address {
+	return pick(g.rng, addrs)
+}
+
+// units returns a random whole number of units of the asset between lo and hi.
+func (g *generator) units(a asset, lo, hi int64) *big.Int {
+	n := big.NewInt(lo + g.rng.Int64N(hi-lo+1))
+	return n.Mul(n, a.unit())
+}
+
+// unit returns the amount of one unit of the asset.
+func (a asset) unit() *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(a.Decimals)), nil)
+}
+
+// share returns a random share, between 1% and 40%, of the account's balance of the asset.
+func (g *generator) share(account base.Address, a asset) *big.Int {
+	n := new(big.Int).Mul(g.balance(account, a), big.NewInt(1+g.rng.Int64N(40)))
+	return n.Div(n, big.NewInt(100))
+}
+
+func (g *generator) balance(account base.Address, a asset) *big.Int {
+	if b, ok := g.balances[account][a.Address]; ok {
+		return b
+	}
+	return new(big.Int)
+}
+
+// next generates a transaction of the account at the given time, choosing what it does
+// at random among what its balances allow. The account's first transaction funds it.
+func (g *generator) next(at time.Time, account base.Address) *tx {
+	t := &tx{
+		time:     at,
+		gasUsed:  21000,
+		gasPrice: base.Gas(10+g.rng.IntN(50)) * 1_000_000_000,
+	}
+	if len(g.balances[account]) == 0 {
+		t.from, t.to = exchange, account
+		t.transfers = []transfer{{asset: eth, from: exchange, to: account, amount: g.units(eth, 5, 50)}}
+		return g.place(t)
+	}
+
+	// A spender must afford the gas of the dearest transaction with some ether to spare
+	reserve := new(big.Int).Mul(big.NewInt(200_000), big.NewInt(int64(t.gasPrice)))
+	canSpend := g.balance(account, eth).Cmp(new(big.Int).Mul(reserve, big.NewInt(2))) > 0
+	stable := pick(g.rng, stables)
+	others := slices.DeleteFunc(slices.Clone(g.cfg.Accounts), func(a base.Address) bool { return a == account })
+
+	switch kind := g.rng.IntN(10); {
+	case !canSpend || kind == 0:
+		t.from, t.to = exchange, account
+		t.transfers = []transfer{{asset: eth, from: exchange, to: account, amount: g.units(eth, 1, 20)}}
+
+	case kind == 1 || kind == 2 && g.balance(account, stable).Sign() == 0:
+		t.from, t.to, t.function, t.gasUsed = exchange, stable.Address, "transfer", 52000
+		t.transfers = []transfer{{asset: stable, from: exchange, to: account, amount: g.units(stable, 100, 50000)}}
+
+	case kind == 2:
+		t.from, t.to, t.function, t.gasUsed = account, stable.Address, "transfer", 52000
+		t.transfers = []transfer{{asset: stable, from: account, to: g.pick(vendors), amount: g.share(account, stable)}}
+
+	case kind == 3:
+		t.from, t.to = account, g.pick(vendors)
+		amount := g.share(account, eth)
+		amount.Sub(amount, reserve)
+		t.transfers = []transfer{{asset: eth, from: account, to: t.to, amount: max0(amount)}}
+
+	case kind == 4 && len(others) > 0:
+		// Between the accounts, so both sides are reconciled
+		other := g.pick(others)
+		if a := pick(g.rng, stables); g.balance(account, a).Sign() > 0 {
+			t.from, t.to, t.function, t.gasUsed = account, a.Address, "transfer", 52000
+			t.transfers = []transfer{{asset: a, from: account, to: other, amount: g.share(account, a)}}
+		} else {
+			t.from, t.to = account, other
+			amount := g.share(account, eth)
+			t.transfers = []transfer{{asset: eth, from: account, to: other, amount: max0(amount.Sub(amount, reserve))}}
+		}
+
+	case kind == 5 && g.balance(account, stable).Sign() > 0:
+		// A swap: the stablecoin goes to the router, ether comes back in an internal call
+		amount := g.share(account, stable)
+		usd := new(big.Float).Quo(new(big.Float).SetInt(amount), new(big.Float).SetInt(stable.unit()))
+		wei, _ := usd.Quo(usd, big.NewFloat(g.spotPrice(eth, at))).Mul(usd, big.NewFloat(1e18)).Int(nil)
+		t.from, t.to, t.function, t.gasUsed = account, router, "swapExactTokensForETH", 150000
+		t.transfers = []transfer{
+			{asset: stable, from: account, to: router, amount: amount},
+			{asset: eth, from: router, to: account, amount: wei, internal: true},
+		}
+
+	case kind == 6 && g.balance(account, gov).Sign() > 0 && g.rng.IntN(2) == 0:
+		t.from, t.to, t.function, t.gasUsed = account, gov.Address, "transfer", 52000
+		t.transfers = []transfer{{asset: gov, from: account, to: treasury, amount: g.share(account, gov)}}
+
+	case kind == 6:
+		t.from, t.to, t.function, t.gasUsed = treasury, gov.Address, "transfer", 52000
+		t.transfers = []transfer{{asset: gov, from: treasury, to: account, amount: g.units(gov, 10, 1000)}}
+
+	case kind == 7:
+		// Spam: the token is airdropped by its own contract to look like a claim
+		token := pick(g.rng, spam)
+		t.from, t.to, t.function, t.gasUsed = token.Address, token.Address, "airdrop", 80000
+		t.transfers = []transfer{{asset: token, from: token.Address, to: account, amount: g.units(token, 1, 5000)}}
+
+	default:
+		t.from, t.to = exchange, account
+		t.transfers = []transfer{{asset: eth, from: exchange, to: account, amount: g.units(eth, 1, 5)}}
+	}
+	return g.place(t)
+}
+
+func max0(n *big.Int) *big.Int {
+	if n.Sign() < 0 {
+		return n.SetInt64(0)
+	}
+	return n
+}
+
+// place gives the transaction a block, an index within it, a hash and log indexes. Blocks
+// are twelve seconds apart, and transactions of the same block follow each other.
+func (g *generator) place(t *tx) *tx {
+	block := base.Blknum(max(1, 18_580_000+int64(t.time.Sub(DefaultStart)/(12*time.Second))))
+	if block <= g.block {
+		block, g.index = g.block, g.index+1
+	} else {
+		g.index = base.Txnum(g.rng.IntN(20))
+	}
+	g.block = block
+	t.block, t.index = block, g.index
+	t.hash = base.HexToHash(fmt.Sprintf("0x%016x%016x%016x%016x", g.rng.Uint64(), g.rng.Uint64(), g.rng.Uint64(), g.rng.Uint64()))
+	logIndex := base.Lognum(g.rng.IntN(100))
+	for i := range t.transfers {
+		if t.transfers[i].asset != eth {
+			t.transfers[i].logIndex = logIndex
+			logIndex++
+		}
+	}
+	return t
+}
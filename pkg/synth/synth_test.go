@@ -0,0 +1,135 @@
+package synth
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+)
+
+var accounts = []base.Address{
+	base.HexToAddress("0xf503017d7baf7fbc0fff7492b751025c6a78179b"),
+	base.HexToAddress("0x054993ab0f2b1acc0fdc65405ee203b4271bebe6"),
+	base.HexToAddress("0x1111111111111111111111111111111111111111"),
+}
+
+func TestReconciled(t *testing.T) {
+	for seed := range uint64(5) {
+		ledger := Generate(Config{Seed: seed, Accounts: accounts})
+		for _, account := range accounts {
+			statements := ledger.Statements[account]
+			if len(statements) == 0 {
+				t.Fatalf("seed %d: no statements for %s", seed, account.Hex())
+			}
+			balances := map[base.Address]*big.Int{}
+			for i, s := range statements {
+				prev, ok := balances[s.Asset]
+				if !ok {
+					prev = new(big.Int)
+				}
+				if s.BegBal.BigInt().Cmp(prev) != 0 || s.PrevBal.BigInt().Cmp(prev) != 0 {
+					t.Fatalf("seed %d: statement %d of %s begins with %s, want %s", seed, i, account.Hex(), s.BegBal.String(), prev)
+				}
+				end := new(big.Int).Add(s.BegBal.BigInt(), s.AmountIn.BigInt())
+				end.Add(end, s.InternalIn.BigInt())
+				end.Sub(end, s.AmountOut.BigInt())
+				end.Sub(end, s.InternalOut.BigInt())
+				end.Sub(end, s.GasOut.BigInt())
+				if s.EndBal.BigInt().Cmp(end) != 0 {
+					t.Fatalf("seed %d: statement %d of %s ends with %s, want %s", seed, i, account.Hex(), s.EndBal.String(), end)
+				}
+				if end.Sign() < 0 {
+					t.Fatalf("seed %d: statement %d of %s ends with a negative balance", seed, i, account.Hex())
+				}
+				if i > 0 && before(s, statements[i-1]) {
+					t.Fatalf("seed %d: statement %d of %s is out of order", seed, i, account.Hex())
+				}
+				balances[s.Asset] = end
+			}
+		}
+	}
+}
+
+// before returns true if the statement comes before the other one.
+func before(s, other *types.Statement) bool {
+	if s.BlockNumber != other.BlockNumber {
+		return s.BlockNumber < other.BlockNumber
+	}
+	if s.TransactionIndex != other.TransactionIndex {
+		return s.TransactionIndex < other.TransactionIndex
+	}
+	return s.LogIndex < other.LogIndex
+}
+
+func TestDeterministic(t *testing.T) {
+	encode := func(seed uint64) string {
+		data, err := json.Marshal(Generate(Config{Seed: seed, Accounts: accounts}).Statements)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if encode(7) != encode(7) {
+		t.Error("the same seed generated different histories")
+	}
+	if encode(7) == encode(8) {
+		t.Error("different seeds generated the same history")
+	}
+
+	reversed := []base.Address{accounts[2], accounts[1], accounts[0]}
+	a := Generate(Config{Seed: 7, Accounts: accounts})
+	b := Generate(Config{Seed: 7, Accounts: reversed})
+	if len(a.Statements[accounts[0]]) != len(b.Statements[accounts[0]]) {
+		t.Error("the order of the accounts changed the history")
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	ledger := Generate(Config{Seed: 1, Accounts: accounts})
+	own := map[base.Address]bool{}
+	for _, a := range accounts {
+		own[a] = true
+	}
+
+	seen := map[string]bool{}
+	for _, account := range accounts {
+		for _, s := range ledger.Statements[account] {
+			at := time.Unix(s.Timestamp, 0).UTC()
+			switch {
+			case s.InternalIn.BigInt().Sign() > 0:
+				seen["internal transfer"] = true
+			case s.GasOut.BigInt().Sign() > 0:
+				seen["gas"] = true
+			}
+			switch {
+			case s.Asset == gov.Address:
+				seen["unpriced token"] = true
+			case s.Asset == spam[0].Address || s.Asset == spam[1].Address:
+				seen["spam token"] = true
+			case s.Asset == usdc.Address || s.Asset == dai.Address:
+				seen["stablecoin"] = true
+			}
+			if own[s.Sender] && own[s.Recipient] && s.Sender != s.Recipient {
+				seen["between accounts"] = true
+			}
+			if at.Month() == time.December && at.Day() == 31 && at.Hour() == 23 && at.Minute() == 59 && at.Second() == 59 {
+				seen["end of year"] = true
+			}
+			if at.Month() == time.January && at.Day() == 1 && at.Hour() == 0 && at.Minute() == 0 && at.Second() == 0 {
+				seen["start of year"] = true
+			}
+		}
+		if len(ledger.Logs[account]) == 0 || len(ledger.Transactions[account]) == 0 || len(ledger.Traces[account]) == 0 ||
+			len(ledger.Receipts[account]) == 0 || len(ledger.Appearances[account]) != len(ledger.Transactions[account]) {
+			t.Errorf("missing records for %s", account.Hex())
+		}
+	}
+	for _, want := range []string{"internal transfer", "gas", "unpriced token", "spam token", "stablecoin", "between accounts", "end of year", "start of year"} {
+		if !seen[want] {
+			t.Errorf("no statement shows a %s", want)
+		}
+	}
+}
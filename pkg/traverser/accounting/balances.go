@@ -1,48 +1,83 @@
 package accounting
 
 import (
+	"cmp"
 	"fmt"
 	"math/big"
 	"reflect"
-	"sort"
-	"strings"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
 // --------------------------------
 type AssetStatement struct {
 	Opts   traverser.Options
-	Values map[string]*types.Statement
+	Values *traverser.Grouping[*types.Statement, assetKey, *lastStatement]
 }
 
-func (c *AssetStatement) Traverse(r *types.Statement) {
-	if len(c.Values) == 0 {
-		c.Values = make(map[string]*types.Statement)
+// lastStatement keeps the last statement of an asset, whose ending balance is its balance.
+type lastStatement struct {
+	Statement *types.Statement `json:"statement"`
+}
+
+func newLastStatement() *lastStatement {
+	return &lastStatement{}
+}
+
+func (a *lastStatement) Add(r *types.Statement) {
+	a.Statement = r
+}
+
+func init() {
+	traverser.Register(traverser.Registration{
+		Name:        "statements",
+		Family:      Family,
+		Description: "Reports the last balance of each asset",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Statement] {
+		return &AssetStatement{Opts: opts}
+	})
+}
+
+func (c *AssetStatement) values() *traverser.Grouping[*types.Statement, assetKey, *lastStatement] {
+	if c.Values == nil {
+		c.Values = traverser.NewGrouping(func(r *types.Statement) assetKey {
+			return assetKey{Asset: r.Asset, Symbol: r.Symbol}
+		}, newLastStatement)
 	}
-	c.Values[c.GetKey(r)] = r
+	return c.Values
+}
+
+func (c *AssetStatement) Traverse(r *types.Statement) error {
+	c.values().Add(r)
+	return nil
 }
 
 func (c *AssetStatement) GetKey(r *types.Statement) string {
-	return r.Asset.Hex() + "_" + r.Symbol
+	return fmt.Sprint(c.values().Key(r))
 }
 
-func (c *AssetStatement) Result() string {
-	return c.Name() + "\n" + c.reportValues("Assets", c.Values)
+func (c *AssetStatement) Result() (*report.Table, error) {
+	return c.reportValues("Assets"), nil
 }
 
 func (c *AssetStatement) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *AssetStatement) Sort(array []*types.Statement) {
-	// Nothing to do
+func (c *AssetStatement) Order() traverser.Ordering {
+	return traverser.Unsorted
+}
+
+// State is the last statement of each asset, whose ending balance is reported.
+func (c *AssetStatement) State() any {
+	return c.values()
 }
 
-func (c *AssetStatement) reportValues(msg string, m map[string]*types.Statement) string {
+func (c *AssetStatement) reportValues(msg string) *report.Table {
 	type stats struct {
 		Address base.Address
 		Symbol  string
@@ -54,10 +89,17 @@ func (c *AssetStatement) reportValues(msg string, m map[string]*types.Statement)
 	zeroPriced := 0
 	zeroNotPriced := 0
 
-	arr := make([]stats, 0, len(m))
-	for k, val := range m {
-		parts := strings.Split(k, "_")
-		stat := stats{Recon: val, Address: base.HexToAddress(parts[0]), Symbol: parts[1]}
+	groups := c.values().Sorted(func(a, b traverser.Group[assetKey, *lastStatement]) int {
+		return cmp.Or(
+			a.Value.Statement.EndBal.Cmp(&b.Value.Statement.EndBal),
+			cmp.Compare(a.Key.Asset.Hex(), b.Key.Asset.Hex()),
+			cmp.Compare(a.Key.Symbol, b.Key.Symbol),
+		)
+	})
+	arr := make([]stats, 0, len(groups))
+	for _, g := range groups {
+		val := g.Value.Statement
+		stat := stats{Recon: val, Address: g.Key.Asset, Symbol: g.Key.Symbol}
 		x := big.Float{}
 		x.SetString(val.EndBal.Text(10))
 		stat.Balance = ToFmtStr(c.Opts.Denom, val.Decimals, val.SpotPrice, &x)
@@ -74,92 +116,27 @@ func (c *AssetStatement) reportValues(msg string, m map[string]*types.Statement)
 			zeroNotPriced++
 		}
 	}
-	sort.Slice(arr, func(i, j int) bool {
-		eb1 := arr[i].Recon.EndBal
-		eb2 := arr[j].Recon.EndBal
-		if eb1.Equal(&eb2) {
-			return arr[i].Address.LessThan(arr[j].Address)
-		}
-		return eb1.LessThan(&eb2)
-	})
-
-	ret := fmt.Sprintf("Number of %s: %d\n", msg, len(c.Values))
-
-	ret += ExportHeader("Non-Zero Units Priced", hasPriced)
-	ret += "Date,Asset,Symbol,Price Source,Spot Price,Units,Usd\n"
+	t := report.NewTable(traverser.TypeName(c))
+	t.AddSummary("Number of "+msg, len(groups))
+
+	columns := []string{"Date", "Asset", "Symbol", "Price Source", "Spot Price", "Balance"}
+	hp := t.AddSection("Non-Zero Units Priced", columns...).AddSummary("Count", hasPriced)
+	hn := t.AddSection("Non-Zero Units Unpriced", columns...).AddSummary("Count", hasNotPriced)
+	zp := t.AddSection("Zero Units Priced", columns...).AddSummary("Count", zeroPriced)
+	zn := t.AddSection("Zero Units Unpriced", columns...).AddSummary("Count", zeroNotPriced)
 	for _, val := range arr {
+		section := zn
 		hasUnits := !val.Recon.EndBal.IsZero()
 		priced := !val.Recon.SpotPrice.IsZero()
 		if hasUnits && priced {
-			format := "%s,%s,%s,%s,%s,%s\n"
-			ret += fmt.Sprintf(
-				format,
-				val.Recon.Date(),
-				val.Address,
-				val.Symbol,
-				val.Recon.PriceSource,
-				val.Recon.SpotPrice.String(),
-				val.Balance,
-			)
-		}
-	}
-
-	ret += ExportHeader("Non-Zero Units Unpriced", hasNotPriced)
-	ret += "Date,Asset,Symbol,Price Source,Spot Price,Units,Usd\n"
-	for _, val := range arr {
-		hasUnits := !val.Recon.EndBal.IsZero()
-		priced := !val.Recon.SpotPrice.IsZero()
-		if hasUnits && !priced {
-			format := "%s,%s,%s,%s,%s,%s\n"
-			ret += fmt.Sprintf(
-				format,
-				val.Recon.Date(),
-				val.Address,
-				val.Symbol,
-				val.Recon.PriceSource,
-				val.Recon.SpotPrice.String(),
-				val.Balance,
-			)
-		}
-	}
-
-	ret += ExportHeader("Zero Units Priced", zeroPriced)
-	ret += "Date,Asset,Symbol,Price Source,Spot Price,Units,Usd\n"
-	for _, val := range arr {
-		hasUnits := !val.Recon.EndBal.IsZero()
-		priced := val.Recon.SpotPrice.GreaterThan(base.ZeroFloat)
-		if !hasUnits && priced {
-			format := "%s,%s,%s,%s,%s,%s\n"
-			ret += fmt.Sprintf(
-				format,
-				val.Recon.Date(),
-				val.Address,
-				val.Symbol,
-				val.Recon.PriceSource,
-				val.Recon.SpotPrice.String(),
-				val.Balance,
-			)
-		}
-	}
-
-	ret += ExportHeader("Zero Units Unpriced", zeroNotPriced)
-	ret += "Date,Asset,Symbol,Price Source,Spot Price,Units,Usd\n"
-	for _, val := range arr {
-		hasUnits := !val.Recon.EndBal.IsZero()
-		priced := !val.Recon.SpotPrice.IsZero()
-		if !hasUnits && !priced {
-			format := "%s,%s,%s,%s,%s,%s\n"
-			ret += fmt.Sprintf(
-				format,
-				val.Recon.Date(),
-				val.Address,
-				val.Symbol,
-				val.Recon.PriceSource,
-				val.Recon.SpotPrice.String(),
-				val.Balance,
-			)
+			section = hp
+		} else if hasUnits && !priced {
+			section = hn
+		} else if !hasUnits && priced {
+			section = zp
 		}
+		section.Append(val.Recon.Date(), val.Address, val.Symbol, val.Recon.PriceSource, val.Recon.SpotPrice.String(), val.Balance)
 	}
 
-	return ret
+	return t
 }
@@ -0,0 +1,77 @@
+package accounting
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/synth"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+func TestAssetStatementFiltered(t *testing.T) {
+	payroll := base.HexToAddress("0x054993ab0f2b1acc0fdc65405ee203b4271bebe6")
+	l := synth.Generate(synth.Config{Seed: 1, Accounts: []base.Address{treasury, payroll}})
+	records := func(yield func(*types.Statement, error) bool) {
+		for _, account := range []base.Address{treasury, payroll} {
+			for _, r := range l.Statements[account] {
+				if !yield(r, nil) {
+					return
+				}
+			}
+		}
+	}
+
+	// Only the balances of the account kept are reported
+	c := &AssetStatement{}
+	stage := traverser.NewFilterStage(traverser.Filters{Accounts: map[base.Address]bool{treasury: true}})
+	if _, err := traverser.Run(records, []traverser.Traverser[*types.Statement]{c}, stage, traverser.FailFast); err != nil {
+		t.Fatal(err)
+	}
+	want := map[base.Address]*types.Statement{}
+	for _, r := range l.Statements[treasury] {
+		want[r.Asset] = r
+	}
+	if c.Values.Len() != len(want) {
+		t.Errorf("got %d assets, want %d", c.Values.Len(), len(want))
+	}
+	for _, g := range c.Values.Sorted(func(a, b traverser.Group[assetKey, *lastStatement]) int { return 0 }) {
+		if got := g.Value.Statement; got != want[got.Asset] {
+			t.Errorf("%s: got the balance of %s at block %d, want the last of %s", got.Symbol, got.AccountedFor.Hex(), got.BlockNumber, treasury.Hex())
+		}
+	}
+	if stage.Dropped["account"] != len(l.Statements[payroll]) {
+		t.Errorf("got %d statements dropped, want %d", stage.Dropped["account"], len(l.Statements[payroll]))
+	}
+}
+
+func TestAssetStatementSymbol(t *testing.T) {
+	asset := base.HexToAddress("0x6b175474e89094c44da98b954eedeac495271d0f")
+	c := &AssetStatement{}
+	c.Traverse(&types.Statement{Asset: asset, Symbol: "UNI_V2", EndBal: *base.NewWei(1)})
+
+	// A checkpointed balance is reported as it was, whole symbol and all
+	data, err := json.Marshal(c.State())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = &AssetStatement{}
+	if err := json.Unmarshal(data, c.State()); err != nil {
+		t.Fatal(err)
+	}
+	table, err := c.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := report.Render(&buf, "csv", table); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.Contains(got, ","+asset.Hex()+",UNI_V2,") {
+		t.Errorf("got %q, want the balance of UNI_V2", got)
+	}
+}
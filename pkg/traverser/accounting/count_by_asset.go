@@ -1,77 +1,109 @@
 package accounting
 
 import (
+	"cmp"
 	"fmt"
 	"reflect"
-	"sort"
-	"strings"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
 // --------------------------------
 type CountByAsset struct {
 	Opts   traverser.Options
-	Values map[string]uint64
+	Values *traverser.Grouping[*types.Statement, assetKey, *assetTotals]
 }
 
-func (c *CountByAsset) Traverse(r *types.Statement) {
-	if len(c.Values) == 0 {
-		c.Values = make(map[string]uint64)
+type assetKey struct {
+	Asset  base.Address `json:"asset"`
+	Symbol string       `json:"symbol"`
+}
+
+// assetTotals counts the statements of an asset and adds up their net amounts.
+type assetTotals struct {
+	traverser.Count[*types.Statement]
+	Net *traverser.Sum[*types.Statement] `json:"net"`
+}
+
+func newAssetTotals() *assetTotals {
+	return &assetTotals{Net: traverser.NewSum((*types.Statement).AmountNet)}
+}
+
+func (a *assetTotals) Add(r *types.Statement) {
+	a.Count.Add(r)
+	a.Net.Add(r)
+}
+
+func init() {
+	traverser.Register(traverser.Registration{
+		Name:        "by_asset",
+		Groups:      []string{"counters"},
+		Family:      Family,
+		Description: "Counts the statements for each asset",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Statement] {
+		return &CountByAsset{Opts: opts}
+	})
+}
+
+func (c *CountByAsset) values() *traverser.Grouping[*types.Statement, assetKey, *assetTotals] {
+	if c.Values == nil {
+		c.Values = traverser.NewGrouping(func(r *types.Statement) assetKey {
+			return assetKey{Asset: r.Asset, Symbol: r.Symbol}
+		}, newAssetTotals)
 	}
-	c.Values[c.GetKey(r)]++
+	return c.Values
+}
+
+func (c *CountByAsset) Traverse(r *types.Statement) error {
+	c.values().Add(r)
+	return nil
 }
 
 func (c *CountByAsset) GetKey(r *types.Statement) string {
-	return r.Asset.Hex() + "_" + r.Symbol
+	return fmt.Sprint(c.values().Key(r))
 }
 
-func (c *CountByAsset) Result() string {
-	return c.Name() + "\n" + c.reportValues("Assets", c.Values)
+func (c *CountByAsset) Result() (*report.Table, error) {
+	return c.reportValues("Assets"), nil
 }
 
 func (c *CountByAsset) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *CountByAsset) Sort(array []*types.Statement) {
-	// Nothing to do
+func (c *CountByAsset) Order() traverser.Ordering {
+	return traverser.Unsorted
 }
 
-func (c *CountByAsset) reportValues(msg string, m map[string]uint64) string {
-	type stats struct {
-		Address base.Address
-		Symbol  string
-		Count   uint64
-	}
-	nTransfers := 0
+func (c *CountByAsset) State() any {
+	return c.values()
+}
 
-	arr := make([]stats, 0, len(m))
-	for k, v := range m {
-		parts := strings.Split(k, "_")
-		arr = append(arr, stats{Count: v, Address: base.HexToAddress(parts[0]), Symbol: parts[1]})
-		nTransfers += int(v)
-	}
-	sort.Slice(arr, func(i, j int) bool {
-		if arr[i].Count == arr[j].Count {
-			if arr[i].Address == arr[j].Address {
-				return arr[i].Symbol < arr[j].Symbol
-			}
-			return arr[i].Address.LessThan(arr[j].Address)
-		}
-		return arr[i].Count > arr[j].Count
+func (c *CountByAsset) reportValues(msg string) *report.Table {
+	groups := c.values().Sorted(func(a, b traverser.Group[assetKey, *assetTotals]) int {
+		return cmp.Or(
+			cmp.Compare(b.Value.N, a.Value.N),
+			cmp.Compare(a.Key.Asset.Hex(), b.Key.Asset.Hex()),
+			cmp.Compare(a.Key.Symbol, b.Key.Symbol),
+		)
 	})
+	nTransfers := uint64(0)
+	for _, g := range groups {
+		nTransfers += g.Value.N
+	}
 
-	ret := fmt.Sprintf("Number of %s: %d\n", msg, len(c.Values))
-	ret += fmt.Sprintf("Number of Transfers: %d\n\n", nTransfers)
+	t := report.NewTable(traverser.TypeName(c))
+	t.AddSummary("Number of "+msg, len(groups))
+	t.AddSummary("Number of Transfers", nTransfers)
 
-	ret += "Count,Asset,Symbol\n"
-	for _, val := range arr {
-		ret += fmt.Sprintf("%d,%s,%s\n", val.Count, val.Address, val.Symbol)
+	section := t.AddSection("", "Count", "Asset", "Symbol", "Net")
+	for _, g := range groups {
+		section.Append(g.Value.N, g.Key.Asset, g.Key.Symbol, &g.Value.Net.Total)
 	}
 
-	return ret
+	return t
 }
@@ -0,0 +1,53 @@
+package accounting
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+func TestCountByAsset(t *testing.T) {
+	colors.ColorsOff()
+	asset := base.HexToAddress("0x6b175474e89094c44da98b954eedeac495271d0f")
+	stmts := []*types.Statement{
+		{Asset: asset, Symbol: "LP_TOKEN", AmountIn: *base.NewWeiStr("100000000000000000000000")},
+		{Asset: asset, Symbol: "LP_TOKEN", AmountOut: *base.NewWei(1)},
+		{Asset: asset, Symbol: "LP", AmountOut: *base.NewWei(5)},
+	}
+
+	c := &CountByAsset{Opts: traverser.Options{}}
+	for _, s := range stmts[:2] {
+		c.Traverse(s)
+	}
+
+	// A checkpointed count and sum carry on where they were
+	data, err := json.Marshal(c.State())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = &CountByAsset{Opts: traverser.Options{}}
+	if err := json.Unmarshal(data, c.State()); err != nil {
+		t.Fatal(err)
+	}
+	c.Traverse(stmts[2])
+
+	var buf bytes.Buffer
+	table, err := c.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Render(&buf, "csv", table); err != nil {
+		t.Fatal(err)
+	}
+	want := "accounting.CountByAsset\nNumber of Assets: 2\nNumber of Transfers: 3\n\nCount,Asset,Symbol,Net\n" +
+		"2," + asset.Hex() + ",LP_TOKEN,99999999999999999999999\n1," + asset.Hex() + ",LP,-5\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
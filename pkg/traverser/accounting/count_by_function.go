@@ -1,125 +1,145 @@
 package accounting
 
 import (
+	"cmp"
 	"fmt"
 	"reflect"
-	"sort"
 	"strings"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
 // --------------------------------
 type CountByFunction struct {
 	Opts   traverser.Options
-	Values map[string]uint64
+	Values *traverser.Grouping[*types.Statement, functionKey, *traverser.Count[*types.Statement]]
 }
 
-func (c *CountByFunction) Traverse(r *types.Statement) {
-	if len(c.Values) == 0 {
-		c.Values = make(map[string]uint64)
+type functionKey struct {
+	Encoding string `json:"encoding"`
+	Name     string `json:"name"`
+}
+
+func init() {
+	traverser.Register(traverser.Registration{
+		Name:        "by_function",
+		Groups:      []string{"counters"},
+		Family:      Family,
+		Description: "Counts the statements for each function called, named or not",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Statement] {
+		return &CountByFunction{Opts: opts}
+	})
+	traverser.Register(traverser.Registration{
+		Name:        "by_function_by_account",
+		Family:      Family,
+		Description: "Counts the statements for each function called, for each account in each period (annually unless --period is given)",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Statement] {
+		period := opts.Period
+		if period == "" {
+			period = "annually"
+		}
+		return traverser.GroupBy("Account", func(r *types.Statement) string {
+			return r.AccountedFor.Hex()
+		}, func() traverser.Traverser[*types.Statement] {
+			return traverser.Window(period, func() traverser.Traverser[*types.Statement] {
+				return &CountByFunction{Opts: opts}
+			})
+		})
+	})
+}
+
+func (c *CountByFunction) values() *traverser.Grouping[*types.Statement, functionKey, *traverser.Count[*types.Statement]] {
+	if c.Values == nil {
+		c.Values = traverser.NewGrouping(func(r *types.Statement) functionKey {
+			return functionKey{
+				Encoding: r.Encoding(),
+				Name:     strings.Split(strings.Replace(strings.Replace(r.Signature(), "{name:", "", -1), "}", "", -1), "|")[0],
+			}
+		}, traverser.NewCount[*types.Statement])
 	}
-	c.Values[c.GetKey(r)]++
+	return c.Values
+}
+
+func (c *CountByFunction) Traverse(r *types.Statement) error {
+	c.values().Add(r)
+	return nil
 }
 
 func (c *CountByFunction) GetKey(r *types.Statement) string {
-	return r.Encoding() + "_" + strings.Split(strings.Replace(strings.Replace(r.Signature(), "{name:", "", -1), "}", "", -1), "|")[0]
+	return fmt.Sprint(c.values().Key(r))
 }
 
-func (c *CountByFunction) Result() string {
-	return c.Name() + "\n" + c.reportValues("Functions", c.Values)
+func (c *CountByFunction) Result() (*report.Table, error) {
+	return c.reportValues("Functions"), nil
 }
 
 func (c *CountByFunction) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *CountByFunction) Sort(array []*types.Statement) {
-	// Nothing to do
+func (c *CountByFunction) Order() traverser.Ordering {
+	return traverser.Unsorted
 }
 
-func (c *CountByFunction) reportValues(msg string, m map[string]uint64) string {
-	type stats struct {
-		Encoding string
-		Name     string
-		Count    uint64
-		Status   string
-	}
-	nTransfers := 0
-	nEthTransfers := 0
-	nNamed := 0
-	nMessages := 0
-
-	arr := make([]stats, 0, len(m))
-	for k, v := range m {
-		parts := strings.Split(k, "_")
-		status := "unnamed"
-		if len(parts) > 1 {
-			if len(parts[1]) > 0 && parts[0] != parts[1] {
-				if strings.HasPrefix(parts[1], "message:") {
-					status = "message"
-				} else {
-					status = "named"
-				}
-			}
-			arr = append(arr, stats{Count: v, Encoding: parts[0], Name: parts[1], Status: status})
-		}
+func (c *CountByFunction) State() any {
+	return c.values()
+}
 
-		nTransfers += int(v)
-		if parts[0] == "0x" {
-			nEthTransfers += int(v)
-		} else if status == "named" {
-			nNamed += int(v)
-		} else if status == "message" {
-			nMessages += int(v)
-		}
-	}
-	sort.Slice(arr, func(i, j int) bool {
-		if arr[i].Count == arr[j].Count {
-			if arr[i].Encoding == arr[j].Encoding {
-				return arr[i].Name < arr[j].Name
-			}
-			return arr[i].Encoding < arr[j].Encoding
-		}
-		return arr[i].Count > arr[j].Count
+func (c *CountByFunction) reportValues(msg string) *report.Table {
+	groups := c.values().Sorted(func(a, b traverser.Group[functionKey, *traverser.Count[*types.Statement]]) int {
+		return cmp.Or(
+			cmp.Compare(b.Value.N, a.Value.N),
+			cmp.Compare(a.Key.Encoding, b.Key.Encoding),
+			cmp.Compare(a.Key.Name, b.Key.Name),
+		)
 	})
-
-	ret := fmt.Sprintf("Number of %s: %d\n", msg, len(c.Values))
-	ret += fmt.Sprintf("Number of Transfers: %d\n", nTransfers)
-
-	ret += ExportHeader("Calls to Named Functions", nNamed)
-	ret += "Count,Encoding,Name\n"
-	for _, val := range arr {
-		if val.Status == "named" {
-			ret += fmt.Sprintf("%d,%s,%s\n", val.Count, val.Encoding, val.Name)
-		}
-	}
-
-	ret += ExportHeader("Calls to Unnamed Functions", nTransfers-nNamed-nMessages-nEthTransfers)
-	ret += "Count,Encoding,Name\n"
-	for _, val := range arr {
-		if val.Status != "named" && val.Status != "message" && val.Encoding != "0x" {
-			ret += fmt.Sprintf("%d,%s,%s\n", val.Count, val.Encoding, val.Name)
+	status := func(k functionKey) string {
+		if len(k.Name) > 0 && k.Encoding != k.Name {
+			if strings.HasPrefix(k.Name, "message:") {
+				return "message"
+			}
+			return "named"
 		}
+		return "unnamed"
 	}
-
-	ret += ExportHeader("Eth Transfers", nEthTransfers)
-	ret += "Count,Encoding,Name\n"
-	for _, val := range arr {
-		if val.Encoding == "0x" {
-			ret += fmt.Sprintf("%d,%s,%s\n", val.Count, val.Encoding, val.Name)
+	nTransfers := uint64(0)
+	nEthTransfers := uint64(0)
+	nNamed := uint64(0)
+	nMessages := uint64(0)
+	for _, g := range groups {
+		nTransfers += g.Value.N
+		if g.Key.Encoding == "0x" {
+			nEthTransfers += g.Value.N
+		} else if status(g.Key) == "named" {
+			nNamed += g.Value.N
+		} else if status(g.Key) == "message" {
+			nMessages += g.Value.N
 		}
 	}
 
-	ret += ExportHeader("Messages", nMessages)
-	ret += "Count,Message\n"
-	for _, val := range arr {
-		if val.Status == "message" {
-			ret += fmt.Sprintf("%d,%s\n", val.Count, val.Name)
+	t := report.NewTable(traverser.TypeName(c))
+	t.AddSummary("Number of "+msg, len(groups))
+	t.AddSummary("Number of Transfers", nTransfers)
+
+	named := t.AddSection("Calls to Named Functions", "Count", "Encoding", "Name").AddSummary("Count", nNamed)
+	unnamed := t.AddSection("Calls to Unnamed Functions", "Count", "Encoding", "Name").AddSummary("Count", nTransfers-nNamed-nMessages-nEthTransfers)
+	eth := t.AddSection("Eth Transfers", "Count", "Encoding", "Name").AddSummary("Count", nEthTransfers)
+	messages := t.AddSection("Messages", "Count", "Message").AddSummary("Count", nMessages)
+	for _, g := range groups {
+		switch {
+		case status(g.Key) == "named":
+			named.Append(g.Value.N, g.Key.Encoding, g.Key.Name)
+		case status(g.Key) == "message":
+			messages.Append(g.Value.N, g.Key.Name)
+		case g.Key.Encoding == "0x":
+			eth.Append(g.Value.N, g.Key.Encoding, g.Key.Name)
+		default:
+			unnamed.Append(g.Value.N, g.Key.Encoding, g.Key.Name)
 		}
 	}
 
-	return ret
+	return t
 }
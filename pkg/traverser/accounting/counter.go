@@ -1,11 +1,11 @@
 package accounting
 
 import (
-	"fmt"
 	"reflect"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
@@ -15,26 +15,41 @@ type Counter struct {
 	Value uint64
 }
 
-func (c *Counter) Traverse(r *types.Statement) {
+func init() {
+	traverser.Register(traverser.Registration{
+		Name:        "counter",
+		Aliases:     []string{"accounting.counter"},
+		Groups:      []string{"counters"},
+		Family:      Family,
+		Description: "Counts the statements",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Statement] {
+		return &Counter{Opts: opts}
+	})
+}
+
+func (c *Counter) Traverse(r *types.Statement) error {
 	c.Value += 1
+	return nil
 }
 
 func (c *Counter) GetKey(r *types.Statement) string {
 	return ""
 }
 
-func (c *Counter) Result() string {
-	return c.Name() + "\n\t" + c.reportValue("Counter: ", c.Value)
+func (c *Counter) Result() (*report.Table, error) {
+	t := report.NewTable(traverser.TypeName(c))
+	t.AddSection("", "Counter").Append(c.Value)
+	return t, nil
 }
 
 func (c *Counter) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *Counter) Sort(array []*types.Statement) {
-	// Nothing to do
+func (c *Counter) Order() traverser.Ordering {
+	return traverser.Unsorted
 }
 
-func (c *Counter) reportValue(msg string, v uint64) string {
-	return fmt.Sprintf("%s%d", msg, v)
+func (c *Counter) State() any {
+	return &c.Value
 }
@@ -1,10 +1,12 @@
 package accounting
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
@@ -20,8 +22,16 @@ func TestCounter(t *testing.T) {
 	if c.Value != 2 {
 		t.Errorf("Traverse failed: got %d, want 2", c.Value)
 	}
-	wantResult := "accounting.Counter\n\tCounter: 2"
-	if got := c.Result(); got != wantResult {
+	var buf bytes.Buffer
+	table, err := c.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Render(&buf, "csv", table); err != nil {
+		t.Fatal(err)
+	}
+	wantResult := "accounting.Counter\n\nCounter\n2\n"
+	if got := buf.String(); got != wantResult {
 		t.Errorf("Result failed: got %q, want %q", got, wantResult)
 	}
 }
@@ -2,6 +2,7 @@ package accounting
 
 import (
 	"fmt"
+	"io"
 	"math/big"
 	"reflect"
 	"sort"
@@ -12,6 +13,7 @@ import (
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/excel"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 	"github.com/xuri/excelize/v2"
 )
@@ -21,48 +23,29 @@ type Excel struct {
 	ExcelFile *excelize.File
 	Line      int
 	Assets    map[string][]*types.Statement
-	showOnce  map[string]bool
+	err       error
+}
+
+func init() {
+	traverser.Register(traverser.Registration{
+		Name:        "excel",
+		Family:      Family,
+		Description: "Writes a workbook with one sheet per asset to Book1.xlsx",
+		Writes:      "Book1.xlsx",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Statement] {
+		return &Excel{Opts: opts}
+	})
 }
 
 // --------------------------------
-func (c *Excel) Traverse(r *types.Statement) {
-	if c.ExcelFile == nil {
-		c.ExcelFile = excel.NewWorkbook("Summary", []string{"This is the summary text"})
+func (c *Excel) Traverse(r *types.Statement) error {
+	if c.Assets == nil {
 		c.Assets = make(map[string][]*types.Statement)
-		c.showOnce = make(map[string]bool)
-	}
-
-	passedAddrFilter := c.Opts.AddrFilters[r.Asset]
-	if len(c.Opts.AddrFilters) > 0 && !passedAddrFilter {
-		if !c.showOnce[r.Asset.String()] {
-			fmt.Printf("Skipping asset: %s\r", r.Asset.String())
-		}
-		c.showOnce[r.Asset.String()] = true
-		return
 	}
 
-	l := 0 // len(c.Opts.DateFilters)
-	if l > 0 {
-		firstDate := base.NewDateTime(2015, 7, 30, 23, 59, 59)
-		lastDate := c.Opts.DateFilters[0]
-		if l > 1 {
-			firstDate = c.Opts.DateFilters[0]
-			lastDate = c.Opts.DateFilters[1]
-		}
-		dt := r.DateTime()
-		if dt.Before(&firstDate) || dt.After(&lastDate) {
-			month := dt.String()[:7]
-			if !c.showOnce[month] {
-				fmt.Printf("Skipping month: %s\r", month)
-			}
-			c.showOnce[month] = true
-			return
-		}
-	}
-	// fmt.Println("")
-
 	c.Line += 1
 	c.Assets[c.GetKey(r)] = append(c.Assets[c.GetKey(r)], r)
+	return nil
 }
 
 func (c *Excel) GetKey(r *types.Statement) string {
@@ -73,20 +56,18 @@ func (c *Excel) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *Excel) Sort(array []*types.Statement) {
-	sort.Slice(array, func(i, j int) bool {
-		item1 := array[i]
-		item2 := array[j]
-		dt1 := item1.DateTime()
-		dt2 := item2.DateTime()
-		if dt1 == dt2 {
-			if item1.TransactionIndex == item2.TransactionIndex {
-				return item1.LogIndex < item2.LogIndex
-			}
-			return item1.TransactionIndex < item2.TransactionIndex
-		}
-		return dt1.Before(&dt2)
-	})
+// Order is chronological because the sheets are written in date order across all accounts.
+func (c *Excel) Order() traverser.Ordering {
+	return traverser.Chronological
+}
+
+// State is the statements of each sheet and the number of lines, since the workbook is
+// written again in full by each run.
+func (c *Excel) State() any {
+	return &struct {
+		Line   *int
+		Assets *map[string][]*types.Statement
+	}{&c.Line, &c.Assets}
 }
 
 type Field struct {
@@ -108,10 +89,11 @@ type CellRange struct {
 	CurRows []int
 }
 
-func (c *Excel) Result() string {
+func (c *Excel) Result() (*report.Table, error) {
+	c.ExcelFile = excel.NewWorkbook("Summary", []string{"This is the summary text"})
 	styles, err := c.GetStyles()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	var fieldMap = map[string]*Field{
@@ -207,7 +189,9 @@ func (c *Excel) Result() string {
 	sheets := c.assetsToSheets()
 	for _, sheet := range sheets {
 		c.ExcelFile.NewSheet(sheet.Name)
-		c.SetHeader(&sheet, &styles, lastCol)
+		if err := c.SetHeader(&sheet, &styles, lastCol); err != nil {
+			return nil, err
+		}
 		for _, field := range fields {
 			c.ExcelFile.SetColWidth(sheet.Name, fieldMap[field].Column, fieldMap[field].Column, fieldMap[field].Wid)
 		}
@@ -341,7 +325,7 @@ func (c *Excel) Result() string {
 				c.SetCell(sheet.Name, curRow, rowRange, fieldMap["Recipient"], r.Recipient)
 				c.SetCell(sheet.Name, curRow, rowRange, fieldMap["AccountedFor"], r.AccountedFor)
 				c.SetCell(sheet.Name, curRow, rowRange, fieldMap["TransactionHash"], r.TransactionHash)
-				c.setLink(sheet.Name, fieldMap["TransactionHash"].Cell(curRow), "https://etherscan.io/tx/"+r.TransactionHash.String(), "View on Etherscan")
+				c.setLink(sheet.Name, fieldMap["TransactionHash"].Cell(curRow), c.Opts.ExplorerURL("tx", r.TransactionHash.String()), "View in Explorer")
 
 				// both or neither can be true...
 				senderCell := fmt.Sprintf("%s%d", fieldMap["Sender"].Column, curRow)
@@ -486,10 +470,29 @@ func (c *Excel) Result() string {
 	// 	cnt++
 	// }
 
-	excel.WriteLicenseSheet(c.ExcelFile)
+	if err := excel.WriteLicenseSheet(c.ExcelFile); err != nil {
+		return nil, err
+	}
 	c.ExcelFile.SetActiveSheet(1)
-	c.ExcelFile.SaveAs("Book1.xlsx")
-	return c.Name() + "\n\t" + fmt.Sprintf("%s%d", "Excel:", c.Line)
+	path := c.Opts.ChainFile("Book1.xlsx")
+	if c.err != nil {
+		return nil, c.err
+	}
+	if err := report.WriteFile(path, func(w io.Writer) error { return c.ExcelFile.Write(w) }); err != nil {
+		return nil, err
+	}
+
+	t := report.NewTable(traverser.TypeName(c))
+	t.AddSection("", "File", "Sheets", "Lines").Append(path, len(sheets), c.Line)
+	return t, nil
+}
+
+// fail remembers the first error writing the workbook. Result reports it rather than
+// save a workbook with missing cells.
+func (c *Excel) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
 }
 
 func (c *Excel) assetsToSheets() []AssetSheet {
@@ -513,7 +516,8 @@ func (c *Excel) assetsToSheets() []AssetSheet {
 			Symbol:   asset[0].Symbol,
 			Decimals: int(asset[0].Decimals),
 			nRecords: len(asset),
-			Records:  asset,
+			// Statements restored from a checkpoint precede the newer ones of every account
+			Records: traverser.Sorted(asset, traverser.Chronological),
 		}
 		sheets = append(sheets, s)
 	}
@@ -2,7 +2,6 @@ package accounting
 
 import (
 	"fmt"
-	"log"
 	"math/big"
 	"strconv"
 	"strings"
@@ -108,7 +107,7 @@ func (c *Excel) SetCell(sheetName string, row int, sumRange CellRange, field *Fi
 	}
 
 	if err != nil {
-		log.Fatal(err)
+		c.fail(fmt.Errorf("setting %s!%s: %w", sheetName, cell, err))
 	}
 
 	return cell
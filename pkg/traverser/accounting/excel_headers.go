@@ -31,7 +31,7 @@ func (c *Excel) SetHeader(sheet *AssetSheet, styles *Styles, lastCol string) err
 	c.setStyle(sheet.Name, fmt.Sprintf("A%d", headerRow), fmt.Sprintf((lastCol+"%d"), headerRow), styles.tableHeader)
 	c.setStyle(sheet.Name, fmt.Sprintf("A%d", 1), fmt.Sprintf("F%d", 4), styles.mainHeader)
 	c.setStyle(sheet.Name, "D1", "D1", styles.link)
-	c.setLink(sheet.Name, "D1", "https://etherscan.io/address/"+sheet.Address, "Open in Explorer")
+	c.setLink(sheet.Name, "D1", c.Opts.ExplorerURL("address", sheet.Address), "Open in Explorer")
 	c.ExcelFile.SetPanes(sheet.Name, &excelize.Panes{
 		Freeze:      true,
 		Split:       false,
@@ -49,11 +49,15 @@ func (c *Excel) SetHeader(sheet *AssetSheet, styles *Styles, lastCol string) err
 	return nil
 }
 
+// setLink links a cell to a URL, unless there is none (the chain has no known explorer).
 func (c *Excel) setLink(sheetName, cell, url, tooltip string) {
+	if url == "" {
+		return
+	}
 	if err := c.ExcelFile.SetCellHyperLink(sheetName, cell, url, "External", excelize.HyperlinkOpts{
 		Tooltip: &tooltip,
 	}); err != nil {
-		panic(err)
+		c.fail(fmt.Errorf("linking %s!%s: %w", sheetName, cell, err))
 	}
 }
 
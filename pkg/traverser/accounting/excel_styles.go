@@ -2,7 +2,6 @@ package accounting
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/xuri/excelize/v2"
 )
@@ -342,6 +341,6 @@ func (c *Excel) GetStyles() (styles Styles, err error) {
 func (c *Excel) setStyle(sheetName, topLeft, bottomRight string, styleId int) {
 	err := c.ExcelFile.SetCellStyle(sheetName, topLeft, bottomRight, styleId)
 	if err != nil {
-		log.Fatal(fmt.Errorf("error setStyle::Setregular(%s, %s, %s, %d) %w", sheetName, topLeft, bottomRight, styleId, err))
+		c.fail(fmt.Errorf("styling %s!%s:%s: %w", sheetName, topLeft, bottomRight, err))
 	}
 }
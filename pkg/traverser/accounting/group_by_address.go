@@ -1,14 +1,15 @@
 package accounting
 
 import (
+	"cmp"
 	"fmt"
 	"reflect"
-	"sort"
 	"strings"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
@@ -16,114 +17,127 @@ import (
 type GroupByAddress struct {
 	Opts   traverser.Options
 	Source string
-	Values map[string]uint64
+	Values *traverser.Grouping[*types.Statement, pairKey, *traverser.Count[*types.Statement]]
 }
 
-func (c *GroupByAddress) Traverse(r *types.Statement) {
-	if len(c.Values) == 0 {
-		c.Values = make(map[string]uint64)
+// pairKey is the sender, the recipient, or both, as the source of the counts has it.
+type pairKey struct {
+	Sender    base.Address `json:"sender"`
+	Recipient base.Address `json:"recipient"`
+}
+
+func init() {
+	traverser.Register(traverser.Registration{
+		Name:        "senders",
+		Groups:      []string{"groups", "by_address"},
+		Family:      Family,
+		Description: "Counts the statements for each sender",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Statement] {
+		return &GroupByAddress{Opts: opts, Source: "senders"}
+	})
+	traverser.Register(traverser.Registration{
+		Name:        "recipients",
+		Groups:      []string{"groups", "by_address"},
+		Family:      Family,
+		Description: "Counts the statements for each recipient",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Statement] {
+		return &GroupByAddress{Opts: opts, Source: "recipients"}
+	})
+	traverser.Register(traverser.Registration{
+		Name:        "pairings",
+		Groups:      []string{"groups", "by_address"},
+		Family:      Family,
+		Description: "Counts the statements for each sender and recipient pair",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Statement] {
+		return &GroupByAddress{Opts: opts, Source: "pairings"}
+	})
+}
+
+func (c *GroupByAddress) values() *traverser.Grouping[*types.Statement, pairKey, *traverser.Count[*types.Statement]] {
+	if c.Values == nil {
+		c.Values = traverser.NewGrouping(func(r *types.Statement) pairKey {
+			switch c.Source {
+			case "senders":
+				return pairKey{Sender: r.Sender}
+			case "recipients":
+				return pairKey{Recipient: r.Recipient}
+			case "pairings":
+				fallthrough
+			default:
+				return pairKey{Sender: r.Sender, Recipient: r.Recipient}
+			}
+		}, traverser.NewCount[*types.Statement])
 	}
-	c.Values[c.GetKey(r)]++
+	return c.Values
+}
+
+func (c *GroupByAddress) Traverse(r *types.Statement) error {
+	c.values().Add(r)
+	return nil
 }
 
 func (c *GroupByAddress) GetKey(r *types.Statement) string {
-	switch c.Source {
-	case "senders":
-		return c.Source + "_" + r.Sender.String()
-	case "recipients":
-		return c.Source + "_" + r.Recipient.String()
-	case "pairings":
-		fallthrough
-	default:
-		return c.Source + "_" + r.Sender.String() + "_" + r.Recipient.String()
-	}
+	return fmt.Sprint(c.values().Key(r))
 }
 
-func (c *GroupByAddress) Result() string {
-	return c.Name() + "\n" + c.reportValues("Addresses", c.Values)
+func (c *GroupByAddress) Result() (*report.Table, error) {
+	return c.reportValues(), nil
 }
 
 func (c *GroupByAddress) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *GroupByAddress) Sort(array []*types.Statement) {
-	// Nothing to do
+func (c *GroupByAddress) Order() traverser.Ordering {
+	return traverser.Unsorted
 }
 
-func (c *GroupByAddress) reportValues(msg string, m map[string]uint64) string {
-	_ = msg
-	type stats struct {
-		Sender    base.Address
-		Recipient base.Address
-		Name      string
-		Count     uint64
-		Status    string
-	}
-	nTransfers := 0
-	counter := 0
-
-	arr := make([]stats, 0, len(m))
-	for k, v := range m {
-		parts := strings.Split(k, "_")
-		record := stats{
-			Count:  v,
-			Status: parts[0],
-			Name:   c.Opts.Names[base.HexToAddress(parts[1])].Name,
-		}
-		if k == "sender" {
-			record.Sender = base.HexToAddress(parts[1])
-			record.Name = c.Opts.Names[base.HexToAddress(parts[1])].Name
-		} else if k == "recipient" {
-			record.Recipient = base.HexToAddress(parts[1])
-			record.Name = c.Opts.Names[base.HexToAddress(parts[1])].Name
-		} else {
-			record.Sender = base.HexToAddress(parts[1])
-			record.Recipient = base.HexToAddress(parts[2])
-			record.Name = c.Opts.Names[base.HexToAddress(parts[1])].Name + "," + c.Opts.Names[base.HexToAddress(parts[2])].Name
-		}
-		arr = append(arr, record)
+func (c *GroupByAddress) State() any {
+	return c.values()
+}
 
-		nTransfers += int(v)
-		counter++
+func (c *GroupByAddress) reportValues() *report.Table {
+	names := func(k pairKey) string {
+		return c.Opts.Names[k.Sender].Name + c.Opts.Names[k.Recipient].Name
 	}
-	sort.Slice(arr, func(i, j int) bool {
-		if arr[i].Count == arr[j].Count {
-			v1 := arr[i].Sender.Hex() + arr[i].Recipient.Hex()
-			v2 := arr[j].Sender.Hex() + arr[j].Recipient.Hex()
-			if v1 == v2 {
-				return arr[i].Name < arr[j].Name
-			}
-			return v1 < v2
-		}
-		return arr[i].Count > arr[j].Count
+	groups := c.values().Sorted(func(a, b traverser.Group[pairKey, *traverser.Count[*types.Statement]]) int {
+		return cmp.Or(
+			cmp.Compare(b.Value.N, a.Value.N),
+			cmp.Compare(a.Key.Sender.Hex(), b.Key.Sender.Hex()),
+			cmp.Compare(a.Key.Recipient.Hex(), b.Key.Recipient.Hex()),
+			cmp.Compare(names(a.Key), names(b.Key)),
+		)
 	})
+	nTransfers := uint64(0)
+	for _, g := range groups {
+		nTransfers += g.Value.N
+	}
 
-	proper := strings.ToUpper(c.Source[:1]) + c.Source[1:len(c.Source)]
-	ret := fmt.Sprintf("Number of %s: %d\n", proper, counter)
-	ret += fmt.Sprintf("Number of Transfers: %d\n\n", nTransfers)
+	proper := strings.ToUpper(c.Source[:1]) + c.Source[1:]
+	t := report.NewTable(traverser.TypeName(c))
+	t.AddSummary("Number of "+proper, len(groups))
+	t.AddSummary("Number of Transfers", nTransfers)
 
 	source := proper[:len(proper)-1]
-	ret += source + "\n"
-	if c.Source == "pairings" {
-		ret += "Count,Sender,Sender Name,Recipient,Recipient Name\n"
+	var section *report.Section
+	if c.Source == "senders" || c.Source == "recipients" {
+		section = t.AddSection(source, "Count", source, source+" Name")
 	} else {
-		ret += "Count," + source + "," + source + " Name\n"
+		section = t.AddSection(source, "Count", "Sender", "Sender Name", "Recipient", "Recipient Name")
 	}
-	for _, val := range arr {
-		if val.Status == c.Source {
-			switch c.Source {
-			case "senders":
-				ret += fmt.Sprintf("%d,%s,%s\n", val.Count, val.Sender, val.Name)
-			case "recipients":
-				ret += fmt.Sprintf("%d,%s,%s\n", val.Count, val.Recipient, val.Name)
-			case "pairings":
-				fallthrough
-			default:
-				ret += fmt.Sprintf("%d,%s,%s,%s,%s\n", val.Count, val.Sender, val.Name, val.Recipient, c.Opts.Names[val.Recipient].Name)
-			}
+	for _, g := range groups {
+		sender, recipient := g.Key.Sender, g.Key.Recipient
+		switch c.Source {
+		case "senders":
+			section.Append(g.Value.N, sender, c.Opts.Names[sender].Name)
+		case "recipients":
+			section.Append(g.Value.N, recipient, c.Opts.Names[recipient].Name)
+		case "pairings":
+			fallthrough
+		default:
+			section.Append(g.Value.N, sender, c.Opts.Names[sender].Name, recipient, c.Opts.Names[recipient].Name)
 		}
 	}
 
-	return ret
+	return t
 }
@@ -1,97 +1,105 @@
 package accounting
 
 import (
+	"cmp"
 	"fmt"
 	"reflect"
-	"sort"
-	"strings"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
 // --------------------------------
 type GroupByPriced struct {
 	Opts   traverser.Options
-	Values map[string]uint64
+	Values *traverser.Grouping[*types.Statement, pricedKey, *traverser.Count[*types.Statement]]
 }
 
-func (c *GroupByPriced) Traverse(r *types.Statement) {
-	if len(c.Values) == 0 {
-		c.Values = make(map[string]uint64)
+type pricedKey struct {
+	Priced bool         `json:"priced"`
+	Asset  base.Address `json:"asset"`
+	Symbol string       `json:"symbol"`
+}
+
+func init() {
+	traverser.Register(traverser.Registration{
+		Name:        "by_priced",
+		Groups:      []string{"groups"},
+		Family:      Family,
+		Description: "Counts the statements for each asset, split by whether the asset is priced",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Statement] {
+		return &GroupByPriced{Opts: opts}
+	})
+}
+
+func (c *GroupByPriced) values() *traverser.Grouping[*types.Statement, pricedKey, *traverser.Count[*types.Statement]] {
+	if c.Values == nil {
+		c.Values = traverser.NewGrouping(func(r *types.Statement) pricedKey {
+			return pricedKey{Priced: !r.SpotPrice.IsZero(), Asset: r.Asset, Symbol: r.Symbol}
+		}, traverser.NewCount[*types.Statement])
 	}
-	c.Values[c.GetKey(r)]++
+	return c.Values
+}
+
+func (c *GroupByPriced) Traverse(r *types.Statement) error {
+	c.values().Add(r)
+	return nil
 }
 
 func (c *GroupByPriced) GetKey(r *types.Statement) string {
-	status := "unpriced"
-	if !r.SpotPrice.IsZero() {
-		status = "priced"
-	}
-	return status + "_" + r.Asset.Hex() + "_" + r.Symbol + "," + c.Opts.Names[base.HexToAddress(r.Asset.String())].Name
+	return fmt.Sprint(c.values().Key(r))
 }
 
-func (c *GroupByPriced) Result() string {
-	return c.Name() + "\n" + c.reportValues("Assets", c.Values)
+func (c *GroupByPriced) Result() (*report.Table, error) {
+	return c.reportValues("Assets"), nil
 }
 
 func (c *GroupByPriced) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *GroupByPriced) Sort(array []*types.Statement) {
-	// Nothing to do
+func (c *GroupByPriced) Order() traverser.Ordering {
+	return traverser.Unsorted
 }
 
-func (c *GroupByPriced) reportValues(msg string, m map[string]uint64) string {
-	type stats struct {
-		Address base.Address
-		Symbol  string
-		Count   uint64
-		Status  string
-	}
-	nTransfers := 0
-	nPriced := 0
-
-	arr := make([]stats, 0, len(m))
-	for k, v := range m {
-		parts := strings.Split(k, "_")
-		arr = append(arr, stats{Count: v, Status: parts[0], Address: base.HexToAddress(parts[1]), Symbol: parts[2]})
-		nTransfers += int(v)
-		if parts[0] == "priced" {
-			nPriced += int(v)
-		}
-	}
-	sort.Slice(arr, func(i, j int) bool {
-		if arr[i].Count == arr[j].Count {
-			if arr[i].Address == arr[j].Address {
-				return arr[i].Symbol < arr[j].Symbol
-			}
-			return arr[i].Address.LessThan(arr[j].Address)
-		}
-		return arr[i].Count > arr[j].Count
-	})
-
-	ret := fmt.Sprintf("Number of %s: %d\n", msg, len(c.Values))
-	ret += fmt.Sprintf("Number of Transfers: %d\n", nTransfers)
+func (c *GroupByPriced) State() any {
+	return c.values()
+}
 
-	ret += ExportHeader("Priced Assets", nPriced)
-	ret += "Count,Asset,Symbol,Name\n"
-	for _, val := range arr {
-		if val.Status == "priced" {
-			ret += fmt.Sprintf("%d,%s,%s\n", val.Count, val.Address, val.Symbol)
+func (c *GroupByPriced) reportValues(msg string) *report.Table {
+	groups := c.values().Sorted(func(a, b traverser.Group[pricedKey, *traverser.Count[*types.Statement]]) int {
+		return cmp.Or(
+			cmp.Compare(b.Value.N, a.Value.N),
+			cmp.Compare(a.Key.Asset.Hex(), b.Key.Asset.Hex()),
+			cmp.Compare(a.Key.Symbol, b.Key.Symbol),
+		)
+	})
+	nTransfers := uint64(0)
+	nPriced := uint64(0)
+	for _, g := range groups {
+		nTransfers += g.Value.N
+		if g.Key.Priced {
+			nPriced += g.Value.N
 		}
 	}
 
-	ret += ExportHeader("Unpriced Assets", nTransfers-nPriced)
-	ret += "Count,Address,Symbol,Name\n"
-	for _, val := range arr {
-		if val.Status == "unpriced" {
-			ret += fmt.Sprintf("%d,%s,%s\n", val.Count, val.Address, val.Symbol)
+	t := report.NewTable(traverser.TypeName(c))
+	t.AddSummary("Number of "+msg, len(groups))
+	t.AddSummary("Number of Transfers", nTransfers)
+
+	priced := t.AddSection("Priced Assets", "Count", "Asset", "Symbol", "Name").AddSummary("Count", nPriced)
+	unpriced := t.AddSection("Unpriced Assets", "Count", "Asset", "Symbol", "Name").AddSummary("Count", nTransfers-nPriced)
+	for _, g := range groups {
+		name := c.Opts.Names[g.Key.Asset].Name
+		if g.Key.Priced {
+			priced.Append(g.Value.N, g.Key.Asset, g.Key.Symbol, name)
+		} else {
+			unpriced.Append(g.Value.N, g.Key.Asset, g.Key.Symbol, name)
 		}
 	}
 
-	return ret
+	return t
 }
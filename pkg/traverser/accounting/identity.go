@@ -6,39 +6,85 @@ import (
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
 // --------------------------------
 type Identity struct {
-	Opts     traverser.Options
-	NotFirst bool
-	Count    uint64
+	Opts       traverser.Options
+	Count      uint64
+	Statements []*types.Statement
 }
 
-func (c *Identity) Traverse(val *types.Statement) {
+func init() {
+	traverser.Register(traverser.Registration{
+		Name:        "identity",
+		Family:      Family,
+		Description: "Reports every statement",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Statement] {
+		return &Identity{Opts: opts}
+	})
+}
+
+func (c *Identity) Traverse(val *types.Statement) error {
 	c.Count++
-	if c.NotFirst {
-		fmt.Println(",")
-	} else {
-		fmt.Println("[")
-	}
-	c.NotFirst = true
-	fmt.Println(val)
+	c.Statements = append(c.Statements, val)
+	return nil
 }
 
 func (c *Identity) GetKey(r *types.Statement) string {
 	return ""
 }
 
-func (c *Identity) Result() string {
-	return "]"
+func (c *Identity) Result() (*report.Table, error) {
+	t := report.NewTable(traverser.TypeName(c))
+	section := t.AddTypedSection("",
+		report.Column{Name: "Block Number", Kind: report.Int},
+		report.Column{Name: "Transaction Index", Kind: report.Int},
+		report.Column{Name: "Log Index", Kind: report.Int},
+		report.Column{Name: "Date", Kind: report.Date},
+		report.Column{Name: "Accounted For", Kind: report.Address},
+		report.Column{Name: "Asset", Kind: report.Address},
+		report.Column{Name: "Symbol", Kind: report.String},
+		report.Column{Name: "Decimals", Kind: report.Int},
+		report.Column{Name: "Beg Bal", Kind: report.Amount},
+		report.Column{Name: "Amount Net", Kind: report.Amount},
+		report.Column{Name: "End Bal", Kind: report.Amount},
+		report.Column{Name: "Spot Price", Kind: report.Float},
+		report.Column{Name: "Reconciled", Kind: report.Bool},
+	)
+	for _, r := range c.Statements {
+		section.Append(
+			r.BlockNumber,
+			r.TransactionIndex,
+			r.LogIndex,
+			r.Date(),
+			r.AccountedFor,
+			r.Asset,
+			r.Symbol,
+			r.Decimals,
+			&r.BegBal,
+			r.AmountNet(),
+			&r.EndBal,
+			r.SpotPrice.Float64(),
+			r.Reconciled(),
+		)
+	}
+	return t, nil
 }
 
 func (a *Identity) Name() string {
 	return colors.Green + reflect.TypeOf(a).Elem().String() + colors.Off + ": " + fmt.Sprintf("%d", a.Count)
 }
 
-func (c *Identity) Sort(array []*types.Statement) {
-	// Nothing to do
+func (c *Identity) Order() traverser.Ordering {
+	return traverser.Unsorted
+}
+
+func (c *Identity) State() any {
+	return &struct {
+		Count      *uint64
+		Statements *[]*types.Statement
+	}{&c.Count, &c.Statements}
 }
@@ -1,36 +1,327 @@
 package accounting
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"math/big"
 	"reflect"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
 // --------------------------------
+// OfxWriter writes the statements as an OFX 2.2 file of bank statements, one for each
+// account and asset, so that bookkeeping software can import them. Amounts are in dollars
+// at the spot price of each statement or, only with --denom units, in units of the asset.
+// Each file holds the statements traversed since the last one was written, along with
+// the balance of every account and asset seen so far.
 type OfxWriter struct {
-	Opts  traverser.Options
-	Value uint64
+	Opts       traverser.Options
+	Holdings   map[string]*ofxHolding
+	statements []*types.Statement
 }
 
-func (c *OfxWriter) Traverse(r *types.Statement) {
-	// SendToOfx(connection, r)
+// ofxHolding is an account's holdings of an asset, as of the last file written: what a
+// statement needs of an account and asset that has no transactions since.
+type ofxHolding struct {
+	Account base.Address   `json:"account"`
+	Asset   base.Address   `json:"asset"`
+	Symbol  string         `json:"symbol"`
+	Balance string         `json:"balance"`
+	AsOf    base.Timestamp `json:"asOf"`
+}
+
+func init() {
+	traverser.Register(traverser.Registration{
+		Name:        "ofx",
+		Family:      Family,
+		Description: "Writes an OFX bank statement for each account and asset to Ledger.ofx",
+		Writes:      "Ledger.ofx",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Statement] {
+		return &OfxWriter{Opts: opts, Holdings: map[string]*ofxHolding{}}
+	})
+}
+
+// Init refuses amounts in wei, which no bookkeeping software would take for a currency.
+func (c *OfxWriter) Init() error {
+	if c.Opts.Denom == "wei" {
+		return errors.New("OFX amounts are in dollars, or with --denom units in units, not in wei")
+	}
+	return nil
+}
+
+// Traverse fails for a statement whose OFX account id is already that of another account
+// or asset, rather than merge the two.
+func (c *OfxWriter) Traverse(r *types.Statement) error {
+	id := c.GetKey(r)
+	h, ok := c.Holdings[id]
+	if !ok {
+		h = &ofxHolding{Account: r.AccountedFor, Asset: r.Asset, Symbol: r.Symbol}
+		c.Holdings[id] = h
+	} else if h.Account != r.AccountedFor || h.Asset != r.Asset {
+		return fmt.Errorf("OFX account id %s of %s %s is already that of %s %s", id, r.AccountedFor.Hex(), r.Asset.Hex(), h.Account.Hex(), h.Asset.Hex())
+	}
+	c.statements = append(c.statements, r)
+	return nil
 }
 
 func (c *OfxWriter) GetKey(r *types.Statement) string {
-	return ""
+	return ofxAccountId(r.AccountedFor, r.Asset)
 }
 
-func (c *OfxWriter) Result() string {
-	return c.Name() + "\n\t" + c.reportValue("OfxWriter: ", c.Value)
+// Result writes the file and reports the account of each OFX account id. The holdings
+// are brought up to date only once the file is written, so that a file that cannot be
+// written leaves its statements to the next.
+func (c *OfxWriter) Result() (*report.Table, error) {
+	ids, doc, holdings := c.document()
+	path := c.Opts.ChainFile("Ledger.ofx")
+	if err := report.WriteFile(path, func(w io.Writer) error { return writeOfx(w, doc) }); err != nil {
+		return nil, fmt.Errorf("writing %s: %w", path, err)
+	}
+	c.Holdings, c.statements = holdings, nil
+
+	t := report.NewTable(traverser.TypeName(c))
+	t.AddSummary("File", path)
+	t.AddSummary("Currency", c.currency())
+	section := t.AddSection("", "AcctId", "Account", "AccountName", "Asset", "Symbol", "Transactions", "Balance")
+	for i, s := range doc.Bank.Statements {
+		h := c.Holdings[ids[i]]
+		section.Append(s.Statement.From.AcctId, h.Account.Hex(), c.Opts.Names[h.Account].Name,
+			h.Asset.Hex(), h.Symbol, len(s.Statement.Transactions.Transactions), s.Statement.Balance.Amount)
+	}
+	return t, nil
 }
 
 func (c *OfxWriter) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *OfxWriter) reportValue(msg string, v uint64) string {
-	return fmt.Sprintf("%s%d", msg, v)
+// Order is chronological so that the transactions of each statement are in date order.
+func (c *OfxWriter) Order() traverser.Ordering {
+	return traverser.Chronological
+}
+
+// State is the balance and date of each account and asset as of the last file, which is
+// all the next file needs of the statements already written.
+func (c *OfxWriter) State() any {
+	return &c.Holdings
+}
+
+// currency is the ISO 4217 code of the amounts, XXX (no currency) when they are in units
+// of each asset.
+func (c *OfxWriter) currency() string {
+	if c.Opts.Denom == "units" {
+		return "XXX"
+	}
+	return "USD"
+}
+
+// document returns the statement of every account and asset, in the order of their OFX
+// account ids, along with the ids and a copy of the holdings brought up to date with the
+// statements traversed since the last file. The holding as of the last file begins its
+// statement.
+func (c *OfxWriter) document() ([]string, *ofxDocument, map[string]*ofxHolding) {
+	byId := map[string][]*types.Statement{}
+	for _, r := range c.statements {
+		id := c.GetKey(r)
+		byId[id] = append(byId[id], r)
+	}
+
+	var last base.Timestamp
+	ids := slices.Sorted(maps.Keys(c.Holdings))
+	doc := &ofxDocument{}
+	holdings := make(map[string]*ofxHolding, len(c.Holdings))
+	for i, id := range ids {
+		h, stmts := *c.Holdings[id], byId[id]
+		holdings[id] = &h
+		start := h.AsOf
+		if len(stmts) > 0 {
+			end := stmts[len(stmts)-1]
+			if start == 0 {
+				start = stmts[0].Timestamp
+			}
+			h.Balance, h.AsOf = c.amount(end, &end.EndBal), end.Timestamp
+		}
+		s := ofxStatement{
+			Currency: c.currency(),
+			From: ofxAccount{
+				BankId:   truncate(c.Opts.Chain, 9),
+				AcctId:   id,
+				AcctType: "CHECKING",
+			},
+			Transactions: ofxTransactions{
+				Start: ofxDate(start),
+				End:   ofxDate(h.AsOf),
+			},
+			Balance: ofxBalance{
+				Amount: h.Balance,
+				AsOf:   ofxDate(h.AsOf),
+			},
+		}
+		for _, r := range stmts {
+			s.Transactions.Transactions = append(s.Transactions.Transactions, c.transaction(r))
+		}
+		last = max(last, h.AsOf)
+		doc.Bank.Statements = append(doc.Bank.Statements, ofxStatementResponse{
+			TrnUid:    fmt.Sprint(i + 1),
+			Status:    ofxOk,
+			Statement: s,
+		})
+	}
+	// The file is dated by its last balance, so that the same records make the same file
+	doc.SignOn.Response = ofxSignOn{Status: ofxOk, Server: ofxDate(last), Language: "ENG"}
+	return ids, doc, holdings
+}
+
+func (c *OfxWriter) transaction(r *types.Statement) ofxTransaction {
+	net := r.AmountNet()
+	trnType, other := "CREDIT", r.Sender
+	if net.LessThan(base.ZeroWei) {
+		trnType, other = "DEBIT", r.Recipient
+	}
+	memo := fmt.Sprintf("%s %s %s", r.Symbol, strings.ToLower(trnType), other.Hex())
+	if c.currency() == "USD" && r.SpotPrice.IsZero() {
+		memo += " (not priced)"
+	}
+	return ofxTransaction{
+		Type:   trnType,
+		Posted: ofxDate(r.Timestamp),
+		Amount: c.amount(r, net),
+		FitId:  fmt.Sprintf("%s-%d", r.TransactionHash.Hex(), r.LogIndex),
+		Name:   c.payee(other),
+		Memo:   truncate(memo, 255),
+	}
+}
+
+// amount returns an amount of the statement's asset in dollars at the statement's spot
+// price, or in units.
+func (c *OfxWriter) amount(r *types.Statement, wei *base.Wei) string {
+	v := new(big.Float).SetPrec(256).SetInt(wei.BigInt())
+	ten := new(big.Float).SetPrec(256).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(r.Decimals)), nil))
+	v.Quo(v, ten)
+	if c.currency() == "USD" {
+		spot := (big.Float)(r.SpotPrice)
+		return v.Mul(v, &spot).Text('f', 2)
+	}
+	return v.Text('f', int(r.Decimals))
+}
+
+// payee returns the name of an address, or the address shortened to the 32 characters
+// OFX allows.
+func (c *OfxWriter) payee(addr base.Address) string {
+	if name := displayName(addr, c.Opts.Names); name != "" {
+		return truncate(name, 32)
+	}
+	text := addr.Hex()
+	return text[:12] + "..." + text[len(text)-8:]
+}
+
+// ofxAccountId identifies an account's holdings of an asset in the 22 characters OFX
+// allows an account id, with the first 22 hex digits of the SHA-256 of the two addresses.
+// Two holdings with the same id are refused (see Traverse) rather than merged.
+func ofxAccountId(account, asset base.Address) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(account.Hex() + asset.Hex())))
+	return hex.EncodeToString(sum[:])[:22]
+}
+
+func ofxDate(ts base.Timestamp) string {
+	return time.Unix(int64(ts), 0).UTC().Format("20060102150405") + "[0:GMT]"
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// writeOfx writes the headers of an OFX 2.2 file followed by the document.
+func writeOfx(w io.Writer, doc *ofxDocument) error {
+	header := `<?xml version="1.0" encoding="UTF-8" standalone="no"?>` + "\n" +
+		`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// The elements of an OFX file of bank statements, named as in the OFX 2.2 specification.
+type ofxDocument struct {
+	XMLName xml.Name `xml:"OFX"`
+	SignOn  struct {
+		Response ofxSignOn `xml:"SONRS"`
+	} `xml:"SIGNONMSGSRSV1"`
+	Bank struct {
+		Statements []ofxStatementResponse `xml:"STMTTRNRS"`
+	} `xml:"BANKMSGSRSV1"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+var ofxOk = ofxStatus{Code: 0, Severity: "INFO"}
+
+type ofxSignOn struct {
+	Status   ofxStatus `xml:"STATUS"`
+	Server   string    `xml:"DTSERVER"`
+	Language string    `xml:"LANGUAGE"`
+}
+
+type ofxStatementResponse struct {
+	TrnUid    string       `xml:"TRNUID"`
+	Status    ofxStatus    `xml:"STATUS"`
+	Statement ofxStatement `xml:"STMTRS"`
+}
+
+type ofxStatement struct {
+	Currency     string          `xml:"CURDEF"`
+	From         ofxAccount      `xml:"BANKACCTFROM"`
+	Transactions ofxTransactions `xml:"BANKTRANLIST"`
+	Balance      ofxBalance      `xml:"LEDGERBAL"`
+}
+
+type ofxAccount struct {
+	BankId   string `xml:"BANKID"`
+	AcctId   string `xml:"ACCTID"`
+	AcctType string `xml:"ACCTTYPE"`
+}
+
+type ofxTransactions struct {
+	Start        string           `xml:"DTSTART"`
+	End          string           `xml:"DTEND"`
+	Transactions []ofxTransaction `xml:"STMTTRN"`
+}
+
+type ofxTransaction struct {
+	Type   string `xml:"TRNTYPE"`
+	Posted string `xml:"DTPOSTED"`
+	Amount string `xml:"TRNAMT"`
+	FitId  string `xml:"FITID"`
+	Name   string `xml:"NAME"`
+	Memo   string `xml:"MEMO"`
+}
+
+type ofxBalance struct {
+	Amount string `xml:"BALAMT"`
+	AsOf   string `xml:"DTASOF"`
 }
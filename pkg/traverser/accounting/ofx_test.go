@@ -0,0 +1,181 @@
+package accounting
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/synth"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+var treasury = base.HexToAddress("0xf503017d7baf7fbc0fff7492b751025c6a78179b")
+
+// writeOfxRun feeds the statements to a writer resumed from the state, if any, and
+// returns the document it wrote and its state.
+func writeOfxRun(t *testing.T, opts traverser.Options, state []byte, statements []*types.Statement) (*ofxDocument, []byte) {
+	t.Helper()
+	c := &OfxWriter{Opts: opts, Holdings: map[string]*ofxHolding{}}
+	if state != nil {
+		if err := json.Unmarshal(state, c.State()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, r := range statements {
+		if err := c.Traverse(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := c.Result(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("Ledger.ofx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := &ofxDocument{}
+	if err := xml.Unmarshal(data, doc); err != nil {
+		t.Fatal(err)
+	}
+	if state, err = json.Marshal(c.State()); err != nil {
+		t.Fatal(err)
+	}
+	return doc, state
+}
+
+func TestOfxWriter(t *testing.T) {
+	colors.ColorsOff()
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	statements := synth.Generate(synth.Config{Seed: 1, Accounts: []base.Address{treasury}}).Statements[treasury]
+	half := len(statements) / 2
+
+	tests := []struct {
+		denom    string
+		currency string
+	}{
+		{"", "USD"},
+		{"usd", "USD"},
+		{"units", "XXX"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.currency+"/"+tt.denom, func(t *testing.T) {
+			opts := traverser.Options{Chain: "mainnet", Denom: tt.denom}
+			full, _ := writeOfxRun(t, opts, nil, statements)
+			first, state := writeOfxRun(t, opts, nil, statements[:half])
+			resumed, _ := writeOfxRun(t, opts, state, statements[half:])
+
+			// The resumed file has the transactions since the first, and the same balances
+			// as a file written in one go
+			if len(resumed.Bank.Statements) != len(full.Bank.Statements) {
+				t.Fatalf("got %d statements, want %d", len(resumed.Bank.Statements), len(full.Bank.Statements))
+			}
+			ends := map[string]string{}
+			for _, s := range first.Bank.Statements {
+				ends[s.Statement.From.AcctId] = s.Statement.Transactions.End
+			}
+			fitIds, nTransactions := map[string]bool{}, 0
+			for i, s := range resumed.Bank.Statements {
+				want := full.Bank.Statements[i].Statement
+				got := s.Statement
+				if got.Currency != tt.currency || got.From != want.From || got.Balance != want.Balance {
+					t.Errorf("statement %d: got %s %+v %+v, want %s %+v %+v", i, got.Currency, got.From, got.Balance, tt.currency, want.From, want.Balance)
+				}
+				if end, ok := ends[got.From.AcctId]; ok && got.Transactions.Start != end {
+					t.Errorf("statement %d: starts %s, want the end of the first file %s", i, got.Transactions.Start, end)
+				}
+				for _, trn := range want.Transactions.Transactions {
+					if fitIds[trn.FitId] {
+						t.Errorf("FITID %s repeated", trn.FitId)
+					}
+					fitIds[trn.FitId] = true
+				}
+				nTransactions += len(got.Transactions.Transactions)
+			}
+			if nTransactions != len(statements)-half {
+				t.Errorf("got %d transactions, want %d", nTransactions, len(statements)-half)
+			}
+			if len(state) > 4096 {
+				t.Errorf("got a state of %d bytes, want one that does not grow with the statements", len(state))
+			}
+		})
+	}
+}
+
+func TestOfxWriteFailure(t *testing.T) {
+	colors.ColorsOff()
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	statements := synth.Generate(synth.Config{Seed: 1, Accounts: []base.Address{treasury}}).Statements[treasury]
+	c := &OfxWriter{Opts: traverser.Options{Chain: "mainnet"}, Holdings: map[string]*ofxHolding{}}
+	for _, r := range statements {
+		if err := c.Traverse(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A folder in the way of the file fails the write, which leaves the holdings as they were
+	if err := os.MkdirAll(filepath.Join("Ledger.ofx", "in the way"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Result(); err == nil {
+		t.Fatal("Result wrote over a folder")
+	}
+	for id, h := range c.Holdings {
+		if h.AsOf != 0 || h.Balance != "" {
+			t.Errorf("%s: got a holding of %s as of %d, want none before a file is written", id, h.Balance, h.AsOf)
+		}
+	}
+
+	// The next file holds the statements the failed one could not
+	if err := os.RemoveAll("Ledger.ofx"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Result(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile("Ledger.ofx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := &ofxDocument{}
+	if err := xml.Unmarshal(data, doc); err != nil {
+		t.Fatal(err)
+	}
+	nTransactions := 0
+	for _, s := range doc.Bank.Statements {
+		nTransactions += len(s.Statement.Transactions.Transactions)
+	}
+	if nTransactions != len(statements) {
+		t.Errorf("got %d transactions, want %d", nTransactions, len(statements))
+	}
+}
+
+func TestOfxAccountIdCollision(t *testing.T) {
+	r := synth.Generate(synth.Config{Seed: 1, Accounts: []base.Address{treasury}}).Statements[treasury][0]
+	c := &OfxWriter{Holdings: map[string]*ofxHolding{
+		ofxAccountId(r.AccountedFor, r.Asset): {Account: base.HexToAddress("0x01"), Asset: r.Asset},
+	}}
+	if err := c.Traverse(r); err == nil {
+		t.Error("Traverse merged two accounts with the same OFX account id")
+	}
+	if len(c.statements) != 0 {
+		t.Errorf("got %d statements, want none", len(c.statements))
+	}
+	if err := (&OfxWriter{Opts: traverser.Options{Denom: "wei"}}).Init(); err == nil {
+		t.Error("Init accepted amounts in wei")
+	}
+}
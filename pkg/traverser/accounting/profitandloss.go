@@ -1,18 +1,15 @@
 package accounting
 
 import (
-	"math/big"
-	"os"
-	"strings"
-	"text/tabwriter"
-
-	//    "accounting/pkg/utils"
 	"fmt"
+	"math/big"
 	"reflect"
+	"strings"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/utils"
 )
@@ -23,50 +20,53 @@ type ProfitAndLoss struct {
 	LastDate string
 	Ledgers  map[string]*types.Statement
 	LastKey  string
-	w        *tabwriter.Writer
+	LastSpot base.Float
+	table    *report.Table
+	rows     *report.Section
 }
 
-func (c *ProfitAndLoss) Traverse(r *types.Statement) {
+func init() {
+	traverser.Register(traverser.Registration{
+		Name:        "profit_and_loss",
+		Family:      Family,
+		Description: "Reports the change in each ledger per period",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Statement] {
+		return &ProfitAndLoss{Opts: opts}
+	})
+}
+
+func (c *ProfitAndLoss) Traverse(r *types.Statement) error {
 	if len(c.Ledgers) == 0 {
-		c.w = tabwriter.NewWriter(os.Stdout, 0, 0, 1, ',', 0)
-		if c.Ledgers == nil { // order matters
-			c.ReportHeader(c.Opts.Verbose, r)
-		}
 		c.Ledgers = make(map[string]*types.Statement)
 		c.LastKey = ""
 	}
 
-	if len(c.Opts.AddrFilters) > 0 && !c.Opts.AddrFilters[r.Asset] {
-		return
-	}
-
-	// fmt.Println(r)
 	key := c.GetKey(r)
 	l := c.Ledgers[key]
 	if l != nil {
 		// We have this ledger, so first report on the current reconciliation...
 		if c.Opts.Verbose > 0 {
-			c.Report("Tx", colors.BrightCyan, r.SpotPrice, r)
+			c.Report("Tx", r.SpotPrice, r)
 		}
 		// ...then accumulate it into the ledger
 		c.UpdateLedger(key, r)
 
 	} else {
 		if c.LastKey != "" {
-			c.Report("Summary", colors.BrightYellow, r.SpotPrice, c.Ledgers[c.LastKey])
-			if colors.White != "" {
-				fmt.Fprintln(c.w)
-			}
+			c.Report("Summary", r.SpotPrice, c.Ledgers[c.LastKey])
 		}
 		if c.Opts.Verbose > 0 {
-			c.Report("Tx", colors.BrightCyan, r.SpotPrice, r)
+			c.Report("Tx", r.SpotPrice, r)
 		}
-		// Remember the current ledger
-		c.Ledgers[key] = r
+		// Remember the current ledger, on a copy since the statement is shared with other traversers
+		ledger := *r
+		c.Ledgers[key] = &ledger
 	}
 
 	c.LastDate = base.GetDateKey(c.Opts.Period, r.DateTime())
 	c.LastKey = key
+	c.LastSpot = r.SpotPrice
+	return nil
 }
 
 func (c *ProfitAndLoss) GetKey(r *types.Statement) string {
@@ -81,16 +81,36 @@ func (c *ProfitAndLoss) GetAsset(r *types.Statement) string {
 	return fmt.Sprintf("%s-%s-%s", r.Asset.String(), r.Symbol, r.AccountedFor.String())
 }
 
-func (c *ProfitAndLoss) Result() string {
-	return ""
+// Result closes the last open ledger and returns every row reported along the way.
+func (c *ProfitAndLoss) Result() (*report.Table, error) {
+	if c.LastKey != "" && c.Ledgers[c.LastKey] != nil {
+		c.Report("Summary", c.LastSpot, c.Ledgers[c.LastKey])
+		c.LastKey = ""
+	}
+	c.section()
+	return c.table, nil
 }
 
 func (a *ProfitAndLoss) Name() string {
 	return colors.Green + reflect.TypeOf(a).Elem().String() + colors.Off
 }
 
-func (c *ProfitAndLoss) Sort(array []*types.Statement) {
-	// Nothing to do
+// Order keeps the statements of each ledger together, so that a ledger is summarized
+// once, when the next one starts.
+func (c *ProfitAndLoss) Order() traverser.Ordering {
+	return traverser.ByAccountAsset
+}
+
+// State is the ledger of each asset and the period last reported, along with the rows
+// reported so far, so that a resumed run reports every period.
+func (c *ProfitAndLoss) State() any {
+	return &struct {
+		LastDate *string
+		Ledgers  *map[string]*types.Statement
+		LastKey  *string
+		LastSpot *base.Float
+		Rows     *[][]any
+	}{&c.LastDate, &c.Ledgers, &c.LastKey, &c.LastSpot, &c.section().Rows}
 }
 
 func ToFmtStrFloat(denom string, decimals base.Value, spot base.Float, x string) string {
@@ -132,64 +152,26 @@ func (c *ProfitAndLoss) UpdateLedger(key string, r *types.Statement) {
 	c.Ledgers[key].EndBal = r.EndBal
 }
 
-func Display(color string, a base.Address, aF *base.Address, verbose int, nMap map[base.Address]types.Name) string {
-	n := nMap[base.HexToAddress(a.String())].Name
-	n = strings.Replace(strings.Replace(n, ",", "", -1), "#", "", -1)
-	if len(n) > 0 {
-		n = "," + n
-	} else {
-		n = "," + colors.BrightBlack + a.String() + colors.Off
-	}
-
-	ad := a.String()
-	if aF != nil && a == *aF {
-		return colors.Green + ad + colors.Off + n
-	}
-
-	if verbose > 0 {
-		return color + ad + colors.Off + n
-	}
-
-	if len(ad) < 15 {
-		return color + ad + strings.Repeat(" ", 17-len(ad)) + colors.Off + n
-	}
-	return color + ad[0:8] + "..." + ad[len(ad)-6:] + colors.Off + n
+func displayName(a base.Address, nMap map[base.Address]types.Name) string {
+	return strings.Replace(strings.Replace(nMap[a].Name, ",", "", -1), "#", "", -1)
 }
 
-func (c *ProfitAndLoss) Report(msg, color string, spot base.Float, r *types.Statement) {
-	if len(c.Opts.AddrFilters) > 0 && !c.Opts.AddrFilters[r.Asset] {
-		return
-	}
-
-	f := func(x big.Float) uint64 {
-		v, _ := x.Uint64()
-		return v
-	}
-
+func (c *ProfitAndLoss) Report(msg string, spot base.Float, r *types.Statement) {
 	denom := c.Opts.Denom
 	if denom == "usd" && r.SpotPrice.IsZero() {
 		denom = "not-priced"
 	}
-	date := colors.Red + base.GetDateKey(c.Opts.Period, r.DateTime())
+	date := base.GetDateKey(c.Opts.Period, r.DateTime())
 	if msg != "Summary" {
-		date = colors.Red + base.GetDateKey("secondly", r.DateTime())
+		date = base.GetDateKey("secondly", r.DateTime())
 	}
-	symbol := colors.Green + r.Symbol
-	asset := color + Display(color, r.Asset, nil, c.Opts.Verbose, c.Opts.Names)
-	sender := Display(color, r.Sender, &r.AccountedFor, c.Opts.Verbose, c.Opts.Names)
-	recipient := Display(color, r.Recipient, &r.AccountedFor, c.Opts.Verbose, c.Opts.Names)
 	var x big.Float
 	x.SetString(r.BegBal.Text(10))
-	beg := color + ToFmtStr(c.Opts.Denom, r.Decimals, spot, &x)
+	beg := ToFmtStr(c.Opts.Denom, r.Decimals, spot, &x)
 	x.SetString(r.AmountNet().Text(10))
 	net := ToFmtStr(c.Opts.Denom, r.Decimals, spot, &x)
 	x.SetString(r.EndBal.Text(10))
 	end := ToFmtStr(c.Opts.Denom, r.Decimals, spot, &x)
-	if f(x) == 0 {
-		end = colors.BrightBlack + end
-	}
-	bigTotIn := big.Float{}
-	bigTotIn.SetString(r.TotalIn().Text(10))
 	totIn := ToFmtStrFloat(c.Opts.Denom, r.Decimals, spot, r.TotalIn().Text(10))
 	gasOut := ToFmtStrFloat(c.Opts.Denom, r.Decimals, spot, r.GasOut.Text(10))
 	totOutLessGas := ToFmtStrFloat(c.Opts.Denom, r.Decimals, spot, r.TotalOutLessGas().Text(10))
@@ -197,170 +179,93 @@ func (c *ProfitAndLoss) Report(msg, color string, spot base.Float, r *types.Stat
 	if len(sig) == 0 {
 		sig = r.Encoding()
 	}
-	sig = colors.White + sig
-	if msg == "Summary" {
-		sig = "---------------"
-	}
-	hash := color + r.TransactionHash.String()
 
-	checks := map[bool]string{false: colors.Red + "x", true: colors.Green + "ok"}
-	check := checks[r.Reconciled()]
+	var blockNumber, transactionIndex, logIndex, spotPrice, reconType any
+	var hash, sender, senderName, recipient, recipientName, priceSource any
+	if msg != "Summary" {
+		blockNumber, transactionIndex, logIndex = r.BlockNumber, r.TransactionIndex, r.LogIndex
+		hash, priceSource, spotPrice, reconType = r.TransactionHash, r.PriceSource, r.SpotPrice.Float64(), r.ReconciliationType()
+		sender, senderName = r.Sender, displayName(r.Sender, c.Opts.Names)
+		recipient, recipientName = r.Recipient, displayName(r.Recipient, c.Opts.Names)
+	} else {
+		sig = ""
+	}
+	asset, assetName := r.Asset, displayName(r.Asset, c.Opts.Names)
 
-	var row string
-	if msg == "Summary" {
-		if c.Opts.Verbose > 1 {
-			row = fmt.Sprintf(
-				"%s\t\t\t\t\t%s\t%s\t%s\t%s\t\t\t\t\t\t\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\t\t%s%s",
-				msg,
-				date,
-				r.AccountedFor,
-				symbol,
-				asset,
-				r.Decimals,
-				denom,
-				beg,
-				net,
-				end,
-				totIn,
-				gasOut,
-				totOutLessGas,
-				check,
-				colors.Off)
-		} else {
-			row = fmt.Sprintf(
-				"%s\t\t\t%s\t%s\t%s\t\t\t\t\t\t\t%d\t%s\t%s\t%s\t%s\t\t\t%s%s",
-				msg,
-				date,
-				symbol,
-				asset,
-				r.Decimals,
-				denom,
-				beg,
-				net,
-				end,
-				check,
-				colors.Off)
-		}
+	if c.Opts.Verbose > 1 {
+		c.section().Append(msg, blockNumber, transactionIndex, logIndex, hash, date, r.AccountedFor, r.Symbol, asset, assetName,
+			sender, senderName, recipient, recipientName, priceSource, spotPrice, r.Decimals, denom,
+			beg, net, end, totIn, gasOut, totOutLessGas, sig, reconType, r.Reconciled())
 	} else {
-		if c.Opts.Verbose > 1 {
-			row = fmt.Sprintf(
-				"%s\t%d\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s",
-				msg,
-				r.BlockNumber,
-				r.TransactionIndex,
-				r.LogIndex,
-				hash,
-				date,
-				r.AccountedFor,
-				symbol,
-				asset,
-				sender,
-				recipient,
-				r.PriceSource,
-				r.SpotPrice.String(),
-				r.Decimals,
-				denom,
-				beg,
-				net,
-				end,
-				totIn,
-				gasOut,
-				totOutLessGas,
-				sig,
-				r.ReconciliationType(),
-				check,
-				colors.Off)
-		} else {
-			row = fmt.Sprintf(
-				"%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s%s",
-				msg,
-				r.BlockNumber,
-				r.TransactionIndex,
-				date,
-				symbol,
-				asset,
-				sender,
-				recipient,
-				r.PriceSource,
-				r.SpotPrice.String(),
-				r.Decimals,
-				denom,
-				beg,
-				net,
-				end,
-				sig,
-				r.ReconciliationType(),
-				check,
-				colors.Off)
-		}
+		c.section().Append(msg, blockNumber, transactionIndex, date, r.Symbol, asset, assetName,
+			sender, senderName, recipient, recipientName, priceSource, spotPrice, r.Decimals, denom,
+			beg, net, end, sig, reconType, r.Reconciled())
 	}
-	fmt.Fprintf(c.w, "%s\n", row)
-	c.w.Flush()
 }
 
-func (c *ProfitAndLoss) ReportHeader(verbose int, r *types.Statement) {
-	var fields []string
-	if verbose > 1 {
-		fields = []string{
-			"type",
-			"blockNumber",
-			"transactionIndex",
-			"logIndex",
-			"transactionHash",
-			"date",
-			"accountedFor",
-			"assetSymbol",
-			"assetAddress",
-			"assetName",
-			"sender",
-			"senderName",
-			"recipient",
-			"recipientName",
-			"priceSource",
-			"spotPrice",
-			"decimals",
-			"denom",
-			"begBal",
-			"amountNet",
-			"endBal",
-			"totalIn",
-			"gasOut",
-			"totalOutLessGas",
-			"function",
-			"reconciliationType",
-			"reconciled",
+// section creates the table on first use. The columns depend on the verbosity.
+func (c *ProfitAndLoss) section() *report.Section {
+	if c.rows != nil {
+		return c.rows
+	}
+
+	var columns []report.Column
+	if c.Opts.Verbose > 1 {
+		columns = []report.Column{
+			{Name: "type"},
+			{Name: "blockNumber", Kind: report.Int},
+			{Name: "transactionIndex", Kind: report.Int},
+			{Name: "logIndex", Kind: report.Int},
+			{Name: "transactionHash", Kind: report.Hash},
+			{Name: "date", Kind: report.Date},
+			{Name: "accountedFor", Kind: report.Address},
+			{Name: "assetSymbol"},
+			{Name: "assetAddress", Kind: report.Address},
+			{Name: "assetName"},
+			{Name: "sender", Kind: report.Address},
+			{Name: "senderName"},
+			{Name: "recipient", Kind: report.Address},
+			{Name: "recipientName"},
+			{Name: "priceSource"},
+			{Name: "spotPrice", Kind: report.Float},
+			{Name: "decimals", Kind: report.Int},
+			{Name: "denom"},
+			{Name: "begBal", Kind: report.Amount},
+			{Name: "amountNet", Kind: report.Amount},
+			{Name: "endBal", Kind: report.Amount},
+			{Name: "totalIn", Kind: report.Amount},
+			{Name: "gasOut", Kind: report.Amount},
+			{Name: "totalOutLessGas", Kind: report.Amount},
+			{Name: "function"},
+			{Name: "reconciliationType"},
+			{Name: "reconciled", Kind: report.Bool},
 		}
 	} else {
-		fields = []string{
-			"type",
-			"blockNumber",
-			"transactionIndex",
-			"date",
-			"assetSymbol",
-			"assetAddress",
-			"assetName",
-			"sender",
-			"senderName",
-			"recipient",
-			"recipientName",
-			"priceSource",
-			"spotPrice",
-			"decimals",
-			"denom",
-			"begBal",
-			"amountNet",
-			"endBal",
-			"function",
-			"reconciliationType",
-			"reconciled",
-		}
-	}
-	for i, f := range fields {
-		if i > 0 {
-			fmt.Fprint(c.w, ",")
+		columns = []report.Column{
+			{Name: "type"},
+			{Name: "blockNumber", Kind: report.Int},
+			{Name: "transactionIndex", Kind: report.Int},
+			{Name: "date", Kind: report.Date},
+			{Name: "assetSymbol"},
+			{Name: "assetAddress", Kind: report.Address},
+			{Name: "assetName"},
+			{Name: "sender", Kind: report.Address},
+			{Name: "senderName"},
+			{Name: "recipient", Kind: report.Address},
+			{Name: "recipientName"},
+			{Name: "priceSource"},
+			{Name: "spotPrice", Kind: report.Float},
+			{Name: "decimals", Kind: report.Int},
+			{Name: "denom"},
+			{Name: "begBal", Kind: report.Amount},
+			{Name: "amountNet", Kind: report.Amount},
+			{Name: "endBal", Kind: report.Amount},
+			{Name: "function"},
+			{Name: "reconciliationType"},
+			{Name: "reconciled", Kind: report.Bool},
 		}
-		fmt.Fprint(c.w, f)
 	}
-	c.w.Write([]byte{'\n'})
-	c.w.Flush()
+	c.table = report.NewTable(traverser.TypeName(c))
+	c.rows = c.table.AddTypedSection("", columns...)
+	return c.rows
 }
@@ -1,9 +1,7 @@
 package accounting
 
 import (
-	"os"
 	"testing"
-	"text/tabwriter"
 	"time"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
@@ -31,15 +29,19 @@ func TestProfitAndLossBasic(t *testing.T) {
 	if diff := deep.Equal(c.Ledgers[wantKey], want); diff != nil {
 		t.Errorf("Statement differs: %v", diff)
 	}
-	if got := c.Result(); got != "" {
-		t.Errorf("Result differs: got %q, want empty string", got)
-	}
 	if got := c.LastDate; got != "2025-03-26" {
 		t.Errorf("LastDate differs: got %q, want \"2025-03-26\"", got)
 	}
 	if got := c.LastKey; got != wantKey {
 		t.Errorf("LastKey differs: got %q, want %q", got, wantKey)
 	}
+	table, err := c.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(table.Sections[0].Rows); got != 1 {
+		t.Errorf("Result differs: got %d rows, want 1", got)
+	}
 }
 
 func TestProfitAndLossTwoStatements(t *testing.T) {
@@ -48,21 +50,12 @@ func TestProfitAndLossTwoStatements(t *testing.T) {
 		Period: "daily",
 		Denom:  "wei",
 	}
-	old := os.Stdout
-	_, w, _ := os.Pipe()
-	os.Stdout = w
-	defer func() {
-		w.Close()
-		os.Stdout = old
-	}()
 	c := &ProfitAndLoss{
 		Opts:    opts,
 		Ledgers: make(map[string]*types.Statement),
-		w:       tabwriter.NewWriter(os.Stdout, 0, 0, 1, ',', 0),
 	}
 	c.Traverse(want)
 	c.Traverse(want2)
-	c.w.Flush()
 	if len(c.Ledgers) != 2 {
 		t.Errorf("Ledger count differs: got %d, want 2", len(c.Ledgers))
 	}
@@ -74,15 +67,63 @@ func TestProfitAndLossTwoStatements(t *testing.T) {
 	if diff := deep.Equal(c.Ledgers[wantKey2], want2); diff != nil {
 		t.Errorf("Second statement differs: %v", diff)
 	}
-	if got := c.Result(); got != "" {
-		t.Errorf("Result differs: got %q, want empty string", got)
-	}
 	if got := c.LastDate; got != "2025-03-27" {
 		t.Errorf("LastDate differs: got %q, want \"2025-03-27\"", got)
 	}
 	if got := c.LastKey; got != wantKey2 {
 		t.Errorf("LastKey differs: got %q, want %q", got, wantKey2)
 	}
+	table, err := c.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := table.Sections[0].Rows
+	if len(rows) != 2 {
+		t.Fatalf("Result differs: got %d rows, want 2", len(rows))
+	}
+	if got := rows[0][3]; got != "2025-03-26" {
+		t.Errorf("First summary date differs: got %v, want \"2025-03-26\"", got)
+	}
+	if got := rows[1][17]; got != "500" {
+		t.Errorf("Second summary end balance differs: got %v, want \"500\"", got)
+	}
+}
+
+func TestProfitAndLossInterleaved(t *testing.T) {
+	colors.ColorsOff()
+	c := &ProfitAndLoss{
+		Opts: traverser.Options{
+			Period: "monthly",
+			Denom:  "wei",
+		},
+	}
+	// The account's statements alternate between two assets
+	other := func(r *types.Statement, tx base.Txnum, endBal int64) *types.Statement {
+		ret := *r
+		ret.Asset, ret.Symbol, ret.TransactionIndex, ret.EndBal = base.HexToAddress("0x2"), "DAI", tx, *base.NewWei(endBal)
+		return &ret
+	}
+	input := []*types.Statement{want, other(want, 1, 10), want2, other(want2, 1, 20)}
+	for _, r := range traverser.Sorted(input, c.Order()) {
+		if err := c.Traverse(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	table, err := c.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each ledger is summarized once, with the balance of its last statement
+	rows := table.Sections[0].Rows
+	if len(rows) != 2 {
+		t.Fatalf("Result differs: got %d rows, want 2", len(rows))
+	}
+	for i, want := range [][]any{{"ETH", "500"}, {"DAI", "20"}} {
+		if got := []any{rows[i][4], rows[i][17]}; got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("Summary %d differs: got %v, want %v", i, got, want)
+		}
+	}
 }
 
 var want = &types.Statement{
@@ -1,36 +1,62 @@
 package accounting
 
 import (
-	"fmt"
 	"reflect"
 
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/sqldb"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
 // --------------------------------
+// SqlWriter writes the statements, their assets, the accounts of interest and the names
+// of the addresses involved to a SQLite database (see package sqldb).
 type SqlWriter struct {
-	Opts  traverser.Options
-	Value uint64
+	*sqldb.Writer
+	Opts traverser.Options
 }
 
-func (c *SqlWriter) Traverse(r *types.Statement) {
-	// SendToSQL(connection, r)
+func init() {
+	traverser.Register(traverser.Registration{
+		Name:        "sql",
+		Family:      Family,
+		Description: "Writes the statements, with their assets, accounts and names, to the SQLite database Ledger.sqlite",
+		Writes:      "Ledger.sqlite",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Statement] {
+		return &SqlWriter{Writer: sqldb.NewWriter(opts, "Statements"), Opts: opts}
+	})
+}
+
+func (c *SqlWriter) Traverse(r *types.Statement) error {
+	db, err := c.DB()
+	if err != nil {
+		return err
+	}
+	for _, addr := range []base.Address{r.AccountedFor, r.Sender, r.Recipient, r.Asset} {
+		if name, ok := c.Opts.Names[addr]; ok {
+			if err := db.AddName(name); err != nil {
+				return err
+			}
+		}
+	}
+	return db.AddStatement(r)
 }
 
 func (c *SqlWriter) GetKey(r *types.Statement) string {
 	return ""
 }
 
-func (c *SqlWriter) Result() string {
-	return c.Name() + "\n\t" + c.reportValue("SqlWriter: ", c.Value)
+func (c *SqlWriter) Result() (*report.Table, error) {
+	return c.Report(traverser.TypeName(c))
 }
 
 func (c *SqlWriter) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *SqlWriter) reportValue(msg string, v uint64) string {
-	return fmt.Sprintf("%s%d", msg, v)
+func (c *SqlWriter) Order() traverser.Ordering {
+	return traverser.Unsorted
 }
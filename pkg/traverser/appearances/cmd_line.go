@@ -0,0 +1,13 @@
+package appearances
+
+import (
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+const Family = "appearances"
+
+func init() {
+	traverser.RegisterFamily[*types.Appearance](Family, "reports over the appearances of each account")
+	traverser.RegisterCounter[*types.Appearance](Family, "appearances")
+}
@@ -0,0 +1,41 @@
+package appearances
+
+import (
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+// --------------------------------
+// GroupBy counts the appearances by account or reason.
+type GroupBy = traverser.GroupCounter[*types.Appearance, groupKey]
+
+// groupKey holds the account or the reason of an appearance, as the way of counting has it.
+type groupKey struct {
+	Address base.Address `json:"address"`
+	Name    string       `json:"name"`
+	Reason  string       `json:"reason"`
+}
+
+func init() {
+	traverser.RegisterGroupCounters(Family, "Appearances",
+		traverser.CountBy[*types.Appearance, groupKey]{
+			Name:        "by_account",
+			Description: "Counts the appearances of each account",
+			Columns:     []string{"Address", "Name"},
+			Key: func(opts *traverser.Options, r *types.Appearance) groupKey {
+				return groupKey{Address: r.Address, Name: opts.NameOf(r.Address)}
+			},
+			Row: func(k groupKey) []any { return []any{k.Address, k.Name} },
+		},
+		traverser.CountBy[*types.Appearance, groupKey]{
+			Name:        "by_reason",
+			Description: "Counts the appearances for each reason",
+			Columns:     []string{"Reason"},
+			Key: func(opts *traverser.Options, r *types.Appearance) groupKey {
+				return groupKey{Reason: r.Reason}
+			},
+			Row: func(k groupKey) []any { return []any{k.Reason} },
+		},
+	)
+}
@@ -0,0 +1,121 @@
+package traverser
+
+import (
+	"log"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/names"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/expr"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/synth"
+)
+
+// Explorers are the block explorers linked to from reports, by chain.
+var Explorers = map[string]string{
+	"mainnet":  "https://etherscan.io",
+	"sepolia":  "https://sepolia.etherscan.io",
+	"gnosis":   "https://gnosisscan.io",
+	"optimism": "https://optimistic.etherscan.io",
+	"arbitrum": "https://arbiscan.io",
+	"base":     "https://basescan.org",
+	"polygon":  "https://polygonscan.com",
+}
+
+// ExplorerURL returns the link to an address or a transaction (kind is "address" or "tx")
+// on the block explorer of the chain, or an empty string if the chain has none.
+func (opts *Options) ExplorerURL(kind, id string) string {
+	explorer, ok := Explorers[opts.Chain]
+	if !ok {
+		return ""
+	}
+	return explorer + "/" + kind + "/" + id
+}
+
+// Chains returns the chains with accounts of interest in name order, or the chain of the
+// run if there are none.
+func (opts *Options) Chains() []string {
+	ret := []string{}
+	for chain, accounts := range opts.ChainAccounts {
+		for _, account := range accounts {
+			if opts.IsOfInterest(account.Tags) {
+				ret = append(ret, chain)
+				break
+			}
+		}
+	}
+	if len(ret) == 0 {
+		return []string{opts.Chain}
+	}
+	slices.Sort(ret)
+	return ret
+}
+
+// ForChain returns the options for processing the accounts on another chain than the
+// run's, with the names database of that chain. The records of the chain are read from
+// the folder of its name under the input folder, and the files written for it are named
+// as by ChainFile.
+func (opts Options) ForChain(chain string) (Options, error) {
+	if chain == opts.Chain {
+		return opts, nil
+	}
+
+	ret := opts
+	ret.Chain = chain
+	ret.Accounts = opts.ChainAccounts[chain]
+	ret.InputPath = filepath.Join(opts.InputPath, chain)
+	if opts.Checkpoint != "" {
+		ret.Checkpoint = ret.ChainFile(opts.Checkpoint)
+	}
+	ret.Names = ret.loadNames()
+	if opts.Filters.Where != nil {
+		var err error
+		if ret.Filters.Where, err = expr.Compile(opts.Filters.Where.Source, ret.Names); err != nil {
+			return opts, err
+		}
+	}
+	return ret, nil
+}
+
+// ChainFile returns the name of a file written for the options' chain. Files of the run's
+// own chain keep their name, while those of other chains have the chain added before the
+// extension (so Book1.xlsx becomes Book1-gnosis.xlsx).
+func (opts *Options) ChainFile(path string) string {
+	if opts.runChain == "" || opts.Chain == opts.runChain {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + opts.Chain + ext
+}
+
+// NamesDatabase reads the names of a chain from chifra's names database. Tests replace it
+// so that their results do not depend on the names installed.
+var NamesDatabase = func(chain string) (map[base.Address]types.Name, error) {
+	return names.LoadNamesMap(chain, types.Regular|types.Custom|types.Prefund, []string{})
+}
+
+// loadNames reads the names database of the options' chain, to which the names of the
+// synthetic source's counterparties (if it is used) and the configured accounts of every
+// chain are added. Those on the options' chain are added last so that their names win.
+func (opts *Options) loadNames() map[base.Address]types.Name {
+	ret, _ := NamesDatabase(opts.Chain)
+	if ret == nil {
+		ret = make(map[base.Address]types.Name)
+	}
+	ret[base.HexToAddress("0x")] = types.Name{Name: "Creation/Mint"}
+	if opts.Source == "synthetic" {
+		maps.Copy(ret, synth.Names())
+	}
+	for _, chain := range slices.Sorted(maps.Keys(opts.ChainAccounts)) {
+		if chain != opts.Chain {
+			maps.Copy(ret, opts.ChainAccounts[chain])
+		}
+	}
+	maps.Copy(ret, opts.ChainAccounts[opts.Chain])
+	log.Println(colors.Yellow+"Loaded", len(ret), "names for", opts.Chain, colors.Off)
+	return ret
+}
@@ -0,0 +1,58 @@
+package traverser
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+)
+
+func TestChains(t *testing.T) {
+	active := types.Name{Address: base.HexToAddress("0x1"), Tags: "00-Active"}
+	dead := types.Name{Address: base.HexToAddress("0x2"), Tags: "19-Dead"}
+	opts := Options{
+		Chain:    "mainnet",
+		runChain: "mainnet",
+		Tags:     []string{"00-Active"},
+		ChainAccounts: map[string]map[base.Address]types.Name{
+			"mainnet": {active.Address: active},
+			"gnosis":  {active.Address: active},
+			"sepolia": {dead.Address: dead},
+		},
+	}
+	if got, want := opts.Chains(), []string{"gnosis", "mainnet"}; !slices.Equal(got, want) {
+		t.Errorf("Chains: got %v, want %v", got, want)
+	}
+
+	if got := opts.ChainFile("Book1.xlsx"); got != "Book1.xlsx" {
+		t.Errorf("ChainFile on the run's chain: got %q", got)
+	}
+	if got, want := opts.ExplorerURL("tx", "0xabc"), "https://etherscan.io/tx/0xabc"; got != want {
+		t.Errorf("ExplorerURL: got %q, want %q", got, want)
+	}
+
+	opts.InputPath, opts.Checkpoint = "raw", "state.json"
+	gnosis, err := opts.ForChain("gnosis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gnosis.InputPath != "raw/gnosis" || gnosis.Checkpoint != "state-gnosis.json" || len(gnosis.Accounts) != 1 {
+		t.Errorf("ForChain: got input %q, checkpoint %q and %d accounts", gnosis.InputPath, gnosis.Checkpoint, len(gnosis.Accounts))
+	}
+	if gnosis.Names[active.Address].Tags != active.Tags {
+		t.Errorf("ForChain: the account is not named")
+	}
+
+	opts.Chain = "gnosis"
+	if got, want := opts.ChainFile("out/Book1.xlsx"), "out/Book1-gnosis.xlsx"; got != want {
+		t.Errorf("ChainFile: got %q, want %q", got, want)
+	}
+	if got, want := opts.ExplorerURL("address", "0x1"), "https://gnosisscan.io/address/0x1"; got != want {
+		t.Errorf("ExplorerURL: got %q, want %q", got, want)
+	}
+	opts.Chain = "unknown"
+	if got := opts.ExplorerURL("tx", "0xabc"); got != "" {
+		t.Errorf("ExplorerURL of a chain without an explorer: got %q", got)
+	}
+}
@@ -0,0 +1,224 @@
+package traverser
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+)
+
+// Checkpointer is implemented by traversers whose accumulated state can be carried from one
+// run to the next. State returns a pointer to that state, which is written as JSON at the
+// end of a run and read back into before the next, which then traverses only newer records.
+type Checkpointer interface {
+	State() any
+}
+
+// CheckpointVersion is the version of the checkpoint file format. Version 2 saves the
+// counts of the grouping traversers as lists of groups (see Grouping). Version 3 saves the
+// position of the last record traversed for each account, rather than its block. Version 4
+// saves when each group of the group counters was first and last seen, the net amount of
+// each asset counted by by_asset, and the balances of statements as a list of groups.
+const CheckpointVersion = 4
+
+// --------------------------------
+// Checkpoint is the state of every traverser of a run along with the position of the last
+// record traversed for each account.
+type Checkpoint struct {
+	Version     int                        `json:"version"`
+	Fingerprint string                     `json:"fingerprint"`
+	Positions   map[base.Address]Position  `json:"positions"`
+	States      map[string]json.RawMessage `json:"states"`
+}
+
+// --------------------------------
+// Position is where a record is in the chain: its block, its transaction and, for logs,
+// statements and traces, its log index or trace address within the transaction.
+type Position struct {
+	Block base.Blknum `json:"block"`
+	Tx    base.Txnum  `json:"tx"`
+	Sub   []uint64    `json:"sub,omitempty"`
+}
+
+func positionOf(r any) Position {
+	key := keyOf(r)
+	return Position{Block: key.block, Tx: key.tx, Sub: key.sub}
+}
+
+// Compare orders positions chronologically (see cmp.Compare).
+func (p Position) Compare(other Position) int {
+	return cmp.Or(
+		cmp.Compare(p.Block, other.Block),
+		cmp.Compare(p.Tx, other.Tx),
+		slices.Compare(p.Sub, other.Sub),
+	)
+}
+
+// --------------------------------
+// Progress tracks the position of the last record traversed for each account, and skips
+// the records of each account at or before the position an earlier run reached. Records
+// later in the same block, written after that run, are not skipped.
+type Progress struct {
+	since map[base.Address]Position
+	last  map[base.Address]Position
+}
+
+func NewProgress() *Progress {
+	return &Progress{last: map[base.Address]Position{}}
+}
+
+// Skip returns true if an earlier run already traversed the record at the position, and
+// otherwise records the position as reached for the account.
+func (p *Progress) Skip(account base.Address, pos Position) bool {
+	if p == nil {
+		return false
+	}
+	if since, ok := p.since[account]; ok && pos.Compare(since) <= 0 {
+		return true
+	}
+	if last, ok := p.last[account]; !ok || pos.Compare(last) > 0 {
+		p.last[account] = pos
+	}
+	return false
+}
+
+// Advance has the progress skip every record traversed so far, so that the next pass
+// over the accounts (see watch mode) traverses only the newer ones.
+func (p *Progress) Advance() {
+	if p == nil {
+		return
+	}
+	p.since = maps.Clone(p.last)
+}
+
+// Fingerprint identifies the traversers and the options that shape their state. A
+// checkpoint made with a different fingerprint is not used.
+func Fingerprint(regs []Registration, opts *Options) string {
+	names := make([]string, 0, len(regs))
+	for _, r := range regs {
+		names = append(names, r.Family+"."+r.Name)
+	}
+	slices.Sort(names)
+	tags := slices.Sorted(slices.Values(opts.Tags))
+	data, _ := json.Marshal([]any{names, opts.Chain, opts.Period, opts.Denom, opts.Verbose, tags, opts.Filters, opts.Field, opts.Of, opts.PerTx, opts.GroupBy})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadCheckpoint reads a checkpoint. A missing file is not an error, but returns nil.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if cp.Version != CheckpointVersion {
+		return nil, fmt.Errorf("%s: unsupported checkpoint version %d", path, cp.Version)
+	}
+	return cp, nil
+}
+
+// Resume restores the state of the traversers from the checkpoint and has the progress
+// skip the records it covers. It returns false, leaving everything untouched, if the
+// checkpoint is nil, was made with a different fingerprint or covers an account that is
+// no longer of interest. It returns an error if any of the traversers cannot be
+// checkpointed, checkpoint or not, so that a run asked to checkpoint fails from the start.
+func Resume[T Traversable](cp *Checkpoint, fingerprint string, opts *Options, regs []Registration, traversers []Traverser[T], progress *Progress) (bool, error) {
+	if err := checkpointable(regs, traversers); err != nil {
+		return false, err
+	}
+	if cp == nil {
+		return false, nil
+	}
+	reason := ""
+	if cp.Fingerprint != fingerprint {
+		reason = "the traversers or options have changed"
+	}
+	for addr := range cp.Positions {
+		if account, ok := opts.Accounts[addr]; reason == "" && (!ok || !opts.IsOfInterest(account.Tags)) {
+			reason = "account " + addr.Hex() + " is no longer processed"
+		}
+	}
+	if reason != "" {
+		log.Println(colors.Yellow+"Ignoring the checkpoint because", reason, colors.Off)
+		return false, nil
+	}
+
+	for i, t := range traversers {
+		state, ok := cp.States[regs[i].Name]
+		if !ok {
+			return false, fmt.Errorf("the checkpoint has no state for %s", regs[i].Name)
+		}
+		if err := json.Unmarshal(state, t.(Checkpointer).State()); err != nil {
+			return false, fmt.Errorf("restoring %s: %w", regs[i].Name, err)
+		}
+	}
+	progress.since = cp.Positions
+	for addr, pos := range cp.Positions {
+		progress.last[addr] = pos
+	}
+	log.Println(colors.Yellow+"Resuming from the checkpoint of", len(cp.Positions), "accounts", colors.Off)
+	return true, nil
+}
+
+// checkpointable returns an error naming the traversers that cannot be checkpointed, if any.
+func checkpointable[T Traversable](regs []Registration, traversers []Traverser[T]) error {
+	names := []string{}
+	for i, t := range traversers {
+		if _, ok := t.(Checkpointer); !ok {
+			names = append(names, regs[i].Name)
+		}
+	}
+	if len(names) > 0 {
+		return fmt.Errorf("%s cannot be checkpointed", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// SaveCheckpoint writes the state of the traversers and the progress made. It returns an
+// error if any of the traversers cannot be checkpointed. The file is replaced only once it
+// is completely written.
+func SaveCheckpoint[T Traversable](path, fingerprint string, regs []Registration, traversers []Traverser[T], progress *Progress) error {
+	if err := checkpointable(regs, traversers); err != nil {
+		return err
+	}
+
+	cp := Checkpoint{
+		Version:     CheckpointVersion,
+		Fingerprint: fingerprint,
+		Positions:   progress.last,
+		States:      make(map[string]json.RawMessage, len(traversers)),
+	}
+	for i, t := range traversers {
+		state, err := json.Marshal(t.(Checkpointer).State())
+		if err != nil {
+			return fmt.Errorf("saving %s: %w", regs[i].Name, err)
+		}
+		cp.States[regs[i].Name] = state
+	}
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return report.WriteFile(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
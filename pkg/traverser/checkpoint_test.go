@@ -0,0 +1,122 @@
+package traverser
+
+import (
+	"maps"
+	"path/filepath"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+)
+
+// tally counts the statements of each block and can be checkpointed.
+type tally struct {
+	Blocks map[base.Blknum]int
+}
+
+func (c *tally) Traverse(r *types.Statement) error {
+	if c.Blocks == nil {
+		c.Blocks = map[base.Blknum]int{}
+	}
+	c.Blocks[r.BlockNumber]++
+	return nil
+}
+func (c *tally) GetKey(r *types.Statement) string { return "" }
+func (c *tally) Result() (*report.Table, error)   { return report.NewTable("tally"), nil }
+func (c *tally) Name() string                     { return "tally" }
+func (c *tally) Order() Ordering                  { return Unsorted }
+func (c *tally) State() any                       { return &c.Blocks }
+
+func TestCheckpoint(t *testing.T) {
+	a1 := types.Name{Address: base.HexToAddress("0x1"), Tags: "00-Active"}
+	a2 := types.Name{Address: base.HexToAddress("0x2"), Tags: "00-Active"}
+	stmts := map[base.Address][]*types.Statement{}
+	add := func(account base.Address, blocks ...base.Blknum) {
+		for _, b := range blocks {
+			stmts[account] = append(stmts[account], &types.Statement{AccountedFor: account, BlockNumber: b})
+		}
+	}
+	fetch := func(account types.Name) ([]*types.Statement, error) {
+		return stmts[account.Address], nil
+	}
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	regs := []Registration{{Family: "test", Name: "tally"}}
+	run := func(opts Options) *tally {
+		t.Helper()
+		c := &tally{}
+		traversers := []Traverser[*types.Statement]{c}
+		opts.Progress = NewProgress()
+		fingerprint := Fingerprint(regs, &opts)
+		cp, err := LoadCheckpoint(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Resume(cp, fingerprint, &opts, regs, traversers, opts.Progress); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Run(Stream(&opts, "statements", fetch), traversers, nil, FailFast); err != nil {
+			t.Fatal(err)
+		}
+		if err := SaveCheckpoint(path, fingerprint, regs, traversers, opts.Progress); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	opts := Options{
+		Tags:     []string{"00-Active"},
+		Accounts: map[base.Address]types.Name{a1.Address: a1, a2.Address: a2},
+	}
+	add(a1.Address, 10, 20)
+	add(a2.Address, 15)
+	if got, want := run(opts).Blocks, map[base.Blknum]int{10: 1, 15: 1, 20: 1}; !maps.Equal(got, want) {
+		t.Errorf("first run: got %v, want %v", got, want)
+	}
+
+	// Only the newer statements are traversed, and merged with those already counted, even
+	// those later in the last block of the earlier run
+	add(a1.Address, 20)
+	stmts[a1.Address] = append(stmts[a1.Address], &types.Statement{AccountedFor: a1.Address, BlockNumber: 20, LogIndex: 1})
+	add(a1.Address, 30)
+	add(a2.Address, 15, 25)
+	if got, want := run(opts).Blocks, map[base.Blknum]int{10: 1, 15: 1, 20: 2, 25: 1, 30: 1}; !maps.Equal(got, want) {
+		t.Errorf("resumed run: got %v, want %v", got, want)
+	}
+
+	// A checkpoint made with other options is ignored
+	opts.Period = "monthly"
+	if got, want := run(opts).Blocks, map[base.Blknum]int{10: 1, 15: 2, 20: 3, 25: 1, 30: 1}; !maps.Equal(got, want) {
+		t.Errorf("full run: got %v, want %v", got, want)
+	}
+
+	// As is one covering an account no longer processed
+	a2.Tags = "19-Dead"
+	opts.Accounts[a2.Address] = a2
+	if got, want := run(opts).Blocks, map[base.Blknum]int{10: 1, 20: 3, 30: 1}; !maps.Equal(got, want) {
+		t.Errorf("run without a2: got %v, want %v", got, want)
+	}
+}
+
+func TestLoadCheckpointMissing(t *testing.T) {
+	cp, err := LoadCheckpoint(filepath.Join(t.TempDir(), "missing.json"))
+	if cp != nil || err != nil {
+		t.Errorf("LoadCheckpoint: got %v, %v, want nil, nil", cp, err)
+	}
+}
+
+func TestCheckpointRefused(t *testing.T) {
+	regs := []Registration{{Name: "tally"}, {Name: "grouped"}}
+	traversers := []Traverser[*types.Statement]{&tally{}, GroupBy("Block", func(r *types.Statement) string { return "" }, func() Traverser[*types.Statement] { return &tally{} })}
+
+	// A run that cannot be checkpointed fails before any record, even without a checkpoint
+	want := "grouped cannot be checkpointed"
+	if _, err := Resume(nil, "", &Options{}, regs, traversers, nil); err == nil || err.Error() != want {
+		t.Errorf("Resume: got %v, want %q", err, want)
+	}
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	if err := SaveCheckpoint(path, "", regs, traversers, nil); err == nil || err.Error() != want {
+		t.Errorf("SaveCheckpoint: got %v, want %q", err, want)
+	}
+}
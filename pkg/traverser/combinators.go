@@ -0,0 +1,310 @@
+package traverser
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+)
+
+// The combinators assemble reports from existing traversers instead of writing a new type
+// for each. For example, the functions called by each account in each year:
+//
+//	traverser.GroupBy("Account", func(r *types.Statement) string { return r.AccountedFor.Hex() },
+//		func() traverser.Traverser[*types.Statement] {
+//			return traverser.Window("annually", func() traverser.Traverser[*types.Statement] {
+//				return &accounting.CountByFunction{Opts: opts}
+//			})
+//		})
+//
+// Combinators take the ordering of the traversers they feed, and initialize them (see
+// Initializer) before they are fed. They cannot be checkpointed, so that a run of one
+// asked to checkpoint fails (see Resume).
+
+// --------------------------------
+// filtered feeds its child only the records that satisfy keep.
+type filtered[T Traversable] struct {
+	child Traverser[T]
+	keep  func(T) bool
+}
+
+// Filter feeds the child only the records that satisfy keep.
+func Filter[T Traversable](child Traverser[T], keep func(T) bool) Traverser[T] {
+	return &filtered[T]{child: child, keep: keep}
+}
+
+func (c *filtered[T]) Init() error {
+	return initialize(c.child)
+}
+
+func (c *filtered[T]) Traverse(r T) error {
+	if !c.keep(r) {
+		return nil
+	}
+	return c.child.Traverse(r)
+}
+
+func (c *filtered[T]) GetKey(r T) string {
+	return c.child.GetKey(r)
+}
+
+func (c *filtered[T]) Result() (*report.Table, error) {
+	return c.child.Result()
+}
+
+func (c *filtered[T]) Name() string {
+	return c.child.Name()
+}
+
+func (c *filtered[T]) Order() Ordering {
+	return c.child.Order()
+}
+
+// --------------------------------
+// mapper feeds its child each record turned into a record of another type.
+type mapper[T, U Traversable] struct {
+	child Traverser[U]
+	f     func(T) (U, error)
+}
+
+// Map feeds the child each record turned into another (possibly of another type) by f.
+// An error of f is the error of the record.
+func Map[T, U Traversable](child Traverser[U], f func(T) (U, error)) Traverser[T] {
+	return &mapper[T, U]{child: child, f: f}
+}
+
+func (c *mapper[T, U]) Init() error {
+	return initialize(c.child)
+}
+
+func (c *mapper[T, U]) Traverse(r T) error {
+	u, err := c.f(r)
+	if err != nil {
+		return err
+	}
+	return c.child.Traverse(u)
+}
+
+func (c *mapper[T, U]) GetKey(r T) string {
+	u, err := c.f(r)
+	if err != nil {
+		return ""
+	}
+	return c.child.GetKey(u)
+}
+
+func (c *mapper[T, U]) Result() (*report.Table, error) {
+	return c.child.Result()
+}
+
+func (c *mapper[T, U]) Name() string {
+	return c.child.Name()
+}
+
+func (c *mapper[T, U]) Order() Ordering {
+	return c.child.Order()
+}
+
+// --------------------------------
+// tee feeds every record to each of its children.
+type tee[T Traversable] struct {
+	children []Traverser[T]
+	order    Ordering
+}
+
+// Tee feeds every record to each child. Its result holds the sections of every child's
+// result in turn, named after the child's table, and their summary values. Children
+// that need records in different orders cannot be teed, and Tee returns an error if
+// asked to.
+func Tee[T Traversable](children ...Traverser[T]) (Traverser[T], error) {
+	ret := &tee[T]{children: children}
+	for _, child := range children {
+		if o := child.Order(); o != Unsorted {
+			if ret.order != Unsorted && ret.order != o {
+				return nil, fmt.Errorf("cannot tee traversers sorted %s and %s", ret.order, o)
+			}
+			ret.order = o
+		}
+	}
+	return ret, nil
+}
+
+func (c *tee[T]) Init() error {
+	errs := []error{}
+	for _, child := range c.children {
+		errs = append(errs, initialize(child))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *tee[T]) Traverse(r T) error {
+	errs := []error{}
+	for _, child := range c.children {
+		errs = append(errs, child.Traverse(r))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *tee[T]) GetKey(r T) string {
+	if len(c.children) == 0 {
+		return ""
+	}
+	return c.children[0].GetKey(r)
+}
+
+func (c *tee[T]) Result() (*report.Table, error) {
+	ret := report.NewTable("Tee")
+	for _, child := range c.children {
+		t, err := child.Result()
+		if err != nil {
+			return nil, err
+		}
+		ret.Summary = append(ret.Summary, t.Summary...)
+		for _, s := range t.Sections {
+			named := *s
+			named.Name = t.Name
+			if s.Name != "" {
+				named.Name += ": " + s.Name
+			}
+			ret.Sections = append(ret.Sections, &named)
+		}
+	}
+	return ret, nil
+}
+
+func (c *tee[T]) Name() string {
+	return colors.Green + "Tee" + colors.Off
+}
+
+func (c *tee[T]) Order() Ordering {
+	return c.order
+}
+
+// --------------------------------
+// groupBy feeds the records of each key to a traverser of its own. The spare is a child
+// made before any record arrived, to ask its order, and is given the first key.
+type groupBy[T Traversable] struct {
+	column   string
+	key      func(T) string
+	newChild func() Traverser[T]
+	spare    Traverser[T]
+	children map[string]Traverser[T]
+}
+
+// GroupBy feeds the records of each key to a child of its own, made by newChild when the
+// first record of the key arrives. Its result merges the children's results, in key
+// order, adding the key in the named column in front of every section (see report.Merge).
+func GroupBy[T Traversable](column string, key func(T) string, newChild func() Traverser[T]) Traverser[T] {
+	return &groupBy[T]{column: column, key: key, newChild: newChild}
+}
+
+// child returns a new child, or the spare if there is one.
+func (c *groupBy[T]) child() Traverser[T] {
+	if child := c.spare; child != nil {
+		c.spare = nil
+		return child
+	}
+	return c.newChild()
+}
+
+func (c *groupBy[T]) Traverse(r T) error {
+	if c.children == nil {
+		c.children = map[string]Traverser[T]{}
+	}
+	key := c.key(r)
+	child, ok := c.children[key]
+	if !ok {
+		child = c.child()
+		if err := initialize(child); err != nil {
+			return fmt.Errorf("%s %s: %w", c.column, key, err)
+		}
+		c.children[key] = child
+	}
+	return child.Traverse(r)
+}
+
+func (c *groupBy[T]) GetKey(r T) string {
+	return c.key(r)
+}
+
+func (c *groupBy[T]) Result() (*report.Table, error) {
+	keys := slices.Sorted(maps.Keys(c.children))
+	tables := make([]*report.Table, 0, len(keys))
+	for _, key := range keys {
+		t, err := c.children[key].Result()
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", c.column, key, err)
+		}
+		tables = append(tables, t)
+	}
+	if ret := report.Merge(c.column, keys, tables); ret != nil {
+		return ret, nil
+	}
+	// Without records, the result is that of a child that saw none, with the key column
+	child := c.child()
+	if err := initialize(child); err != nil {
+		return nil, err
+	}
+	empty, err := emptied(child)
+	if err != nil {
+		return nil, err
+	}
+	return report.Merge(c.column, []string{""}, []*report.Table{empty}), nil
+}
+
+func (c *groupBy[T]) Name() string {
+	return colors.Green + "GroupBy " + c.column + colors.Off
+}
+
+// Order is that of the children, asked of any child made so far or else of the spare.
+func (c *groupBy[T]) Order() Ordering {
+	for _, child := range c.children {
+		return child.Order()
+	}
+	if c.spare == nil {
+		c.spare = c.newChild()
+	}
+	return c.spare.Order()
+}
+
+// Untimed is the period under which Window puts the records that have no timestamp, such
+// as receipts.
+const Untimed = "untimed"
+
+// Window feeds the records of each period (see Options.Period) to a child of its own, as
+// GroupBy does with the period as the key, in a Period column. Records without a
+// timestamp are fed to a child of their own, under the Untimed period.
+func Window[T Traversable](period string, newChild func() Traverser[T]) Traverser[T] {
+	return GroupBy("Period", func(r T) string {
+		if ts, ok := timestampOf(r); ok {
+			return base.GetDateKey(period, base.NewDateTimeTs(ts))
+		}
+		return Untimed
+	}, newChild)
+}
+
+// initialize initializes a traverser if it needs it.
+func initialize(t any) error {
+	if init, ok := t.(Initializer); ok {
+		return init.Init()
+	}
+	return nil
+}
+
+// emptied returns the result of a traverser that saw no records, without its rows.
+func emptied[T Traversable](t Traverser[T]) (*report.Table, error) {
+	ret, err := t.Result()
+	if err != nil {
+		return nil, err
+	} else if ret == nil {
+		return report.NewTable(TypeName(t)), nil
+	}
+	for _, s := range ret.Sections {
+		s.Rows = nil
+	}
+	return ret, nil
+}
@@ -0,0 +1,178 @@
+package traverser
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+)
+
+// blockCount counts statements and sums their block numbers. Init fails with initErr,
+// if given.
+type blockCount struct {
+	order   Ordering
+	initErr error
+	inited  bool
+	count   int
+	sum     base.Blknum
+}
+
+func (c *blockCount) Init() error { c.inited = c.initErr == nil; return c.initErr }
+func (c *blockCount) Traverse(r *types.Statement) error {
+	if !c.inited {
+		return fmt.Errorf("fed before Init")
+	}
+	c.count++
+	c.sum += r.BlockNumber
+	return nil
+}
+func (c *blockCount) GetKey(r *types.Statement) string { return "" }
+func (c *blockCount) Name() string                     { return "blockCount" }
+func (c *blockCount) Order() Ordering                  { return c.order }
+func (c *blockCount) Result() (*report.Table, error) {
+	if !c.inited {
+		return nil, fmt.Errorf("result before Init")
+	}
+	t := report.NewTable("blockCount").AddSummary("Count", c.count)
+	t.AddSection("Blocks", "Count", "Sum").Append(c.count, c.sum)
+	return t, nil
+}
+
+func must(t Traverser[*types.Statement], err error) Traverser[*types.Statement] {
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// rows returns the rows of every section of a table, formatted.
+func rows(t *report.Table) []string {
+	ret := []string{}
+	for _, s := range t.Sections {
+		for _, row := range s.Rows {
+			ret = append(ret, s.Name+fmt.Sprint(row))
+		}
+	}
+	return ret
+}
+
+func TestCombinators(t *testing.T) {
+	a, b := base.HexToAddress("0xa"), base.HexToAddress("0xb")
+	at := func(year int) base.Timestamp {
+		return time.Date(year, time.June, 1, 0, 0, 0, 0, time.UTC).Unix()
+	}
+	records := []*types.Statement{
+		{AccountedFor: a, BlockNumber: 1, Timestamp: at(2022)},
+		{AccountedFor: b, BlockNumber: 2, Timestamp: at(2022)},
+		{AccountedFor: a, BlockNumber: 3, Timestamp: at(2023)},
+		{AccountedFor: a, BlockNumber: 4, Timestamp: at(2023)},
+	}
+	count := func() Traverser[*types.Statement] { return &blockCount{} }
+	byAccount := func(newChild func() Traverser[*types.Statement]) Traverser[*types.Statement] {
+		return GroupBy("Account", func(r *types.Statement) string { return r.AccountedFor.Hex() }, newChild)
+	}
+
+	tests := []struct {
+		name      string
+		traverser Traverser[*types.Statement]
+		want      []string
+	}{
+		{"filter", Filter(count(), func(r *types.Statement) bool { return r.BlockNumber%2 == 0 }),
+			[]string{"Blocks[2 6]"}},
+		{"map", Map(count(), func(r *types.Statement) (*types.Statement, error) {
+			return &types.Statement{BlockNumber: r.BlockNumber * 10}, nil
+		}), []string{"Blocks[4 100]"}},
+		{"tee", must(Tee(count(), Filter(count(), func(r *types.Statement) bool { return r.AccountedFor == b }))),
+			[]string{"blockCount: Blocks[4 10]", "blockCount: Blocks[1 2]"}},
+		{"group by", byAccount(count),
+			[]string{"Blocks[" + a.Hex() + " 3 8]", "Blocks[" + b.Hex() + " 1 2]"}},
+		{"window", Window("annually", count),
+			[]string{"Blocks[2022 2 3]", "Blocks[2023 2 7]"}},
+		{"nested", byAccount(func() Traverser[*types.Statement] { return Window("annually", count) }),
+			[]string{"Blocks[" + a.Hex() + " 2022 1 1]", "Blocks[" + a.Hex() + " 2023 2 7]", "Blocks[" + b.Hex() + " 2022 1 2]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := initialize(tt.traverser); err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range records {
+				if err := tt.traverser.Traverse(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			table, err := tt.traverser.Result()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rows(table); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got rows %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCombinatorsOrder(t *testing.T) {
+	made := 0
+	sorted := func() Traverser[*types.Statement] { made++; return &blockCount{order: ByAccount} }
+	window := Window("monthly", sorted)
+	if made != 0 {
+		t.Errorf("Window: made %d children before being asked its order", made)
+	}
+	if o := window.Order(); o != ByAccount {
+		t.Errorf("Window: got order %s, want %s", o, ByAccount)
+	}
+	// The child made for the order is the first fed
+	window.Traverse(&types.Statement{})
+	if made != 1 {
+		t.Errorf("Window: made %d children for one period, want 1", made)
+	}
+
+	if tee, err := Tee(&blockCount{}, sorted()); err != nil || tee.Order() != ByAccount {
+		t.Errorf("Tee: got %v, want order %s", err, ByAccount)
+	}
+	if _, err := Tee(sorted(), &blockCount{order: Chronological}); err == nil {
+		t.Error("Tee of traversers needing different orders did not fail")
+	}
+}
+
+func TestWindowUntimed(t *testing.T) {
+	window := Window("annually", func() Traverser[*types.Receipt] { return &Counter[*types.Receipt]{Family: "receipts"} })
+	for _, r := range []*types.Receipt{{BlockNumber: 1}, {BlockNumber: 2}} {
+		if err := window.Traverse(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	table, err := window.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(rows(table)), "[[untimed 2]]"; got != want {
+		t.Errorf("got rows %s, want %s", got, want)
+	}
+}
+
+func TestGroupByEmpty(t *testing.T) {
+	table, err := GroupBy("Account", func(r *types.Statement) string { return "" }, func() Traverser[*types.Statement] {
+		return &blockCount{}
+	}).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Sections) != 1 || len(table.Sections[0].Rows) != 0 || table.Sections[0].Columns[0].Name != "Account" {
+		t.Errorf("got %+v, want the child's sections without rows, with the key column", table.Sections)
+	}
+}
+
+func TestGroupByEmptyInitFails(t *testing.T) {
+	_, err := GroupBy("Account", func(r *types.Statement) string { return "" }, func() Traverser[*types.Statement] {
+		return &blockCount{initErr: fmt.Errorf("no database")}
+	}).Result()
+	if err == nil || err.Error() != "no database" {
+		t.Errorf("got %v, want the error of the child's Init", err)
+	}
+}
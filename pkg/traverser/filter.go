@@ -0,0 +1,213 @@
+package traverser
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/expr"
+)
+
+// --------------------------------
+// Filters select the records the traversers see. Empty sets and zero bounds do not filter.
+// For logs, the asset is the emitting contract, and an account or counterparty matches if
+// it emitted the log or appears in one of its topics. For transactions, traces and
+// receipts, the asset is the contract called, and an account or counterparty matches if
+// it is the sender or recipient. An appearance matches an account (but not a counterparty)
+// by its address. Where, if given, is an expression every record must satisfy.
+type Filters struct {
+	Assets         map[base.Address]bool
+	Accounts       map[base.Address]bool
+	Counterparties map[base.Address]bool
+	FirstTs        base.Timestamp
+	LastTs         base.Timestamp
+	FirstBlock     base.Blknum
+	LastBlock      base.Blknum
+	Where          *expr.Expr
+}
+
+// Check makes sure the where expression can be evaluated against records of the given type.
+func (f *Filters) Check(record reflect.Type) error {
+	if f.Where == nil {
+		return nil
+	}
+	if err := f.Where.Check(record); err != nil {
+		return fmt.Errorf("invalid expression %q: %w", f.Where, err)
+	}
+	return nil
+}
+
+// Unfiltered is implemented by traversers that want every record, including those the
+// filter stage drops.
+type Unfiltered interface {
+	Unfiltered() bool
+}
+
+func isUnfiltered(t any) bool {
+	u, ok := t.(Unfiltered)
+	return ok && u.Unfiltered()
+}
+
+// --------------------------------
+// filter is one test of the filter stage. It returns true to keep a record.
+type filter struct {
+	name string
+	keep func(r any) bool
+}
+
+// --------------------------------
+// FilterStage applies the filters to each record, counting the records each filter drops.
+// A record is counted against the first filter it fails.
+type FilterStage struct {
+	filters []filter
+	Seen    int
+	Dropped map[string]int
+}
+
+// NewFilterStage builds a stage holding only the filters that are in use.
+func NewFilterStage(f Filters) *FilterStage {
+	s := &FilterStage{Dropped: map[string]int{}}
+	if len(f.Assets) > 0 {
+		s.filters = append(s.filters, filter{"asset", func(r any) bool {
+			switch x := r.(type) {
+			case *types.Statement:
+				return f.Assets[x.Asset]
+			case *types.Log:
+				return f.Assets[x.Address]
+			}
+			_, to, ok := parties(r)
+			return !ok || f.Assets[to]
+		}})
+	}
+	if len(f.Accounts) > 0 {
+		s.filters = append(s.filters, filter{"account", func(r any) bool {
+			switch x := r.(type) {
+			case *types.Statement:
+				return f.Accounts[x.AccountedFor]
+			case *types.Log:
+				return logMentions(x, f.Accounts)
+			case *types.Appearance:
+				return f.Accounts[x.Address]
+			}
+			from, to, ok := parties(r)
+			return !ok || f.Accounts[from] || f.Accounts[to]
+		}})
+	}
+	if len(f.Counterparties) > 0 {
+		s.filters = append(s.filters, filter{"counterparty", func(r any) bool {
+			switch x := r.(type) {
+			case *types.Statement:
+				return (x.Sender != x.AccountedFor && f.Counterparties[x.Sender]) ||
+					(x.Recipient != x.AccountedFor && f.Counterparties[x.Recipient])
+			case *types.Log:
+				return logMentions(x, f.Counterparties)
+			}
+			from, to, ok := parties(r)
+			return !ok || f.Counterparties[from] || f.Counterparties[to]
+		}})
+	}
+	if f.FirstTs > 0 || f.LastTs > 0 {
+		s.filters = append(s.filters, filter{"date", func(r any) bool {
+			ts, ok := timestampOf(r)
+			return !ok || (ts >= f.FirstTs && (f.LastTs == 0 || ts <= f.LastTs))
+		}})
+	}
+	if f.FirstBlock > 0 || f.LastBlock > 0 {
+		s.filters = append(s.filters, filter{"block", func(r any) bool {
+			bn := keyOf(r).block
+			return bn >= f.FirstBlock && (f.LastBlock == 0 || bn <= f.LastBlock)
+		}})
+	}
+	if f.Where != nil {
+		s.filters = append(s.filters, filter{"where", f.Where.Match})
+	}
+	return s
+}
+
+// parties returns the sender and recipient of a transaction, trace or receipt. It returns
+// false for other records.
+func parties(r any) (from, to base.Address, ok bool) {
+	switch x := r.(type) {
+	case *types.Transaction:
+		return x.From, x.To, true
+	case *types.Trace:
+		if x.Action != nil {
+			return x.Action.From, x.Action.To, true
+		}
+	case *types.Receipt:
+		return x.From, x.To, true
+	}
+	return base.Address{}, base.Address{}, false
+}
+
+// timestampOf returns the time of a record, or false if the record carries none
+// (receipts).
+func timestampOf(r any) (base.Timestamp, bool) {
+	switch x := r.(type) {
+	case *types.Statement:
+		return x.Timestamp, true
+	case *types.Log:
+		return x.Timestamp, true
+	case *types.Transaction:
+		return x.Timestamp, true
+	case *types.Trace:
+		return x.Timestamp, true
+	case *types.Appearance:
+		return x.Timestamp, true
+	case Sample:
+		return x.Time, true
+	}
+	return 0, false
+}
+
+// logMentions returns true if one of the addresses emitted the log or appears in its topics.
+func logMentions(l *types.Log, addrs map[base.Address]bool) bool {
+	if addrs[l.Address] {
+		return true
+	}
+	for _, topic := range l.Topics {
+		hex := topic.Hex()
+		if strings.HasPrefix(hex, "0x000000000000000000000000") && addrs[base.HexToAddress("0x"+hex[26:])] {
+			return true
+		}
+	}
+	return false
+}
+
+// Keep returns true if the record passes every filter. A record is never dropped by a
+// filter that does not apply to its type (receipts carry no date, for example). A nil
+// stage keeps everything.
+func (s *FilterStage) Keep(r any) bool {
+	if s == nil {
+		return true
+	}
+	// Samples were filtered as the records they were taken from
+	if _, ok := r.(Sample); ok {
+		return true
+	}
+	s.Seen++
+	for _, f := range s.filters {
+		if !f.keep(r) {
+			s.Dropped[f.name]++
+			return false
+		}
+	}
+	return true
+}
+
+// Log reports how many records each filter dropped.
+func (s *FilterStage) Log() {
+	if s == nil || len(s.filters) == 0 {
+		return
+	}
+	kept := s.Seen
+	for _, f := range s.filters {
+		log.Println(colors.Yellow+"Filter", f.name, "dropped", s.Dropped[f.name], "records", colors.Off)
+		kept -= s.Dropped[f.name]
+	}
+	log.Println(colors.Yellow+"Kept", kept, "of", s.Seen, "records", colors.Off)
+}
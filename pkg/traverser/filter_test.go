@@ -0,0 +1,136 @@
+package traverser
+
+import (
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/expr"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+)
+
+var (
+	me    = base.HexToAddress("0xa")
+	other = base.HexToAddress("0xb")
+	usdc  = base.HexToAddress("0x1")
+	dai   = base.HexToAddress("0x2")
+)
+
+func TestFilterStage(t *testing.T) {
+	stmt := &types.Statement{AccountedFor: me, Sender: me, Recipient: other, Asset: usdc, BlockNumber: 100, Timestamp: 1000}
+	log := &types.Log{Address: usdc, BlockNumber: 100, Timestamp: 1000, Topics: []base.Hash{
+		base.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"),
+		base.HexToHash("0x000000000000000000000000000000000000000000000000000000000000000b"),
+	}}
+
+	tests := []struct {
+		name     string
+		filters  Filters
+		keepStmt bool
+		keepLog  bool
+	}{
+		{"None", Filters{}, true, true},
+		{"Asset", Filters{Assets: map[base.Address]bool{usdc: true}}, true, true},
+		{"OtherAsset", Filters{Assets: map[base.Address]bool{dai: true}}, false, false},
+		{"Account", Filters{Accounts: map[base.Address]bool{me: true}}, true, false},
+		{"Counterparty", Filters{Counterparties: map[base.Address]bool{other: true}}, true, true},
+		{"SelfIsNotCounterparty", Filters{Counterparties: map[base.Address]bool{me: true}}, false, false},
+		{"DateInside", Filters{FirstTs: 1000, LastTs: 1000}, true, true},
+		{"DateAfter", Filters{FirstTs: 1001}, false, false},
+		{"BlockBefore", Filters{LastBlock: 99}, false, false},
+		{"BlockInside", Filters{FirstBlock: 100}, true, true},
+		{"Where", Filters{Where: where(t, `blockNumber == 100 && date < "1970-01-02"`)}, true, true},
+		{"WhereStatementField", Filters{Where: where(t, `sender == 0x000000000000000000000000000000000000000a`)}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stage := NewFilterStage(tt.filters)
+			if got := stage.Keep(stmt); got != tt.keepStmt {
+				t.Errorf("Keep(statement): got %v, want %v", got, tt.keepStmt)
+			}
+			if got := stage.Keep(log); got != tt.keepLog {
+				t.Errorf("Keep(log): got %v, want %v", got, tt.keepLog)
+			}
+		})
+	}
+}
+
+func where(t *testing.T, src string) *expr.Expr {
+	e, err := expr.Compile(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// unfiltered sees every statement, counting them.
+type unfiltered struct {
+	count int
+}
+
+func (u *unfiltered) Traverse(r *types.Statement) error { u.count++; return nil }
+func (u *unfiltered) GetKey(r *types.Statement) string  { return "" }
+func (u *unfiltered) Result() (*report.Table, error)    { return report.NewTable("unfiltered"), nil }
+func (u *unfiltered) Name() string                      { return "unfiltered" }
+func (u *unfiltered) Order() Ordering                   { return Unsorted }
+func (u *unfiltered) Unfiltered() bool                  { return true }
+
+type counting struct {
+	unfiltered
+}
+
+func (c *counting) Unfiltered() bool { return false }
+
+func TestRunFiltered(t *testing.T) {
+	records := func(yield func(*types.Statement, error) bool) {
+		for _, asset := range []base.Address{usdc, dai, usdc} {
+			if !yield(&types.Statement{Asset: asset}, nil) {
+				return
+			}
+		}
+	}
+
+	stage := NewFilterStage(Filters{Assets: map[base.Address]bool{usdc: true}})
+	all, some := &unfiltered{}, &counting{}
+	if _, err := Run(records, []Traverser[*types.Statement]{all, some}, stage, FailFast); err != nil {
+		t.Fatal(err)
+	}
+	if all.count != 3 || some.count != 2 {
+		t.Errorf("Counts differ: got %d and %d, want 3 and 2", all.count, some.count)
+	}
+	if stage.Seen != 3 || stage.Dropped["asset"] != 1 {
+		t.Errorf("Stage counts differ: seen %d, dropped %v", stage.Seen, stage.Dropped)
+	}
+}
+
+func TestFilterStageOtherRecords(t *testing.T) {
+	tx := &types.Transaction{From: me, To: usdc, BlockNumber: 100, Timestamp: 1000}
+	trace := &types.Trace{Action: &types.TraceAction{From: usdc, To: other}, BlockNumber: 100, Timestamp: 1000}
+	receipt := &types.Receipt{From: me, To: usdc, BlockNumber: 100}
+	app := &types.Appearance{Address: me, BlockNumber: 100, Timestamp: 1000}
+
+	tests := []struct {
+		name    string
+		filters Filters
+		want    []bool // transaction, trace, receipt, appearance
+	}{
+		{"Asset", Filters{Assets: map[base.Address]bool{usdc: true}}, []bool{true, false, true, true}},
+		{"Account", Filters{Accounts: map[base.Address]bool{me: true}}, []bool{true, false, true, true}},
+		{"Counterparty", Filters{Counterparties: map[base.Address]bool{other: true}}, []bool{false, true, false, true}},
+		{"Date", Filters{FirstTs: 1001}, []bool{false, false, true, false}},
+		{"Block", Filters{LastBlock: 99}, []bool{false, false, false, false}},
+		{"Where", Filters{Where: where(t, `to == 0x000000000000000000000000000000000000000b`)}, []bool{false, true, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stage := NewFilterStage(tt.filters)
+			for i, r := range []any{tx, trace, receipt, app} {
+				if got := stage.Keep(r); got != tt.want[i] {
+					t.Errorf("Keep(%T): got %v, want %v", r, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
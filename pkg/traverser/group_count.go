@@ -0,0 +1,152 @@
+package traverser
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+)
+
+// --------------------------------
+// Counter counts the records of a family. It is the counter of the families whose records
+// are only counted (see RegisterCounter).
+type Counter[T Traversable] struct {
+	Family string
+	Value  uint64
+}
+
+// RegisterCounter registers the counter of a family as counter (or <family>.counter), in
+// the counters group. What names the records counted, as in "traces".
+func RegisterCounter[T Traversable](family, what string) {
+	Register(Registration{
+		Name:        "counter",
+		Aliases:     []string{family + ".counter"},
+		Groups:      []string{"counters"},
+		Family:      family,
+		Description: "Counts the " + what,
+	}, func(opts Options) Traverser[T] {
+		return &Counter[T]{Family: family}
+	})
+}
+
+func (c *Counter[T]) Traverse(r T) error {
+	c.Value += 1
+	return nil
+}
+
+func (c *Counter[T]) GetKey(r T) string {
+	return ""
+}
+
+func (c *Counter[T]) Result() (*report.Table, error) {
+	t := report.NewTable(c.Family + ".Counter")
+	t.AddSection("", "Counter").Append(c.Value)
+	return t, nil
+}
+
+func (c *Counter[T]) Name() string {
+	return colors.Green + c.Family + ".Counter" + colors.Off
+}
+
+func (c *Counter[T]) Order() Ordering {
+	return Unsorted
+}
+
+func (c *Counter[T]) State() any {
+	return &c.Value
+}
+
+// --------------------------------
+// CountBy is one way of counting the records of a family, registered by
+// RegisterGroupCounters. Key picks the fields of a record counted on, and Row reports
+// them, one value for each column. The families key every way of counting with one
+// struct, filling only the fields each uses.
+type CountBy[T Traversable, K comparable] struct {
+	Name        string
+	Description string
+	Columns     []string
+	Key         func(opts *Options, r T) K
+	Row         func(k K) []any
+}
+
+// GroupCounter counts the records of a family as one of its CountBy does. It is the
+// group-by traverser of the families whose records are only counted.
+type GroupCounter[T Traversable, K comparable] struct {
+	Opts   Options
+	Family string
+	What   string
+	By     CountBy[T, K]
+	Counts *Grouping[T, K, *Tally[T]]
+}
+
+// RegisterGroupCounters registers a group counter for each way of counting the records
+// of a family, in the group_by group. What names the records counted, as in "Traces".
+func RegisterGroupCounters[T Traversable, K comparable](family, what string, bys ...CountBy[T, K]) {
+	for _, by := range bys {
+		Register(Registration{
+			Name:        by.Name,
+			Groups:      []string{"group_by"},
+			Family:      family,
+			Description: by.Description,
+		}, func(opts Options) Traverser[T] {
+			c := &GroupCounter[T, K]{Opts: opts, Family: family, What: what, By: by}
+			c.Counts = NewGrouping(func(r T) K { return by.Key(&c.Opts, r) }, NewTally[T])
+			return c
+		})
+	}
+}
+
+func (c *GroupCounter[T, K]) Traverse(r T) error {
+	c.Counts.Add(r)
+	return nil
+}
+
+func (c *GroupCounter[T, K]) GetKey(r T) string {
+	return c.text(c.Counts.Key(r), "_")
+}
+
+// Result reports the groups by descending count, then by the text of their rows, with the
+// first and last blocks each was seen at.
+func (c *GroupCounter[T, K]) Result() (*report.Table, error) {
+	groups := c.Counts.Sorted(func(a, b Group[K, *Tally[T]]) int {
+		return cmp.Or(cmp.Compare(b.Value.N, a.Value.N), cmp.Compare(c.text(a.Key, "\x00"), c.text(b.Key, "\x00")))
+	})
+	total := uint64(0)
+	for _, g := range groups {
+		total += g.Value.N
+	}
+
+	t := report.NewTable(c.Family + ".GroupBy." + c.By.Name)
+	t.AddSummary("Number of "+c.What, total)
+	t.AddSummary("Number of Groups", len(groups))
+	section := t.AddSection("", slices.Concat([]string{"Count"}, c.By.Columns, []string{"First Block", "Last Block"})...)
+	for _, g := range groups {
+		section.Append(slices.Concat([]any{g.Value.N}, c.By.Row(g.Key), []any{g.Value.FirstBlock, g.Value.LastBlock})...)
+	}
+	return t, nil
+}
+
+func (c *GroupCounter[T, K]) Name() string {
+	return colors.Green + c.Family + ".GroupBy" + colors.Off
+}
+
+func (c *GroupCounter[T, K]) Order() Ordering {
+	return Unsorted
+}
+
+// State is the count of each group and when it was seen, so that a checkpointed run counts
+// on from them.
+func (c *GroupCounter[T, K]) State() any {
+	return c.Counts
+}
+
+// text joins the formatted values of the row of a key with sep.
+func (c *GroupCounter[T, K]) text(k K, sep string) string {
+	parts := []string{}
+	for _, v := range c.By.Row(k) {
+		parts = append(parts, report.Format(v))
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,188 @@
+package traverser
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+)
+
+// --------------------------------
+// Grouping folds the records of each key into an aggregate of its own. Keys are any
+// comparable value, usually a small struct of the fields grouped on, so that nothing is
+// joined into a string only to be split apart again when reporting. A Grouping is its
+// own checkpoint state (see Checkpointer): it is saved as JSON as a list of its groups.
+type Grouping[T any, K comparable, A Aggregator[T]] struct {
+	key    func(T) K
+	new    func() A
+	groups map[K]A
+}
+
+// Group is a key of a Grouping and the aggregate of its records.
+type Group[K comparable, A any] struct {
+	Key   K `json:"key"`
+	Value A `json:"value"`
+}
+
+// NewGrouping returns a grouping keying each record with key and folding the records of
+// each key into an aggregate made by new.
+func NewGrouping[T any, K comparable, A Aggregator[T]](key func(T) K, new func() A) *Grouping[T, K, A] {
+	return &Grouping[T, K, A]{key: key, new: new, groups: map[K]A{}}
+}
+
+// Add folds the record into the aggregate of its key.
+func (g *Grouping[T, K, A]) Add(r T) {
+	k := g.key(r)
+	a, ok := g.groups[k]
+	if !ok {
+		a = g.new()
+		g.groups[k] = a
+	}
+	a.Add(r)
+}
+
+// Key returns the key of the record.
+func (g *Grouping[T, K, A]) Key(r T) K {
+	return g.key(r)
+}
+
+// Len returns the number of distinct keys.
+func (g *Grouping[T, K, A]) Len() int {
+	return len(g.groups)
+}
+
+// Get returns the aggregate of a key, if any record had it.
+func (g *Grouping[T, K, A]) Get(k K) (A, bool) {
+	a, ok := g.groups[k]
+	return a, ok
+}
+
+// Sorted returns the groups in the order of cmp (see slices.SortFunc).
+func (g *Grouping[T, K, A]) Sorted(cmp func(a, b Group[K, A]) int) []Group[K, A] {
+	ret := make([]Group[K, A], 0, len(g.groups))
+	for k, a := range g.groups {
+		ret = append(ret, Group[K, A]{Key: k, Value: a})
+	}
+	slices.SortFunc(ret, cmp)
+	return ret
+}
+
+// MarshalJSON saves the groups in the order of their keys, so that the same groups are
+// always saved the same way.
+func (g *Grouping[T, K, A]) MarshalJSON() ([]byte, error) {
+	ret := make([]json.RawMessage, 0, len(g.groups))
+	for k, a := range g.groups {
+		data, err := json.Marshal(Group[K, A]{Key: k, Value: a})
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, data)
+	}
+	slices.SortFunc(ret, func(a, b json.RawMessage) int {
+		return bytes.Compare(a, b)
+	})
+	return json.Marshal(ret)
+}
+
+// UnmarshalJSON replaces the groups with those saved. Each aggregate is made by the
+// grouping's new before being read into, so that what is not saved is set.
+func (g *Grouping[T, K, A]) UnmarshalJSON(data []byte) error {
+	saved := []Group[K, json.RawMessage]{}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	g.groups = make(map[K]A, len(saved))
+	for _, s := range saved {
+		a := g.new()
+		if err := json.Unmarshal(s.Value, a); err != nil {
+			return err
+		}
+		g.groups[s.Key] = a
+	}
+	return nil
+}
+
+// Aggregator folds the records of a group into a value. Aggregators are pointers read
+// back from JSON when a checkpoint is resumed, so what they accumulate is exported.
+// Several are combined by a struct of them whose Add calls each in turn.
+type Aggregator[T any] interface {
+	Add(r T)
+}
+
+// --------------------------------
+// Count counts the records of a group.
+type Count[T any] struct {
+	N uint64 `json:"n"`
+}
+
+func NewCount[T any]() *Count[T] {
+	return &Count[T]{}
+}
+
+func (a *Count[T]) Add(r T) {
+	a.N++
+}
+
+// --------------------------------
+// Sum adds up an amount of each record of a group, in wei (or the smallest unit of the
+// token), so that nothing overflows or is rounded.
+type Sum[T any] struct {
+	Of    func(T) *base.Wei `json:"-"`
+	Total base.Wei          `json:"total"`
+}
+
+// NewSum returns a sum of the amounts given by of.
+func NewSum[T any](of func(T) *base.Wei) *Sum[T] {
+	return &Sum[T]{Of: of}
+}
+
+func (a *Sum[T]) Add(r T) {
+	if v := a.Of(r); v != nil {
+		a.Total.Add(&a.Total, v)
+	}
+}
+
+// --------------------------------
+// Seen records the first and last blocks at which a group had records, and their times,
+// whatever the order the records come in. The times of records without one (receipts)
+// are left at zero.
+type Seen[T any] struct {
+	FirstBlock base.Blknum    `json:"firstBlock"`
+	LastBlock  base.Blknum    `json:"lastBlock"`
+	First      base.Timestamp `json:"first"`
+	Last       base.Timestamp `json:"last"`
+	Any        bool           `json:"any"`
+}
+
+func NewSeen[T any]() *Seen[T] {
+	return &Seen[T]{}
+}
+
+func (a *Seen[T]) Add(r T) {
+	block := keyOf(r).block
+	ts, _ := timestampOf(r)
+	if !a.Any || block < a.FirstBlock {
+		a.FirstBlock, a.First = block, ts
+	}
+	if !a.Any || block > a.LastBlock {
+		a.LastBlock, a.Last = block, ts
+	}
+	a.Any = true
+}
+
+// --------------------------------
+// Tally counts the records of a group and records when it was first and last seen.
+type Tally[T any] struct {
+	Count[T]
+	Seen[T]
+}
+
+func NewTally[T any]() *Tally[T] {
+	return &Tally[T]{}
+}
+
+func (a *Tally[T]) Add(r T) {
+	a.Count.Add(r)
+	a.Seen.Add(r)
+}
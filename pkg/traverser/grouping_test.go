@@ -0,0 +1,141 @@
+package traverser
+
+import (
+	"cmp"
+	"encoding/json"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/synth"
+)
+
+type symbolKey struct {
+	Asset  base.Address `json:"asset"`
+	Symbol string       `json:"symbol"`
+}
+
+func TestGrouping(t *testing.T) {
+	statements := synth.Generate(synth.Config{Seed: 1, Accounts: []base.Address{account.Address}}).Statements[account.Address]
+	half := len(statements) / 2
+
+	tests := []struct {
+		name string
+		key  func(r *types.Statement) symbolKey
+	}{
+		{"BySymbol", func(r *types.Statement) symbolKey { return symbolKey{Asset: r.Asset, Symbol: r.Symbol} }},
+		{"ByAsset", func(r *types.Statement) symbolKey { return symbolKey{Asset: r.Asset} }},
+		{"All", func(r *types.Statement) symbolKey { return symbolKey{} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := map[symbolKey]uint64{}
+			all := NewGrouping(tt.key, NewCount[*types.Statement])
+			for _, r := range statements {
+				want[tt.key(r)]++
+				all.Add(r)
+			}
+			if all.Len() != len(want) {
+				t.Errorf("got %d groups, want %d", all.Len(), len(want))
+			}
+			prev := uint64(len(statements))
+			for _, g := range all.Sorted(func(a, b Group[symbolKey, *Count[*types.Statement]]) int {
+				return cmp.Compare(b.Value.N, a.Value.N)
+			}) {
+				if g.Value.N != want[g.Key] || g.Value.N > prev {
+					t.Errorf("%+v: got %d, want %d after %d", g.Key, g.Value.N, want[g.Key], prev)
+				}
+				prev = g.Value.N
+			}
+
+			// A grouping saved half way and restored counts on to the same groups
+			first := NewGrouping(tt.key, NewCount[*types.Statement])
+			for _, r := range statements[:half] {
+				first.Add(r)
+			}
+			data, err := json.Marshal(first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			restored := NewGrouping(tt.key, NewCount[*types.Statement])
+			if err := json.Unmarshal(data, restored); err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range statements[half:] {
+				restored.Add(r)
+			}
+			got, _ := json.Marshal(restored)
+			expected, _ := json.Marshal(all)
+			if string(got) != string(expected) {
+				t.Errorf("restored differs:\n got %s\nwant %s", got, expected)
+			}
+		})
+	}
+}
+
+// netSeen adds up the net amounts of a group and records when it was first and last seen.
+type netSeen struct {
+	Net  *Sum[*types.Statement]  `json:"net"`
+	Seen *Seen[*types.Statement] `json:"seen"`
+}
+
+func newNetSeen() *netSeen {
+	return &netSeen{Net: NewSum((*types.Statement).AmountNet), Seen: NewSeen[*types.Statement]()}
+}
+
+func (a *netSeen) Add(r *types.Statement) {
+	a.Net.Add(r)
+	a.Seen.Add(r)
+}
+
+func TestSumAndSeen(t *testing.T) {
+	statements := synth.Generate(synth.Config{Seed: 1, Accounts: []base.Address{account.Address}}).Statements[account.Address]
+	half := len(statements) / 2
+	key := func(r *types.Statement) base.Address { return r.Asset }
+
+	// The records come in reverse, so the first seen is the last added
+	want := map[base.Address]*netSeen{}
+	all := NewGrouping(key, newNetSeen)
+	for i := len(statements) - 1; i >= 0; i-- {
+		r := statements[i]
+		if want[r.Asset] == nil {
+			want[r.Asset] = &netSeen{Net: &Sum[*types.Statement]{}, Seen: &Seen[*types.Statement]{LastBlock: r.BlockNumber, Last: r.Timestamp, Any: true}}
+		}
+		w := want[r.Asset]
+		w.Net.Total.Add(&w.Net.Total, r.AmountNet())
+		w.Seen.FirstBlock, w.Seen.First = r.BlockNumber, r.Timestamp
+		all.Add(r)
+	}
+	for asset, w := range want {
+		got, _ := all.Get(asset)
+		if got.Net.Total.Cmp(&w.Net.Total) != 0 {
+			t.Errorf("%s: got a net of %s, want %s", asset.Hex(), got.Net.Total.String(), w.Net.Total.String())
+		}
+		if *got.Seen != *w.Seen {
+			t.Errorf("%s: got seen %+v, want %+v", asset.Hex(), *got.Seen, *w.Seen)
+		}
+	}
+
+	// A grouping saved half way and restored sums on, and keeps the amounts it sums
+	first := NewGrouping(key, newNetSeen)
+	for _, r := range statements[:half] {
+		first.Add(r)
+	}
+	data, err := json.Marshal(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := NewGrouping(key, newNetSeen)
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range statements[half:] {
+		restored.Add(r)
+	}
+	got, _ := json.Marshal(restored)
+	expected, _ := json.Marshal(all)
+	if string(got) != string(expected) {
+		t.Errorf("restored differs:\n got %s\nwant %s", got, expected)
+	}
+}
@@ -1,14 +1,15 @@
 package logs
 
 import (
+	"cmp"
 	"fmt"
 	"reflect"
-	"sort"
 	"strings"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
@@ -16,17 +17,56 @@ import (
 type CountByContract struct {
 	Opts   traverser.Options
 	Mode   string
-	Values map[string]uint64
+	Values *traverser.Grouping[*types.Log, contractKey, *traverser.Count[*types.Log]]
 }
 
-func (c *CountByContract) Traverse(r *types.Log) {
-	if len(c.Values) == 0 {
-		c.Values = make(map[string]uint64)
+// contractKey holds the contract, the topic, or both, as the mode of the counts has it.
+type contractKey struct {
+	Contract base.Address `json:"contract"`
+	Name     string       `json:"name"`
+	Topic    base.Topic   `json:"topic"`
+	Function string       `json:"function"`
+}
+
+func init() {
+	traverser.Register(traverser.Registration{
+		Name:        "contract_first",
+		Family:      Family,
+		Description: "Counts the logs for each contract and topic, sorted by contract",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Log] {
+		return &CountByContract{Opts: opts, Mode: "contract_first"}
+	})
+	traverser.Register(traverser.Registration{
+		Name:        "contract_last",
+		Family:      Family,
+		Description: "Counts the logs for each topic and contract, sorted by topic",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Log] {
+		return &CountByContract{Opts: opts, Mode: "contract_last"}
+	})
+	traverser.Register(traverser.Registration{
+		Name:        "contract_only",
+		Family:      Family,
+		Description: "Counts the logs for each contract",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Log] {
+		return &CountByContract{Opts: opts, Mode: "contract_only"}
+	})
+	traverser.Register(traverser.Registration{
+		Name:        "topic_only",
+		Family:      Family,
+		Description: "Counts the logs for each topic",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Log] {
+		return &CountByContract{Opts: opts, Mode: "topic_only"}
+	})
+}
+
+func (c *CountByContract) values() *traverser.Grouping[*types.Log, contractKey, *traverser.Count[*types.Log]] {
+	if c.Values == nil {
+		c.Values = traverser.NewGrouping(c.key, traverser.NewCount[*types.Log])
 	}
-	c.Values[c.GetKey(r)]++
+	return c.Values
 }
 
-func (c *CountByContract) GetKey(r *types.Log) string {
+func (c *CountByContract) key(r *types.Log) contractKey {
 	name := c.Opts.Names[r.Address].Name
 	if name == "" {
 		name = "Unknown"
@@ -42,105 +82,90 @@ func (c *CountByContract) GetKey(r *types.Log) string {
 	}
 	switch c.Mode {
 	case "topic_only":
-		return topic0 + "_" + funcName
+		return contractKey{Topic: topic0, Function: funcName}
 	case "contract_only":
-		return r.Address.Hex() + "_" + name
-	case "contract_last":
-		return topic0 + "_" + funcName + "_" + r.Address.Hex() + "_" + name
-	case "contract_first":
-		fallthrough
+		return contractKey{Contract: r.Address, Name: name}
 	default:
-		return r.Address.Hex() + "_" + name + "_" + topic0 + "_" + funcName
+		return contractKey{Contract: r.Address, Name: name, Topic: topic0, Function: funcName}
 	}
 }
 
-func (c *CountByContract) Result() string {
-	return c.Name() + "\n" + c.reportValues("TopicsPerContract", c.Values)
+func (c *CountByContract) Traverse(r *types.Log) error {
+	c.values().Add(r)
+	return nil
+}
+
+func (c *CountByContract) GetKey(r *types.Log) string {
+	return fmt.Sprint(c.key(r))
+}
+
+func (c *CountByContract) Result() (*report.Table, error) {
+	return c.reportValues("TopicsPerContract"), nil
 }
 
 func (c *CountByContract) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *CountByContract) Sort(array []*types.Log) {
-	// Nothing to do
+func (c *CountByContract) Order() traverser.Ordering {
+	return traverser.Unsorted
 }
 
-func (c *CountByContract) reportValues(msg string, m map[string]uint64) string {
-	type stats struct {
-		Contract base.Address
-		Name     string
-		Topic    base.Topic
-		Function string
-		Count    int64
-		Sort     string
-	}
-	nRecords := uint64(0)
+func (c *CountByContract) State() any {
+	return c.values()
+}
 
-	sortStr := func(a, b, c, d string) string {
-		return a + "_" + b + "_" + c + "_" + d
+func (c *CountByContract) reportValues(msg string) *report.Table {
+	byContract := func(a, b contractKey) int {
+		return cmp.Or(cmp.Compare(a.Contract.Hex(), b.Contract.Hex()), cmp.Compare(a.Name, b.Name))
 	}
-
-	arr := make([]stats, 0, len(m))
-	for k, v := range m {
-		nRecords += v
-		parts := strings.Split(k, "_")
-		var val stats
-		switch c.Mode {
-		case "topic_only":
-			val = stats{Count: int64(v), Topic: parts[0], Function: parts[1]}
-			val.Sort = sortStr(val.Topic, val.Function, "", "")
-		case "contract_only":
-			val = stats{Count: int64(v), Contract: base.HexToAddress(parts[0]), Name: parts[1]}
-			val.Sort = sortStr(val.Contract.Hex(), val.Name, "", "")
-		case "contract_last":
-			val = stats{Count: int64(v), Topic: parts[0], Function: parts[1], Contract: base.HexToAddress(parts[2]), Name: parts[3]}
-			val.Sort = sortStr(val.Topic, val.Function, val.Contract.Hex(), val.Name)
-		case "contract_first":
-			fallthrough
-		default:
-			val = stats{Count: int64(v), Contract: base.HexToAddress(parts[0]), Name: parts[1], Topic: parts[2], Function: parts[3]}
-			val.Sort = sortStr(val.Contract.Hex(), val.Name, val.Topic, val.Function)
-		}
-		arr = append(arr, val)
+	byTopic := func(a, b contractKey) int {
+		return cmp.Or(cmp.Compare(a.Topic, b.Topic), cmp.Compare(a.Function, b.Function))
 	}
-
-	sort.Slice(arr, func(i, j int) bool {
-		if arr[i].Count == arr[j].Count {
-			return arr[i].Sort < arr[j].Sort
+	groups := c.values().Sorted(func(a, b traverser.Group[contractKey, *traverser.Count[*types.Log]]) int {
+		if c.Mode == "contract_last" || c.Mode == "topic_only" {
+			return cmp.Or(cmp.Compare(b.Value.N, a.Value.N), byTopic(a.Key, b.Key), byContract(a.Key, b.Key))
 		}
-		return arr[i].Count > arr[j].Count
+		return cmp.Or(cmp.Compare(b.Value.N, a.Value.N), byContract(a.Key, b.Key), byTopic(a.Key, b.Key))
 	})
+	nRecords := uint64(0)
+	for _, g := range groups {
+		nRecords += g.Value.N
+	}
+
+	t := report.NewTable(traverser.TypeName(c))
+	t.AddSummary("Number of "+msg, len(groups))
+	t.AddSummary("Number of Topics", nRecords)
 
-	ret := fmt.Sprintf("Number of %s: %d\n", msg, len(c.Values))
-	ret += fmt.Sprintf("Number of Topics: %d\n", nRecords)
+	var section *report.Section
+	switch c.Mode {
+	case "topic_only":
+		section = t.AddSection("", "Count", "Topic", "FuncName")
+	case "contract_only":
+		section = t.AddSection("", "Count", "Contract", "Name")
+	case "contract_last":
+		section = t.AddSection("", "Count", "Topic", "FuncName", "Contract", "Name")
+	case "contract_first":
+		fallthrough
+	default:
+		section = t.AddSection("", "Count", "Contract", "Name", "Topic", "FuncName")
+	}
 
-	for i, val := range arr {
+	for _, g := range groups {
+		n, k := int64(g.Value.N), g.Key
 		switch c.Mode {
 		case "topic_only":
-			if i == 0 {
-				ret += "Count,Topic,FuncName\n"
-			}
-			ret += fmt.Sprintf("%d,%s,%s\n", val.Count, val.Topic, val.Function)
+			section.Append(n, k.Topic, k.Function)
 		case "contract_only":
-			if i == 0 {
-				ret += "Count,Contract,Name\n"
-			}
-			ret += fmt.Sprintf("%d,%s,%s\n", val.Count, val.Contract, val.Name)
+			section.Append(n, k.Contract, k.Name)
 		case "contract_last":
-			if i == 0 {
-				ret += "Count,Topic,FuncName,Contract,Name\n"
-			}
-			ret += fmt.Sprintf("%d,%s,%s,%s,%s\n", val.Count, val.Topic, val.Function, val.Contract, val.Name)
+			section.Append(n, k.Topic, k.Function, k.Contract, k.Name)
 		case "contract_first":
 			fallthrough
 		default:
-			if i == 0 {
-				ret += "Count,Contract,Name,Topic,FuncName\n"
-			}
-			ret += fmt.Sprintf("%d,%s,%s,%s,%s\n", val.Count, val.Contract, val.Name, val.Topic, val.Function)
+			section.Append(n, k.Contract, k.Name, k.Topic, k.Function)
 		}
 	}
 
-	return ret
+	return t
 }
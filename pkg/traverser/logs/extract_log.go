@@ -6,6 +6,7 @@ import (
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
@@ -13,43 +14,49 @@ import (
 type ExtractLog struct {
 	Opts  traverser.Options
 	Count uint64
-	// w     *tabwriter.Writer
+	Logs  []*types.Log
 }
 
-func (c *ExtractLog) Traverse(l *types.Log) {
-	if c.Count == 0 {
-		// c.w = tabwriter.NewWriter(os.Stdout, 0, 0, 1, ',', 0)
-		c.ReportHeader(c.Opts.Verbose, l)
-	}
-	c.ReportRecord(l)
+func init() {
+	traverser.Register(traverser.Registration{
+		Name:        "extract",
+		Family:      Family,
+		Description: "Reports every log in compressed form",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Log] {
+		return &ExtractLog{Opts: opts}
+	})
+}
+
+func (c *ExtractLog) Traverse(l *types.Log) error {
+	c.Logs = append(c.Logs, l)
 	c.Count++
+	return nil
 }
 
 func (c *ExtractLog) GetKey(r *types.Log) string {
 	return ""
 }
 
-func (c *ExtractLog) Result() string {
-	return ""
+func (c *ExtractLog) Result() (*report.Table, error) {
+	t := report.NewTable(traverser.TypeName(c))
+	section := t.AddSection("", "Block", "Tx", "Log", "Address", "Compressed Log")
+	for _, r := range c.Logs {
+		section.Append(r.BlockNumber, r.TransactionIndex, r.LogIndex, r.Address, r.CompressedLog())
+	}
+	return t, nil
 }
 
 func (a *ExtractLog) Name() string {
 	return colors.Green + reflect.TypeOf(a).Elem().String() + colors.Off + ": " + fmt.Sprintf("%d", a.Count)
 }
 
-func (c *ExtractLog) Sort(array []*types.Log) {
-	// Nothing to do
-}
-
-func (c *ExtractLog) ReportHeader(verbose int, r *types.Log) {
-	if verbose > 0 {
-		fmt.Println("Block\tTx\tLog\tAddress\tTopics\tData\t")
-	}
+func (c *ExtractLog) Order() traverser.Ordering {
+	return traverser.Unsorted
 }
 
-func (c *ExtractLog) ReportRecord(r *types.Log) {
-	// if c.Count%20 == 0 {
-	// 	c.w.Flush()
-	// }
-	fmt.Printf("%d\t%d\t%d\t%s\t%s\n", r.BlockNumber, r.TransactionIndex, r.LogIndex, r.Address.Hex(), r.CompressedLog())
+func (c *ExtractLog) State() any {
+	return &struct {
+		Count *uint64
+		Logs  *[]*types.Log
+	}{&c.Count, &c.Logs}
 }
@@ -0,0 +1,59 @@
+package logs
+
+import (
+	"reflect"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/sqldb"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+// --------------------------------
+// SqlWriter writes the logs, the accounts of interest and the names of the contracts
+// emitting the logs to a SQLite database (see package sqldb).
+type SqlWriter struct {
+	*sqldb.Writer
+	Opts traverser.Options
+}
+
+func init() {
+	traverser.Register(traverser.Registration{
+		Name:        "sql",
+		Family:      Family,
+		Description: "Writes the logs, with the names of their contracts, to the SQLite database Ledger.sqlite",
+		Writes:      "Ledger.sqlite",
+	}, func(opts traverser.Options) traverser.Traverser[*types.Log] {
+		return &SqlWriter{Writer: sqldb.NewWriter(opts, "Logs"), Opts: opts}
+	})
+}
+
+func (c *SqlWriter) Traverse(r *types.Log) error {
+	db, err := c.DB()
+	if err != nil {
+		return err
+	}
+	if name, ok := c.Opts.Names[r.Address]; ok {
+		if err := db.AddName(name); err != nil {
+			return err
+		}
+	}
+	return db.AddLog(r)
+}
+
+func (c *SqlWriter) GetKey(r *types.Log) string {
+	return ""
+}
+
+func (c *SqlWriter) Result() (*report.Table, error) {
+	return c.Report(traverser.TypeName(c))
+}
+
+func (c *SqlWriter) Name() string {
+	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
+}
+
+func (c *SqlWriter) Order() traverser.Ordering {
+	return traverser.Unsorted
+}
@@ -0,0 +1,146 @@
+package traverser
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+)
+
+// Ordering is the order in which a traverser needs to receive its records.
+type Ordering int
+
+const (
+	// Unsorted traversers receive each record as soon as it is produced.
+	Unsorted Ordering = iota
+	// Chronological is by block number, transaction index and log index.
+	Chronological
+	// ByAsset groups records by asset (or the emitting or called contract for other records),
+	// chronologically within each.
+	ByAsset
+	// ByAccount groups records by the account they were reported for, chronologically within each.
+	ByAccount
+	// ByAccountAsset groups records by account, then by asset within each account,
+	// chronologically within each asset.
+	ByAccountAsset
+)
+
+var orderingNames = map[Ordering]string{
+	Unsorted:       "unsorted",
+	Chronological:  "chronological",
+	ByAsset:        "by asset",
+	ByAccount:      "by account",
+	ByAccountAsset: "by account and asset",
+}
+
+func (o Ordering) String() string {
+	return orderingNames[o]
+}
+
+// Sorted returns a copy of records sorted as required by the ordering. The records
+// themselves are shared, so traversers must not modify them.
+func Sorted[T Traversable](records []T, order Ordering) []T {
+	ret := slices.Clone(records)
+	if order != Unsorted {
+		slices.SortStableFunc(ret, compare[T](order))
+	}
+	return ret
+}
+
+func compare[T Traversable](order Ordering) func(a, b T) int {
+	var zero T
+	switch any(zero).(type) {
+	case float64:
+		return func(a, b T) int {
+			return cmp.Compare(any(a).(float64), any(b).(float64))
+		}
+	case int64:
+		return func(a, b T) int {
+			return cmp.Compare(any(a).(int64), any(b).(int64))
+		}
+	case Sample:
+		return func(a, b T) int {
+			x, y := any(a).(Sample), any(b).(Sample)
+			if order != Chronological {
+				if c := cmp.Compare(x.Group, y.Group); c != 0 {
+					return c
+				}
+			}
+			return cmp.Compare(x.Time, y.Time)
+		}
+	}
+	return func(a, b T) int {
+		return compareRecords(order, keyOf(a), keyOf(b))
+	}
+}
+
+// --------------------------------
+// recordKey is what a record is sorted by: the asset (or, for records other than
+// statements, the contract) and the account it concerns, if it has them, and its
+// position in the chain.
+type recordKey struct {
+	asset   *base.Address
+	account *base.Address
+	block   base.Blknum
+	tx      base.Txnum
+	sub     []uint64
+}
+
+func keyOf(r any) recordKey {
+	switch x := r.(type) {
+	case *types.Statement:
+		return recordKey{&x.Asset, &x.AccountedFor, x.BlockNumber, x.TransactionIndex, []uint64{x.LogIndex}}
+	case *types.Log:
+		return recordKey{&x.Address, nil, x.BlockNumber, x.TransactionIndex, []uint64{x.LogIndex}}
+	case *types.Transaction:
+		return recordKey{&x.To, nil, x.BlockNumber, x.TransactionIndex, nil}
+	case *types.Trace:
+		ret := recordKey{nil, nil, x.BlockNumber, x.TransactionIndex, x.TraceAddress}
+		if x.Action != nil {
+			ret.asset = &x.Action.To
+		}
+		return ret
+	case *types.Receipt:
+		return recordKey{&x.To, nil, x.BlockNumber, x.TransactionIndex, nil}
+	case *types.Appearance:
+		return recordKey{nil, &x.Address, base.Blknum(x.BlockNumber), base.Txnum(x.TransactionIndex), nil}
+	}
+	return recordKey{}
+}
+
+// compareRecords groups by asset or account, chronologically within each. Records
+// without the asset or account asked for stay in the order the accounts were streamed.
+func compareRecords(order Ordering, a, b recordKey) int {
+	switch order {
+	case ByAsset:
+		if a.asset == nil || b.asset == nil {
+			return 0
+		}
+		if c := cmp.Compare(a.asset.Hex(), b.asset.Hex()); c != 0 {
+			return c
+		}
+	case ByAccount:
+		if a.account == nil || b.account == nil {
+			return 0
+		}
+		if c := cmp.Compare(a.account.Hex(), b.account.Hex()); c != 0 {
+			return c
+		}
+	case ByAccountAsset:
+		if a.account == nil || b.account == nil || a.asset == nil || b.asset == nil {
+			return 0
+		}
+		if c := cmp.Or(
+			cmp.Compare(a.account.Hex(), b.account.Hex()),
+			cmp.Compare(a.asset.Hex(), b.asset.Hex()),
+		); c != 0 {
+			return c
+		}
+	}
+	return cmp.Or(
+		cmp.Compare(a.block, b.block),
+		cmp.Compare(a.tx, b.tx),
+		slices.Compare(a.sub, b.sub),
+	)
+}
@@ -0,0 +1,58 @@
+package traverser
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+)
+
+func TestSortedStatements(t *testing.T) {
+	s1 := &types.Statement{BlockNumber: 3, Asset: base.HexToAddress("0x1"), AccountedFor: base.HexToAddress("0xb")}
+	s2 := &types.Statement{BlockNumber: 1, Asset: base.HexToAddress("0x2"), AccountedFor: base.HexToAddress("0xa")}
+	s3 := &types.Statement{BlockNumber: 2, Asset: base.HexToAddress("0x1"), AccountedFor: base.HexToAddress("0xa")}
+	s4 := &types.Statement{BlockNumber: 2, TransactionIndex: 1, Asset: base.HexToAddress("0x2"), AccountedFor: base.HexToAddress("0xb")}
+	input := []*types.Statement{s1, s2, s3, s4}
+
+	tests := []struct {
+		order Ordering
+		want  []*types.Statement
+	}{
+		{Unsorted, []*types.Statement{s1, s2, s3, s4}},
+		{Chronological, []*types.Statement{s2, s3, s4, s1}},
+		{ByAsset, []*types.Statement{s3, s1, s2, s4}},
+		{ByAccount, []*types.Statement{s2, s3, s4, s1}},
+		{ByAccountAsset, []*types.Statement{s3, s2, s1, s4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.order.String(), func(t *testing.T) {
+			if got := Sorted(input, tt.order); !slices.Equal(got, tt.want) {
+				t.Errorf("Sorted: got blocks %v, want %v", blocks(got), blocks(tt.want))
+			}
+			if !slices.Equal(input, []*types.Statement{s1, s2, s3, s4}) {
+				t.Errorf("Sorted modified its input")
+			}
+		})
+	}
+}
+
+func blocks(stmts []*types.Statement) []string {
+	ret := make([]string, 0, len(stmts))
+	for _, s := range stmts {
+		ret = append(ret, fmt.Sprintf("%d.%d", s.BlockNumber, s.TransactionIndex))
+	}
+	return ret
+}
+
+func TestSortedTraces(t *testing.T) {
+	t1 := &types.Trace{BlockNumber: 2, TraceAddress: []uint64{1}}
+	t2 := &types.Trace{BlockNumber: 2, TraceAddress: []uint64{}}
+	t3 := &types.Trace{BlockNumber: 2, TraceAddress: []uint64{0, 1}}
+	t4 := &types.Trace{BlockNumber: 1, TraceAddress: []uint64{2}}
+	if got, want := Sorted([]*types.Trace{t1, t2, t3, t4}, Chronological), []*types.Trace{t4, t2, t3, t1}; !slices.Equal(got, want) {
+		t.Errorf("Sorted traces differ: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,366 @@
+package traverser
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"iter"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+)
+
+// Stream yields the records of each account of interest, in address order, fetching one
+// account at a time so that only a single account's records are held in memory. Records
+// an earlier run already traversed (see Options.Progress) are skipped.
+func Stream[T any](opts *Options, what string, fetch func(types.Name) ([]T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		accounts := opts.AccountsOfInterest()
+
+		nRecords, nSkipped := 0, 0
+		for _, account := range accounts {
+			log.Println(colors.Yellow+"Fetching", what, "for", account.Address.Hex(), account.Tags, account.Name, colors.Off)
+			records, err := fetch(account)
+			if err != nil {
+				var zero T
+				yield(zero, fmt.Errorf("%s for %s: %w", what, account.Address.Hex(), err))
+				return
+			}
+			for _, r := range records {
+				if opts.Progress.Skip(account.Address, positionOf(r)) {
+					nSkipped++
+					continue
+				}
+				if !yield(r, nil) {
+					return
+				}
+			}
+			nRecords += len(records)
+		}
+		log.Println(colors.Yellow+"Loaded", nRecords, what, colors.Off)
+		if nSkipped > 0 {
+			log.Println(colors.Yellow+"Skipped", nSkipped, what, "covered by the checkpoint", colors.Off)
+		}
+	}
+}
+
+// Statements streams the statements of every account of interest from the source.
+func Statements(source Source, opts *Options) iter.Seq2[*types.Statement, error] {
+	return Stream(opts, "statements", source.Statements)
+}
+
+// Logs streams the logs of every account of interest from the source.
+func Logs(source Source, opts *Options) iter.Seq2[*types.Log, error] {
+	return Stream(opts, "logs", source.Logs)
+}
+
+// Transactions streams the transactions of every account of interest from the source.
+func Transactions(source Source, opts *Options) iter.Seq2[*types.Transaction, error] {
+	return Stream(opts, "transactions", source.Transactions)
+}
+
+// Traces streams the traces of every account of interest from the source.
+func Traces(source Source, opts *Options) iter.Seq2[*types.Trace, error] {
+	return Stream(opts, "traces", source.Traces)
+}
+
+// Receipts streams the receipts of every account of interest from the source.
+func Receipts(source Source, opts *Options) iter.Seq2[*types.Receipt, error] {
+	return Stream(opts, "receipts", source.Receipts)
+}
+
+// Appearances streams the appearances of every account of interest from the source.
+func Appearances(source Source, opts *Options) iter.Seq2[*types.Appearance, error] {
+	return Stream(opts, "appearances", source.Appearances)
+}
+
+// Policy says what happens when a traverser returns an error or panics.
+type Policy int
+
+const (
+	// ContinueOnError reports the errors along with the results. A traverser carries on
+	// with the next record, unless it failed to initialize or panicked, in which case it
+	// is fed no more records and has no result.
+	ContinueOnError Policy = iota
+	// FailFast stops the run at the first error.
+	FailFast
+)
+
+// Policy returns the policy chosen with --fail-fast.
+func (opts *Options) Policy() Policy {
+	if opts.FailFast {
+		return FailFast
+	}
+	return ContinueOnError
+}
+
+// MaxErrors is the number of errors kept for each traverser. The others are only counted.
+const MaxErrors = 10
+
+// --------------------------------
+// Failure is what went wrong with a traverser during a run.
+type Failure struct {
+	Name    string
+	Errors  []error
+	Count   int
+	Dropped bool
+}
+
+func (f *Failure) Error() string {
+	msgs := make([]string, 0, len(f.Errors)+1)
+	for _, err := range f.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	if f.Count > len(f.Errors) {
+		msgs = append(msgs, fmt.Sprintf("and %d more", f.Count-len(f.Errors)))
+	}
+	return f.Name + ": " + strings.Join(msgs, "; ")
+}
+
+func (f *Failure) Unwrap() []error {
+	return f.Errors
+}
+
+// fail records an error of a traverser, creating its failure if need be. Dropped
+// traversers are fed no more records. It returns the error naming the traverser.
+func fail(f **Failure, t any, err error, drop bool) error {
+	if *f == nil {
+		*f = &Failure{Name: TypeName(t)}
+	}
+	(*f).Count++
+	if len((*f).Errors) < MaxErrors {
+		(*f).Errors = append((*f).Errors, err)
+	}
+	(*f).Dropped = (*f).Dropped || drop
+	return fmt.Errorf("%s: %w", TypeName(t), err)
+}
+
+func dropped(f *Failure) bool {
+	return f != nil && f.Dropped
+}
+
+// start initializes the traversers that need it.
+func start[T Traversable](traversers []Traverser[T], failed []*Failure) error {
+	var first error
+	for i, t := range traversers {
+		if init, ok := t.(Initializer); ok {
+			if err := init.Init(); err != nil {
+				first = cmp.Or(first, fail(&failed[i], t, err, true))
+			}
+		}
+	}
+	return first
+}
+
+// step feeds one record to a traverser, recording the error it returns or the panic it
+// raises in its failure. It returns the error, if any.
+func step[T Traversable](t Traverser[T], r T, f **Failure) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fail(f, t, fmt.Errorf("panic: %v", p), true)
+		}
+	}()
+	if err := t.Traverse(r); err != nil {
+		return fail(f, t, err, false)
+	}
+	return nil
+}
+
+// Run feeds each record that passes the filter stage (which may be nil) to the unsorted
+// traversers as it arrives. Records are buffered only if a traverser declares an ordering,
+// in which case it receives its own sorted copy of the buffer once the stream is exhausted.
+// Traversers that are Unfiltered see every record. What went wrong with each traverser is
+// returned in a slice indexed like traversers. The error is that of the records, or with
+// the FailFast policy the first error of a traverser.
+func Run[T Traversable](records iter.Seq2[T, error], traversers []Traverser[T], stage *FilterStage, policy Policy) ([]*Failure, error) {
+	failed := make([]*Failure, len(traversers))
+	if err := start(traversers, failed); err != nil && policy == FailFast {
+		return failed, err
+	}
+	return failed, Feed(records, traversers, failed, stage, policy)
+}
+
+// Feed feeds more records to traversers that Run already started, as Run does, adding
+// what goes wrong with each traverser to its failure. Sorted traversers receive the new
+// records sorted among themselves, after the records of earlier calls.
+func Feed[T Traversable](records iter.Seq2[T, error], traversers []Traverser[T], failed []*Failure, stage *FilterStage, policy Policy) error {
+	streaming := make([]int, 0, len(traversers))
+	sorted := make([]int, 0, len(traversers))
+	bufferAll, bufferKept := false, false
+	for i, t := range traversers {
+		if t.Order() == Unsorted {
+			streaming = append(streaming, i)
+			continue
+		}
+		sorted = append(sorted, i)
+		if isUnfiltered(t) {
+			bufferAll = true
+		} else {
+			bufferKept = true
+		}
+	}
+
+	var all, kept []T
+	for r, err := range records {
+		if err != nil {
+			return err
+		}
+		keep := stage.Keep(r)
+		for _, i := range streaming {
+			t := traversers[i]
+			if (keep || isUnfiltered(t)) && !dropped(failed[i]) {
+				if err := step(t, r, &failed[i]); err != nil && policy == FailFast {
+					return err
+				}
+			}
+		}
+		if bufferAll {
+			all = append(all, r)
+		}
+		if bufferKept && keep {
+			kept = append(kept, r)
+		}
+	}
+
+	for _, i := range sorted {
+		t := traversers[i]
+		buffer := kept
+		if isUnfiltered(t) {
+			buffer = all
+		}
+		for _, r := range Sorted(buffer, t.Order()) {
+			if dropped(failed[i]) {
+				break
+			}
+			if err := step(t, r, &failed[i]); err != nil && policy == FailFast {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ChannelDepth is the number of records queued for each traverser in parallel mode
+// before the producer waits for it to catch up.
+const ChannelDepth = 256
+
+// RunParallel runs each traverser on its own goroutine, fed through a channel holding
+// at most depth records, after the filter stage as in Run, which it otherwise behaves
+// like. With the FailFast policy, the first error of a traverser stops the producer.
+func RunParallel[T Traversable](records iter.Seq2[T, error], traversers []Traverser[T], stage *FilterStage, depth int, policy Policy) ([]*Failure, error) {
+	failed := make([]*Failure, len(traversers))
+	if err := start(traversers, failed); err != nil && policy == FailFast {
+		return failed, err
+	}
+	return failed, FeedParallel(records, traversers, failed, stage, depth, policy)
+}
+
+// FeedParallel feeds more records to traversers that RunParallel already started, as
+// RunParallel does, adding what goes wrong with each traverser to its failure.
+func FeedParallel[T Traversable](records iter.Seq2[T, error], traversers []Traverser[T], failed []*Failure, stage *FilterStage, depth int, policy Policy) error {
+	var stop atomic.Bool
+	var once sync.Once
+	var firstErr error
+	halt := func(err error) {
+		if err != nil && policy == FailFast {
+			once.Do(func() { firstErr = err })
+			stop.Store(true)
+		}
+	}
+
+	chans := make([]chan T, len(traversers))
+	var wg sync.WaitGroup
+	for i, t := range traversers {
+		chans[i] = make(chan T, depth)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			consume(t, chans[i], &failed[i], halt, stop.Load)
+		}()
+	}
+
+	var err error
+	for r, e := range records {
+		if e != nil {
+			err = e
+			break
+		} else if stop.Load() {
+			break
+		}
+		keep := stage.Keep(r)
+		for i, ch := range chans {
+			if keep || isUnfiltered(traversers[i]) {
+				ch <- r
+			}
+		}
+	}
+	for _, ch := range chans {
+		close(ch)
+	}
+	wg.Wait()
+	return cmp.Or(err, firstErr)
+}
+
+// consume feeds a traverser the records of its channel. Once it is dropped, or the run
+// halted (as stopped reports), the channel is still drained so that the producer is never
+// blocked, but no more records are fed to the traverser.
+func consume[T Traversable](t Traverser[T], ch <-chan T, f **Failure, halt func(error), stopped func() bool) {
+	sorted := t.Order() != Unsorted
+	var buffer []T
+	for r := range ch {
+		if dropped(*f) || stopped() {
+			continue
+		}
+		if sorted {
+			buffer = append(buffer, r)
+		} else {
+			halt(step(t, r, f))
+		}
+	}
+	for _, r := range Sorted(buffer, t.Order()) {
+		if dropped(*f) || stopped() {
+			break
+		}
+		halt(step(t, r, f))
+	}
+}
+
+// Results collects the tables of the traversers in order, skipping those that were
+// dropped during the run and those whose Result fails. Every failure is joined in the
+// error.
+func Results[T Traversable](traversers []Traverser[T], failed []*Failure) ([]*report.Table, error) {
+	tables := make([]*report.Table, 0, len(traversers))
+	errs := []error{}
+	for i, t := range traversers {
+		if i < len(failed) && failed[i] != nil {
+			errs = append(errs, failed[i])
+			if failed[i].Dropped {
+				continue
+			}
+		}
+		table, err := result(t)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		tables = append(tables, table)
+	}
+	return tables, errors.Join(errs...)
+}
+
+func result[T Traversable](t Traverser[T]) (table *report.Table, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: panic: %v", TypeName(t), r)
+		}
+	}()
+	if table, err = t.Result(); err != nil {
+		return nil, fmt.Errorf("%s: %w", TypeName(t), err)
+	}
+	return table, nil
+}
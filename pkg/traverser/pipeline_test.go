@@ -0,0 +1,218 @@
+package traverser
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+)
+
+// recorder remembers the values it sees, optionally asking for sorted input.
+type recorder struct {
+	sorted bool
+	seen   []float64
+}
+
+func (r *recorder) Traverse(v float64) error       { r.seen = append(r.seen, v); return nil }
+func (r *recorder) GetKey(v float64) string        { return "" }
+func (r *recorder) Result() (*report.Table, error) { return report.NewTable("recorder"), nil }
+func (r *recorder) Name() string                   { return "recorder" }
+func (r *recorder) Order() Ordering {
+	if r.sorted {
+		return Chronological
+	}
+	return Unsorted
+}
+
+func values(vals ...float64) func(yield func(float64, error) bool) {
+	return func(yield func(float64, error) bool) {
+		for _, v := range vals {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	streaming := &recorder{}
+	buffered := &recorder{sorted: true}
+	if _, err := Run(values(3, 1, 2), []Traverser[float64]{streaming, buffered}, nil, FailFast); err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{3, 1, 2}; !slices.Equal(streaming.seen, want) {
+		t.Errorf("streaming traverser saw %v, want %v", streaming.seen, want)
+	}
+	if want := []float64{1, 2, 3}; !slices.Equal(buffered.seen, want) {
+		t.Errorf("sorted traverser saw %v, want %v", buffered.seen, want)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	failing := func(yield func(float64, error) bool) {
+		if yield(1, nil) {
+			yield(0, errors.New("boom"))
+		}
+	}
+	r := &recorder{}
+	if _, err := Run(failing, []Traverser[float64]{r}, nil, ContinueOnError); err == nil || err.Error() != "boom" {
+		t.Errorf("Run error: got %v, want boom", err)
+	}
+	if len(r.seen) != 1 {
+		t.Errorf("traverser saw %d values, want 1", len(r.seen))
+	}
+}
+
+func TestStream(t *testing.T) {
+	a1 := types.Name{Address: base.HexToAddress("0x1"), Tags: "00-Active"}
+	a2 := types.Name{Address: base.HexToAddress("0x2"), Tags: "00-Active"}
+	a3 := types.Name{Address: base.HexToAddress("0x3"), Tags: "99-Skipped"}
+	opts := Options{
+		Tags:     []string{"00-Active"},
+		Accounts: map[base.Address]types.Name{a3.Address: a3, a2.Address: a2, a1.Address: a1},
+	}
+
+	fetched := []base.Address{}
+	fetch := func(account types.Name) ([]base.Address, error) {
+		fetched = append(fetched, account.Address)
+		return []base.Address{account.Address, account.Address}, nil
+	}
+
+	n := 0
+	for addr, err := range Stream(&opts, "addresses", fetch) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fetched) != n/2+1 {
+			t.Errorf("record %d arrived after fetching %d accounts, want %d", n, len(fetched), n/2+1)
+		}
+		if addr != fetched[len(fetched)-1] {
+			t.Errorf("record %d: got %s, want %s", n, addr.Hex(), fetched[len(fetched)-1].Hex())
+		}
+		n++
+	}
+	if want := []base.Address{a1.Address, a2.Address}; !slices.Equal(fetched, want) {
+		t.Errorf("fetched %v, want %v", fetched, want)
+	}
+}
+
+// panicker fails on the value it is told to.
+type panicker struct {
+	recorder
+	at float64
+}
+
+func (p *panicker) Traverse(v float64) error {
+	if v == p.at {
+		panic("bad value")
+	}
+	return p.recorder.Traverse(v)
+}
+
+func TestRunParallel(t *testing.T) {
+	vals := make([]float64, 0, 1000)
+	for i := 1000; i > 0; i-- {
+		vals = append(vals, float64(i))
+	}
+
+	streaming := &recorder{}
+	buffered := &recorder{sorted: true}
+	failing := &panicker{at: 500}
+	traversers := []Traverser[float64]{streaming, failing, buffered}
+	failed, err := RunParallel(values(vals...), traversers, nil, 4, ContinueOnError)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(streaming.seen, vals) {
+		t.Errorf("streaming traverser saw %d values out of order", len(streaming.seen))
+	}
+	if !slices.IsSorted(buffered.seen) || len(buffered.seen) != len(vals) {
+		t.Errorf("sorted traverser saw %d values, sorted %v", len(buffered.seen), slices.IsSorted(buffered.seen))
+	}
+	if failed[0] != nil || failed[2] != nil || failed[1] == nil {
+		t.Fatalf("failures: got %v, want only the second traverser", failed)
+	}
+
+	tables, err := Results(traversers, failed)
+	if err == nil || len(tables) != 2 {
+		t.Errorf("Results: got %d tables and %v, want 2 tables and an error", len(tables), err)
+	}
+}
+
+// erring returns an error for values above a limit, and may fail to initialize.
+type erring struct {
+	recorder
+	above   float64
+	initErr error
+}
+
+func (e *erring) Init() error {
+	return e.initErr
+}
+
+func (e *erring) Traverse(v float64) error {
+	if v > e.above {
+		return fmt.Errorf("bad value %v", v)
+	}
+	return e.recorder.Traverse(v)
+}
+
+func TestRunPolicies(t *testing.T) {
+	vals := make([]float64, 0, 20)
+	for i := 1; i <= 20; i++ {
+		vals = append(vals, float64(i))
+	}
+
+	for _, parallel := range []bool{false, true} {
+		run := func(traversers []Traverser[float64], policy Policy) ([]*Failure, error) {
+			if parallel {
+				return RunParallel(values(vals...), traversers, nil, 1, policy)
+			}
+			return Run(values(vals...), traversers, nil, policy)
+		}
+
+		// Errors are reported with the result, and the traverser carries on
+		healthy, bad := &recorder{}, &erring{above: 5}
+		unready := &erring{initErr: errors.New("no workbook")}
+		traversers := []Traverser[float64]{healthy, bad, unready}
+		failed, err := run(traversers, ContinueOnError)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(healthy.seen) != 20 || len(bad.seen) != 5 || len(unready.seen) != 0 {
+			t.Errorf("parallel %v: traversers saw %d, %d and %d values", parallel, len(healthy.seen), len(bad.seen), len(unready.seen))
+		}
+		want := "traverser.erring: bad value 6; bad value 7; bad value 8; bad value 9; bad value 10; " +
+			"bad value 11; bad value 12; bad value 13; bad value 14; bad value 15; and 5 more"
+		if failed[0] != nil || failed[1] == nil || failed[1].Error() != want || failed[1].Dropped {
+			t.Errorf("parallel %v: failure differs:\n got %v\nwant %s", parallel, failed[1], want)
+		}
+		if failed[2] == nil || !failed[2].Dropped {
+			t.Errorf("parallel %v: traverser failing to initialize not dropped: %v", parallel, failed[2])
+		}
+		tables, err := Results(traversers, failed)
+		if len(tables) != 2 || err == nil || !strings.Contains(err.Error(), "no workbook") {
+			t.Errorf("parallel %v: Results: got %d tables and %v, want 2 tables and both failures", parallel, len(tables), err)
+		}
+
+		// The run stops at the first error, and what is queued or buffered is not fed
+		healthy, bad = &recorder{}, &erring{above: 5}
+		buffered := &recorder{sorted: true}
+		_, err = run([]Traverser[float64]{healthy, bad, buffered}, FailFast)
+		if err == nil || err.Error() != "traverser.erring: bad value 6" {
+			t.Errorf("parallel %v: FailFast error: got %v", parallel, err)
+		}
+		if len(healthy.seen) == 20 && !parallel {
+			t.Errorf("FailFast did not stop the run")
+		}
+		if len(buffered.seen) != 0 {
+			t.Errorf("parallel %v: sorted traverser saw %d values after the run stopped", parallel, len(buffered.seen))
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package receipts
+
+import (
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+const Family = "receipts"
+
+func init() {
+	traverser.RegisterFamily[*types.Receipt](Family, "reports over the receipts of the transactions of each account")
+	traverser.RegisterCounter[*types.Receipt](Family, "receipts")
+}
@@ -0,0 +1,42 @@
+package receipts
+
+import (
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+// --------------------------------
+// GroupBy counts the receipts by recipient or error status.
+type GroupBy = traverser.GroupCounter[*types.Receipt, groupKey]
+
+// groupKey holds the recipient or the status of a receipt, as the way of counting has it.
+type groupKey struct {
+	To      base.Address `json:"to"`
+	Name    string       `json:"name"`
+	Status  base.Value   `json:"status"`
+	IsError bool         `json:"isError"`
+}
+
+func init() {
+	traverser.RegisterGroupCounters(Family, "Receipts",
+		traverser.CountBy[*types.Receipt, groupKey]{
+			Name:        "by_to",
+			Description: "Counts the receipts of transactions sent to each address",
+			Columns:     []string{"To", "Name"},
+			Key: func(opts *traverser.Options, r *types.Receipt) groupKey {
+				return groupKey{To: r.To, Name: opts.NameOf(r.To)}
+			},
+			Row: func(k groupKey) []any { return []any{k.To, k.Name} },
+		},
+		traverser.CountBy[*types.Receipt, groupKey]{
+			Name:        "by_status",
+			Description: "Counts the receipts by status",
+			Columns:     []string{"Status", "Is Error"},
+			Key: func(opts *traverser.Options, r *types.Receipt) groupKey {
+				return groupKey{Status: r.Status, IsError: r.IsError}
+			},
+			Row: func(k groupKey) []any { return []any{k.Status, k.IsError} },
+		},
+	)
+}
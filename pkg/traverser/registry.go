@@ -0,0 +1,178 @@
+package traverser
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// --------------------------------
+// Family is a command that groups the traversers consuming the same kind of record.
+type Family struct {
+	Name        string
+	Description string
+	Consumes    reflect.Type
+}
+
+// --------------------------------
+// Registration describes a traverser so it can be listed and selected by name. Packages
+// register their traversers from init functions, so importing a package is enough to
+// make its traversers available. Writes names the file a traverser writes, if it writes
+// one, so that the server can refuse to run it.
+type Registration struct {
+	Name        string
+	Aliases     []string
+	Groups      []string
+	Family      string
+	Description string
+	Writes      string
+	Consumes    reflect.Type
+	New         func(opts Options) any
+}
+
+var (
+	registryMutex sync.Mutex
+	families      = map[string]Family{}
+	registrations = []Registration{}
+)
+
+// RegisterFamily adds a command whose traversers consume records of type T.
+func RegisterFamily[T Traversable](name, description string) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := families[name]; ok {
+		panic(fmt.Sprintf("traverser family %s registered twice", name))
+	}
+	families[name] = Family{Name: name, Description: description, Consumes: reflect.TypeFor[T]()}
+}
+
+// Register adds a traverser to its family. It panics if the name or one of the aliases
+// is already taken in the family, which can only be a programming error.
+func Register[T Traversable](r Registration, ctor func(opts Options) Traverser[T]) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if r.Name == "" || r.Family == "" {
+		panic("traverser registered without a name or family")
+	}
+	for _, existing := range registrations {
+		if existing.Family == r.Family && (existing.matches(r.Name) || slices.ContainsFunc(r.Aliases, existing.matches)) {
+			panic(fmt.Sprintf("traverser %s.%s registered twice", r.Family, r.Name))
+		}
+	}
+	r.Consumes = reflect.TypeFor[T]()
+	r.New = func(opts Options) any { return ctor(opts) }
+	registrations = append(registrations, r)
+}
+
+func (r *Registration) matches(name string) bool {
+	return r.Name == name || slices.Contains(r.Aliases, name)
+}
+
+// Families returns the registered families sorted by name.
+func Families() []Family {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	ret := make([]Family, 0, len(families))
+	for _, f := range families {
+		ret = append(ret, f)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+func LookupFamily(name string) (Family, bool) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	f, ok := families[name]
+	return f, ok
+}
+
+// Registrations returns the traversers of a family (or of every family if family is
+// empty) sorted by family and name.
+func Registrations(family string) []Registration {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	ret := make([]Registration, 0, len(registrations))
+	for _, r := range registrations {
+		if family == "" || r.Family == family {
+			ret = append(ret, r)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Family == ret[j].Family {
+			return ret[i].Name < ret[j].Name
+		}
+		return ret[i].Family < ret[j].Family
+	})
+	return ret
+}
+
+// Groups returns the names of the groups used in a family, sorted, along with their members.
+func Groups(family string) ([]string, map[string][]string) {
+	members := map[string][]string{}
+	for _, r := range Registrations(family) {
+		for _, g := range r.Groups {
+			members[g] = append(members[g], r.Name)
+		}
+	}
+	names := make([]string, 0, len(members))
+	for g := range members {
+		names = append(names, g)
+	}
+	slices.Sort(names)
+	return names, members
+}
+
+// Resolve turns traverser names, aliases and group names into registrations, in the
+// order given and without duplicates.
+func Resolve(family string, names []string) ([]Registration, error) {
+	regs := Registrations(family)
+	ret := make([]Registration, 0, len(names))
+	seen := map[string]bool{}
+	add := func(r Registration) {
+		if !seen[r.Name] {
+			seen[r.Name] = true
+			ret = append(ret, r)
+		}
+	}
+
+	for _, name := range names {
+		found := false
+		for _, r := range regs {
+			if r.matches(name) {
+				add(r)
+				found = true
+			}
+		}
+		if found {
+			continue
+		}
+		for _, r := range regs {
+			if slices.Contains(r.Groups, name) {
+				add(r)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown %s traverser %q", family, name)
+		}
+	}
+	return ret, nil
+}
+
+// Instantiate creates a traverser for each registration. Every registration must consume T.
+func Instantiate[T Traversable](regs []Registration, opts Options) ([]Traverser[T], error) {
+	ret := make([]Traverser[T], 0, len(regs))
+	for _, r := range regs {
+		t, ok := r.New(opts).(Traverser[T])
+		if !ok {
+			return nil, fmt.Errorf("traverser %s.%s consumes %s, not %s", r.Family, r.Name, r.Consumes, reflect.TypeFor[T]())
+		}
+		ret = append(ret, t)
+	}
+	return ret, nil
+}
@@ -0,0 +1,99 @@
+package traverser
+
+import (
+	"strings"
+	"testing"
+)
+
+func init() {
+	RegisterFamily[float64]("test", "a family for testing the registry")
+	Register(Registration{
+		Name:        "mock",
+		Aliases:     []string{"test.mock"},
+		Groups:      []string{"mocks"},
+		Family:      "test",
+		Description: "counts the values",
+	}, func(opts Options) Traverser[float64] {
+		return &mockTraverser{}
+	})
+	Register(Registration{
+		Name:   "other",
+		Groups: []string{"mocks"},
+		Family: "test",
+	}, func(opts Options) Traverser[float64] {
+		return &mockTraverser{count: 10}
+	})
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		want  []string
+	}{
+		{"Name", []string{"other"}, []string{"other"}},
+		{"Alias", []string{"test.mock"}, []string{"mock"}},
+		{"Group", []string{"mocks"}, []string{"mock", "other"}},
+		{"NoDuplicates", []string{"other", "mocks", "mock"}, []string{"other", "mock"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regs, err := Resolve("test", tt.names)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, r := range regs {
+				got = append(got, r.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Resolve failed: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Resolve("test", []string{"mok"}); err == nil {
+		t.Error("expected an error for an unknown traverser")
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	regs, _ := Resolve("test", []string{"mock", "other"})
+	traversers, err := Instantiate[float64](regs, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traversers) != 2 {
+		t.Fatalf("Instantiate failed: got %d traversers, want 2", len(traversers))
+	}
+	if traversers[1].(*mockTraverser).count != 10 {
+		t.Errorf("Instantiate used the wrong constructor")
+	}
+
+	if _, err := Instantiate[int64](regs, Options{}); err == nil {
+		t.Error("expected an error when the record type does not match")
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic when an alias is registered twice")
+		}
+	}()
+	Register(Registration{Name: "another", Aliases: []string{"mock"}, Family: "test"}, func(opts Options) Traverser[float64] {
+		return &mockTraverser{}
+	})
+}
+
+func TestFamilies(t *testing.T) {
+	fam, ok := LookupFamily("test")
+	if !ok || fam.Consumes.String() != "float64" {
+		t.Errorf("LookupFamily failed: got %v %v", fam, ok)
+	}
+	groups, members := Groups("test")
+	if len(groups) != 1 || len(members["mocks"]) != 2 {
+		t.Errorf("Groups failed: got %v %v", groups, members)
+	}
+}
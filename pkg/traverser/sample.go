@@ -0,0 +1,110 @@
+package traverser
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/expr"
+)
+
+// SampledRecords are the records samples may be taken from (see Options.Of).
+var SampledRecords = []string{"statements", "logs", "transactions", "receipts"}
+
+// GroupBys are the ways samples may be grouped (see Options.GroupBy).
+var GroupBys = []string{"asset", "account", "entity"}
+
+// DefaultField is the field sampled when none is chosen.
+const DefaultField = "blockNumber"
+
+// DefaultBins is the number of bins of a histogram when none is chosen.
+const DefaultBins = 10
+
+// --------------------------------
+// Sample is the value of the chosen field of one record (or, with Options.PerTx, the sum
+// over the records of one transaction). Group is the address of the asset or account the
+// record concerns, or the entity of the account (see Options.Entities), if the samples are
+// grouped, and empty otherwise.
+type Sample struct {
+	Value float64
+	Group string
+	Time  base.Timestamp
+}
+
+// Samples turns the records that pass the filter stage into samples of the expression
+// chosen with Options.Field, grouped as chosen with Options.GroupBy. It fails if the
+// expression is not a number that can be evaluated against the records.
+func Samples[T *types.Statement | *types.Log | *types.Transaction | *types.Receipt](opts *Options, records iter.Seq2[T, error], stage *FilterStage) (iter.Seq2[Sample, error], error) {
+	field := opts.Field
+	if field == "" {
+		field = DefaultField
+	}
+	e, err := expr.Compile(field, opts.Names)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field %q: %w", field, err)
+	}
+	if err := e.CheckNumber(reflect.TypeFor[T]()); err != nil {
+		return nil, fmt.Errorf("invalid field %q: %w", field, err)
+	}
+
+	group := func(key recordKey) (string, error) {
+		switch opts.GroupBy {
+		case "asset":
+			if key.asset != nil {
+				return key.asset.Hex(), nil
+			}
+		case "account":
+			if key.account != nil {
+				return key.account.Hex(), nil
+			}
+		case "entity":
+			if key.account != nil {
+				return opts.EntityOf(*key.account), nil
+			}
+		default:
+			return "", nil
+		}
+		return "", fmt.Errorf("%s records cannot be grouped by %s", reflect.TypeFor[T](), opts.GroupBy)
+	}
+
+	return func(yield func(Sample, error) bool) {
+		// With PerTx, the samples of consecutive records of one transaction are summed
+		var pending *Sample
+		var pendingKey recordKey
+		for r, err := range records {
+			if err != nil {
+				yield(Sample{}, err)
+				return
+			} else if stage != nil && !stage.Keep(r) {
+				continue
+			}
+			key := keyOf(r)
+			g, err := group(key)
+			if err != nil {
+				yield(Sample{}, err)
+				return
+			}
+			ts, _ := timestampOf(r)
+			s := Sample{Value: e.Number(r), Group: g, Time: ts}
+			if !opts.PerTx {
+				if !yield(s, nil) {
+					return
+				}
+				continue
+			}
+			if pending != nil && pending.Group == g && pendingKey.block == key.block && pendingKey.tx == key.tx {
+				pending.Value += s.Value
+				continue
+			}
+			if pending != nil && !yield(*pending, nil) {
+				return
+			}
+			pending, pendingKey = &s, key
+		}
+		if pending != nil {
+			yield(*pending, nil)
+		}
+	}, nil
+}
@@ -0,0 +1,95 @@
+package traverser
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+)
+
+func TestSamples(t *testing.T) {
+	usdc, dai := base.HexToAddress("0xa"), base.HexToAddress("0xd")
+	logs := []*types.Log{
+		{Address: usdc, BlockNumber: 1, TransactionIndex: 0, LogIndex: 0, Timestamp: 10},
+		{Address: usdc, BlockNumber: 1, TransactionIndex: 0, LogIndex: 1, Timestamp: 10},
+		{Address: dai, BlockNumber: 1, TransactionIndex: 0, LogIndex: 2, Timestamp: 10},
+		{Address: usdc, BlockNumber: 2, TransactionIndex: 3, LogIndex: 0, Timestamp: 20},
+	}
+	records := func(yield func(*types.Log, error) bool) {
+		for _, l := range logs {
+			if !yield(l, nil) {
+				return
+			}
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want []Sample
+	}{
+		{"Default", Options{}, []Sample{{1, "", 10}, {1, "", 10}, {1, "", 10}, {2, "", 20}}},
+		{"Field", Options{Field: "logIndex"}, []Sample{{0, "", 10}, {1, "", 10}, {2, "", 10}, {0, "", 20}}},
+		{"PerTx", Options{Field: "1", PerTx: true}, []Sample{{3, "", 10}, {1, "", 20}}},
+		{"PerTxByAsset", Options{Field: "1", PerTx: true, GroupBy: "asset"}, []Sample{{2, usdc.Hex(), 10}, {1, dai.Hex(), 10}, {1, usdc.Hex(), 20}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples, err := Samples(&tt.opts, records, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []Sample{}
+			for s, err := range samples {
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, s)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Samples differ: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Accounts are grouped by entity, those without one on their own
+	treasury, payroll, other := base.HexToAddress("0x1"), base.HexToAddress("0x2"), base.HexToAddress("0x3")
+	opts := Options{Field: "1", GroupBy: "entity", Entities: map[base.Address]string{treasury: "Operations", payroll: "Operations"}}
+	statements := func(yield func(*types.Statement, error) bool) {
+		for _, account := range []base.Address{treasury, other, payroll} {
+			if !yield(&types.Statement{AccountedFor: account, Timestamp: 10}, nil) {
+				return
+			}
+		}
+	}
+	samples, err := Samples(&opts, statements, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []Sample{}
+	for s, err := range samples {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, s)
+	}
+	if want := []Sample{{1, "Operations", 10}, {1, other.Hex(), 10}, {1, "Operations", 10}}; !slices.Equal(got, want) {
+		t.Errorf("Samples by entity differ: got %v, want %v", got, want)
+	}
+	if samples, _ := Samples(&Options{GroupBy: "entity"}, records, nil); samples != nil {
+		for _, err := range samples {
+			if err == nil {
+				t.Error("expected an error grouping logs by entity")
+			}
+			break
+		}
+	}
+
+	if _, err := Samples(&Options{Field: "symbol"}, records, nil); err == nil {
+		t.Error("expected an error sampling a string field")
+	}
+	if _, err := Samples(&Options{Field: "address"}, records, nil); err == nil {
+		t.Error("expected an error sampling an address field")
+	}
+}
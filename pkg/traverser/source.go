@@ -0,0 +1,37 @@
+package traverser
+
+import (
+	"fmt"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+)
+
+// --------------------------------
+// Source delivers the records for a single account. The SDK source queries a live
+// chifra installation, the file sources read previously exported data from disk, and
+// the synthetic source makes them up.
+type Source interface {
+	Statements(account types.Name) ([]*types.Statement, error)
+	Logs(account types.Name) ([]*types.Log, error)
+	Transactions(account types.Name) ([]*types.Transaction, error)
+	Traces(account types.Name) ([]*types.Trace, error)
+	Receipts(account types.Name) ([]*types.Receipt, error)
+	Appearances(account types.Name) ([]*types.Appearance, error)
+}
+
+var Sources = []string{"sdk", "json", "csv", "synthetic"}
+
+func NewSource(opts Options) (Source, error) {
+	switch opts.Source {
+	case "", "sdk":
+		return &SdkSource{Chain: opts.Chain}, nil
+	case "json":
+		return &JsonSource{Folder: opts.InputPath}, nil
+	case "csv":
+		return &CsvSource{Folder: opts.InputPath}, nil
+	case "synthetic":
+		return NewSynthSource(opts), nil
+	default:
+		return nil, fmt.Errorf("unknown source %q (one of %v)", opts.Source, Sources)
+	}
+}
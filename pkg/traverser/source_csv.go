@@ -0,0 +1,197 @@
+package traverser
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+)
+
+// --------------------------------
+// CsvSource reads chifra's --fmt csv exports from the same folders as JsonSource (for
+// example, <Folder>/recons/<address>.csv). Columns are matched to top level record fields
+// by their JSON name, so calculated columns such as amountNet or date, and nested values
+// such as the action of a trace, are ignored.
+type CsvSource struct {
+	Folder string
+}
+
+func (s *CsvSource) Statements(account types.Name) ([]*types.Statement, error) {
+	path := findExport(s.Folder, "recons", account, ".csv")
+	if path == "" {
+		return []*types.Statement{}, nil
+	}
+	return readCsv[types.Statement](path)
+}
+
+func (s *CsvSource) Logs(account types.Name) ([]*types.Log, error) {
+	path := findExport(s.Folder, "logs", account, ".csv")
+	if path == "" {
+		return []*types.Log{}, nil
+	}
+	return readCsv[types.Log](path)
+}
+
+func (s *CsvSource) Transactions(account types.Name) ([]*types.Transaction, error) {
+	path := findExport(s.Folder, "transactions", account, ".csv")
+	if path == "" {
+		return []*types.Transaction{}, nil
+	}
+	return readCsv[types.Transaction](path)
+}
+
+func (s *CsvSource) Traces(account types.Name) ([]*types.Trace, error) {
+	path := findExport(s.Folder, "traces", account, ".csv")
+	if path == "" {
+		return []*types.Trace{}, nil
+	}
+	return readCsv[types.Trace](path)
+}
+
+func (s *CsvSource) Receipts(account types.Name) ([]*types.Receipt, error) {
+	path := findExport(s.Folder, "receipts", account, ".csv")
+	if path == "" {
+		return []*types.Receipt{}, nil
+	}
+	return readCsv[types.Receipt](path)
+}
+
+func (s *CsvSource) Appearances(account types.Name) ([]*types.Appearance, error) {
+	path := findExport(s.Folder, "appearances", account, ".csv")
+	if path == "" {
+		return []*types.Appearance{}, nil
+	}
+	return readCsv[types.Appearance](path)
+}
+
+var errNoHeader = errors.New("missing header row")
+
+func readCsv[T any](path string) ([]*T, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%s: %w", path, errNoHeader)
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	fields := jsonFields(reflect.TypeOf(new(T)).Elem())
+	ret := []*T{}
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		item := new(T)
+		value := reflect.ValueOf(item).Elem()
+		for i, cell := range row {
+			if i >= len(header) || len(cell) == 0 {
+				continue
+			}
+			name := header[i]
+			if strings.HasPrefix(name, "topic") {
+				if topics := value.FieldByName("Topics"); topics.IsValid() {
+					topics.Set(reflect.Append(topics, reflect.ValueOf(base.HexToHash(cell))))
+				}
+				continue
+			}
+			index, ok := fields[name]
+			if !ok {
+				continue
+			}
+			if err := setField(value.Field(index), cell); err != nil {
+				return nil, fmt.Errorf("%s:%d: column %s: %w", path, line, name, err)
+			}
+		}
+		ret = append(ret, item)
+	}
+	return ret, nil
+}
+
+// jsonFields maps the json name of each exported field to its index in the struct.
+func jsonFields(t reflect.Type) map[string]int {
+	ret := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		ret[name] = i
+	}
+	return ret
+}
+
+var (
+	addressType = reflect.TypeOf(base.Address{})
+	hashType    = reflect.TypeOf(base.Hash{})
+	weiType     = reflect.TypeOf(base.Wei{})
+	floatType   = reflect.TypeOf(base.Float{})
+)
+
+func setField(field reflect.Value, cell string) error {
+	switch field.Type() {
+	case addressType:
+		field.Set(reflect.ValueOf(base.HexToAddress(cell)))
+		return nil
+	case hashType:
+		field.Set(reflect.ValueOf(base.HexToHash(cell)))
+		return nil
+	case weiType:
+		bi, ok := new(big.Int).SetString(cell, 10)
+		if !ok {
+			return fmt.Errorf("invalid amount %q", cell)
+		}
+		field.Set(reflect.ValueOf(base.Wei(*bi)))
+		return nil
+	case floatType:
+		bf, ok := new(big.Float).SetString(cell)
+		if !ok {
+			return fmt.Errorf("invalid number %q", cell)
+		}
+		field.Set(reflect.ValueOf(base.Float(*bf)))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(cell)
+	case reflect.Bool:
+		v, err := strconv.ParseBool(cell)
+		if err != nil {
+			return err
+		}
+		field.SetBool(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(cell, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetUint(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(cell, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(v)
+	}
+	return nil
+}
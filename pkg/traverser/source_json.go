@@ -0,0 +1,156 @@
+package traverser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/file"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+)
+
+// --------------------------------
+// JsonSource reads records exported with chifra (or by this tool) from disk. Statements
+// are read from <Folder>/recons/<address>.json, logs from <Folder>/logs/<address>.json,
+// and transactions, traces, receipts and appearances from the folders of the same names.
+// Each file may hold a JSON array, chifra's {"data": [...]} envelope, or one record per
+// line (.ndjson).
+type JsonSource struct {
+	Folder string
+}
+
+func (s *JsonSource) Statements(account types.Name) ([]*types.Statement, error) {
+	path := findExport(s.Folder, "recons", account, ".json", ".ndjson")
+	if path == "" {
+		return []*types.Statement{}, nil
+	}
+	return readJson[types.Statement](path)
+}
+
+func (s *JsonSource) Logs(account types.Name) ([]*types.Log, error) {
+	path := findExport(s.Folder, "logs", account, ".json", ".ndjson")
+	if path == "" {
+		return []*types.Log{}, nil
+	}
+	return readJson[types.Log](path)
+}
+
+func (s *JsonSource) Transactions(account types.Name) ([]*types.Transaction, error) {
+	path := findExport(s.Folder, "transactions", account, ".json", ".ndjson")
+	if path == "" {
+		return []*types.Transaction{}, nil
+	}
+	return readJson[types.Transaction](path)
+}
+
+func (s *JsonSource) Traces(account types.Name) ([]*types.Trace, error) {
+	path := findExport(s.Folder, "traces", account, ".json", ".ndjson")
+	if path == "" {
+		return []*types.Trace{}, nil
+	}
+	return readJson[types.Trace](path)
+}
+
+func (s *JsonSource) Receipts(account types.Name) ([]*types.Receipt, error) {
+	path := findExport(s.Folder, "receipts", account, ".json", ".ndjson")
+	if path == "" {
+		return []*types.Receipt{}, nil
+	}
+	return readJson[types.Receipt](path)
+}
+
+func (s *JsonSource) Appearances(account types.Name) ([]*types.Appearance, error) {
+	path := findExport(s.Folder, "appearances", account, ".json", ".ndjson")
+	if path == "" {
+		return []*types.Appearance{}, nil
+	}
+	return readJson[types.Appearance](path)
+}
+
+// findExport returns the first existing file for the account in the given sub-folder
+// trying each extension in order, or an empty string if there is none.
+func findExport(folder, sub string, account types.Name, exts ...string) string {
+	for _, addr := range []string{account.Address.Hex(), strings.ToLower(account.Address.Hex())} {
+		for _, ext := range exts {
+			path := filepath.Join(folder, sub, addr+ext)
+			if file.FileExists(path) {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+func readJson[T any](path string) ([]*T, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	first, err := peekNonSpace(reader)
+	if err == io.EOF {
+		return []*T{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(reader)
+	if first == '[' {
+		ret := []*T{}
+		if err := dec.Decode(&ret); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return ret, nil
+	}
+
+	ret := []*T{}
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: record %d: %w", path, len(ret)+1, err)
+		}
+
+		envelope := struct {
+			Data []*T `json:"data"`
+		}{}
+		if bytes.Contains(raw, []byte(`"data"`)) {
+			if err := json.Unmarshal(raw, &envelope); err == nil && envelope.Data != nil {
+				ret = append(ret, envelope.Data...)
+				continue
+			}
+		}
+
+		item := new(T)
+		if err := json.Unmarshal(raw, item); err != nil {
+			return nil, fmt.Errorf("%s: record %d: %w", path, len(ret)+1, err)
+		}
+		ret = append(ret, item)
+	}
+	return ret, nil
+}
+
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			if _, err := reader.ReadByte(); err != nil {
+				return 0, err
+			}
+		default:
+			return b[0], nil
+		}
+	}
+}
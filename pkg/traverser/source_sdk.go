@@ -0,0 +1,67 @@
+package traverser
+
+import (
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	sdk "github.com/TrueBlocks/trueblocks-sdk/v5"
+)
+
+// --------------------------------
+type SdkSource struct {
+	Chain string
+}
+
+func (s *SdkSource) Statements(account types.Name) ([]*types.Statement, error) {
+	opts := s.exportOptions(account)
+	statements, _, err := opts.ExportStatements()
+	return pointers(statements), err
+}
+
+func (s *SdkSource) Logs(account types.Name) ([]*types.Log, error) {
+	opts := s.exportOptions(account)
+	logs, _, err := opts.ExportLogs()
+	return pointers(logs), err
+}
+
+func (s *SdkSource) Transactions(account types.Name) ([]*types.Transaction, error) {
+	opts := s.exportOptions(account)
+	txs, _, err := opts.Export()
+	return pointers(txs), err
+}
+
+func (s *SdkSource) Traces(account types.Name) ([]*types.Trace, error) {
+	opts := s.exportOptions(account)
+	traces, _, err := opts.ExportTraces()
+	return pointers(traces), err
+}
+
+func (s *SdkSource) Receipts(account types.Name) ([]*types.Receipt, error) {
+	opts := s.exportOptions(account)
+	receipts, _, err := opts.ExportReceipts()
+	return pointers(receipts), err
+}
+
+func (s *SdkSource) Appearances(account types.Name) ([]*types.Appearance, error) {
+	opts := s.exportOptions(account)
+	apps, _, err := opts.ExportAppearances()
+	return pointers(apps), err
+}
+
+func (s *SdkSource) exportOptions(account types.Name) sdk.ExportOptions {
+	return sdk.ExportOptions{
+		Addrs:      []string{account.Address.Hex()},
+		Articulate: true,
+		Globals: sdk.Globals{
+			Cache: true,
+			Chain: s.Chain,
+		},
+	}
+}
+
+// pointers returns a pointer to each item.
+func pointers[T any](items []T) []*T {
+	ret := make([]*T, 0, len(items))
+	for i := range items {
+		ret = append(ret, &items[i])
+	}
+	return ret
+}
@@ -0,0 +1,60 @@
+package traverser
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/synth"
+)
+
+// --------------------------------
+// SynthSource generates the records of the accounts from a seed instead of reading them
+// (see package synth). The history is generated once, for all of the accounts together,
+// so that what one account sends to another shows up in the records of both.
+type SynthSource struct {
+	Config synth.Config
+	once   sync.Once
+	ledger *synth.Ledger
+}
+
+// NewSynthSource returns a source generating the history of the option's accounts from
+// the option's seed.
+func NewSynthSource(opts Options) *SynthSource {
+	return &SynthSource{Config: synth.Config{
+		Seed:     opts.Seed,
+		Accounts: slices.Collect(maps.Keys(opts.Accounts)),
+	}}
+}
+
+func (s *SynthSource) generate() *synth.Ledger {
+	s.once.Do(func() {
+		s.ledger = synth.Generate(s.Config)
+	})
+	return s.ledger
+}
+
+func (s *SynthSource) Statements(account types.Name) ([]*types.Statement, error) {
+	return slices.Clone(s.generate().Statements[account.Address]), nil
+}
+
+func (s *SynthSource) Logs(account types.Name) ([]*types.Log, error) {
+	return slices.Clone(s.generate().Logs[account.Address]), nil
+}
+
+func (s *SynthSource) Transactions(account types.Name) ([]*types.Transaction, error) {
+	return slices.Clone(s.generate().Transactions[account.Address]), nil
+}
+
+func (s *SynthSource) Traces(account types.Name) ([]*types.Trace, error) {
+	return slices.Clone(s.generate().Traces[account.Address]), nil
+}
+
+func (s *SynthSource) Receipts(account types.Name) ([]*types.Receipt, error) {
+	return slices.Clone(s.generate().Receipts[account.Address]), nil
+}
+
+func (s *SynthSource) Appearances(account types.Name) ([]*types.Appearance, error) {
+	return slices.Clone(s.generate().Appearances[account.Address]), nil
+}
@@ -0,0 +1,171 @@
+package traverser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+)
+
+var account = types.Name{Address: base.HexToAddress("0xf503017d7baf7fbc0fff7492b751025c6a78179b")}
+
+func writeExport(t *testing.T, folder, sub, name, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(folder, sub), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(folder, sub, name), []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJsonSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		ext      string
+		contents string
+	}{
+		{"Array", ".json", `[{"blockNumber": 1, "symbol": "ETH"}, {"blockNumber": 2, "symbol": "DAI"}]`},
+		{"Envelope", ".json", `{"data": [{"blockNumber": 1, "symbol": "ETH"}, {"blockNumber": 2, "symbol": "DAI"}]}`},
+		{"Lines", ".ndjson", "{\"blockNumber\": 1, \"symbol\": \"ETH\"}\n{\"blockNumber\": 2, \"symbol\": \"DAI\"}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folder := t.TempDir()
+			writeExport(t, folder, "recons", account.Address.Hex()+tt.ext, tt.contents)
+			src := &JsonSource{Folder: folder}
+			statements, err := src.Statements(account)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(statements) != 2 {
+				t.Fatalf("Statements failed: got %d, want 2", len(statements))
+			}
+			if statements[0].BlockNumber != 1 || statements[1].Symbol != "DAI" {
+				t.Errorf("Statements differ: got %d %s", statements[0].BlockNumber, statements[1].Symbol)
+			}
+		})
+	}
+}
+
+func TestJsonSourceMissing(t *testing.T) {
+	src := &JsonSource{Folder: t.TempDir()}
+	logs, err := src.Logs(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 0 {
+		t.Errorf("Logs failed: got %d, want 0", len(logs))
+	}
+}
+
+func TestJsonSourceTraces(t *testing.T) {
+	folder := t.TempDir()
+	writeExport(t, folder, "traces", account.Address.Hex()+".json",
+		`[{"blockNumber": 1, "type": "call", "action": {"callType": "delegatecall", "to": "0x0000000000000000000000000000000000000002"}}]`)
+	traces, err := (&JsonSource{Folder: folder}).Traces(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 1 || traces[0].Action == nil || traces[0].Action.CallType != "delegatecall" || traces[0].TraceType != "call" {
+		t.Errorf("Traces differ: got %v", traces)
+	}
+}
+
+func TestCsvSource(t *testing.T) {
+	folder := t.TempDir()
+	statements := "blockNumber,transactionIndex,asset,symbol,amountIn,spotPrice,date\n" +
+		"100,2,0x0000000000000000000000000000000000000001,ETH,1000,2000.5,2025-03-26\n"
+	writeExport(t, folder, "recons", account.Address.Hex()+".csv", statements)
+	logs := "blockNumber,logIndex,address,topic0,topic1,data\n" +
+		"100,3,0x0000000000000000000000000000000000000002,0x01,0x02,0x\n"
+	writeExport(t, folder, "logs", account.Address.Hex()+".csv", logs)
+
+	src := &CsvSource{Folder: folder}
+	stmts, err := src.Statements(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 1 {
+		t.Fatalf("Statements failed: got %d, want 1", len(stmts))
+	}
+	s := stmts[0]
+	if s.BlockNumber != 100 || s.TransactionIndex != 2 || s.Symbol != "ETH" {
+		t.Errorf("Statement differs: got %d.%d %s", s.BlockNumber, s.TransactionIndex, s.Symbol)
+	}
+	if s.Asset != base.HexToAddress("0x1") {
+		t.Errorf("Asset differs: got %s", s.Asset.Hex())
+	}
+	if s.AmountIn.Text(10) != "1000" {
+		t.Errorf("AmountIn differs: got %s", s.AmountIn.Text(10))
+	}
+	if s.SpotPrice.Float64() != 2000.5 {
+		t.Errorf("SpotPrice differs: got %f", s.SpotPrice.Float64())
+	}
+
+	ls, err := src.Logs(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ls) != 1 || len(ls[0].Topics) != 2 || ls[0].LogIndex != 3 {
+		t.Errorf("Logs differ: got %v", ls)
+	}
+}
+
+func TestCsvSourceBadValue(t *testing.T) {
+	folder := t.TempDir()
+	writeExport(t, folder, "recons", account.Address.Hex()+".csv", "blockNumber\nnot-a-number\n")
+	src := &CsvSource{Folder: folder}
+	if _, err := src.Statements(account); err == nil {
+		t.Error("expected an error for a malformed block number")
+	}
+}
+
+func TestNewSource(t *testing.T) {
+	if _, err := NewSource(Options{Source: "carrier-pigeon"}); err == nil {
+		t.Error("expected an error for an unknown source")
+	}
+	if src, err := NewSource(Options{Source: "csv", InputPath: "raw"}); err != nil {
+		t.Error(err)
+	} else if _, ok := src.(*CsvSource); !ok {
+		t.Errorf("NewSource failed: got %T, want *CsvSource", src)
+	}
+}
+
+func TestSynthSource(t *testing.T) {
+	other := types.Name{Address: base.HexToAddress("0x054993ab0f2b1acc0fdc65405ee203b4271bebe6")}
+	opts := Options{Source: "synthetic", Seed: 3, Accounts: map[base.Address]types.Name{account.Address: account, other.Address: other}}
+	src, err := NewSource(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// What one account sends the other shows up in the statements of both
+	statements, err := src.Statements(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received, err := src.Statements(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared := 0
+	for _, s := range statements {
+		for _, r := range received {
+			if s.TransactionHash == r.TransactionHash && s.Asset == r.Asset && s.LogIndex == r.LogIndex {
+				shared++
+			}
+		}
+	}
+	if len(statements) == 0 || shared == 0 {
+		t.Errorf("Statements failed: got %d statements, %d shared", len(statements), shared)
+	}
+
+	again, _ := NewSynthSource(opts).Statements(account)
+	if len(again) != len(statements) || again[len(again)-1].EndBal.String() != statements[len(statements)-1].EndBal.String() {
+		t.Error("Statements differ for the same seed")
+	}
+}
@@ -1,44 +1,59 @@
 package stats
 
 import (
-	"fmt"
 	"reflect"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
 // --------------------------------
 type Average struct {
-	Opts  traverser.Options
-	Value float64
+	Opts   traverser.Options
+	Values groups[mean]
+}
+
+// mean is the running sum and count of the samples of a group.
+type mean struct {
+	Count float64
 	Total float64
 }
 
-func (c *Average) Traverse(val float64) {
-	c.Value += 1
-	c.Total += val
+func init() {
+	register("average", "Averages the samples", func(opts traverser.Options) traverser.Traverser[traverser.Sample] {
+		return &Average{Opts: opts}
+	})
+}
+
+func (c *Average) Traverse(s traverser.Sample) error {
+	m := c.Values.of(s, c.Opts.Period)
+	m.Count += 1
+	m.Total += s.Value
+	return nil
 }
 
-func (c *Average) GetKey(unused float64) string {
-	return ""
+func (c *Average) GetKey(s traverser.Sample) string {
+	return s.Group
 }
 
-func (c *Average) Result() string {
-	if c.Value == 0 {
-		return c.Name() + "\n\t" + c.reportValue("Average: ", 0)
-	}
-	return c.Name() + "\n\t" + c.reportValue("Average: ", c.Total/c.Value)
+func (c *Average) Result() (*report.Table, error) {
+	return c.Values.table(traverser.TypeName(c), "Average", &c.Opts, func(m *mean) any {
+		if m.Count == 0 {
+			return 0.
+		}
+		return m.Total / m.Count
+	}), nil
 }
 
 func (c *Average) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *Average) Sort(array []float64) {
-	// Nothing to do
+func (c *Average) Order() traverser.Ordering {
+	return traverser.Unsorted
 }
 
-func (c *Average) reportValue(msg string, v float64) string {
-	return fmt.Sprintf("%s%f", msg, v)
+func (c *Average) State() any {
+	return &c.Values
 }
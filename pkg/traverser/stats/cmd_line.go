@@ -1,30 +1,21 @@
 package stats
 
 import (
-	"os"
-
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
-func GetTraversers(opts traverser.Options) []traverser.Traverser[float64] {
-	ret := make([]traverser.Traverser[float64], 0)
-	for _, a := range os.Args {
-		if a == "stats.counter" || a == "counters" || a == "stats" {
-			ret = append(ret, &Counter{Opts: opts})
-		}
-		if a == "total" || a == "stats" {
-			ret = append(ret, &Total{Opts: opts})
-		}
-		if a == "average" || a == "stats" {
-			ret = append(ret, &Average{Opts: opts})
-		}
-		if a == "min" || a == "stats" {
-			ret = append(ret, &Min{Opts: opts, Value: 4294967295.})
-		}
-		if a == "max" || a == "stats" {
-			ret = append(ret, &Max{Opts: opts})
-		}
-	}
+const Family = "stats"
+
+func init() {
+	traverser.RegisterFamily[traverser.Sample](Family, "statistics over a numeric field of the statements, logs, transactions or receipts (see --field)")
+}
 
-	return ret
+func register(name, description string, ctor func(opts traverser.Options) traverser.Traverser[traverser.Sample]) {
+	traverser.Register(traverser.Registration{
+		Name:        name,
+		Aliases:     []string{"stats." + name},
+		Groups:      []string{"all"},
+		Family:      Family,
+		Description: description,
+	}, ctor)
 }
@@ -1,39 +1,46 @@
 package stats
 
 import (
-	"fmt"
 	"reflect"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
 // --------------------------------
 type Counter struct {
-	Opts  traverser.Options
-	Value float64
+	Opts   traverser.Options
+	Values groups[uint64]
 }
 
-func (c *Counter) Traverse(val float64) {
-	c.Value += 1
+func init() {
+	register("counter", "Counts the samples", func(opts traverser.Options) traverser.Traverser[traverser.Sample] {
+		return &Counter{Opts: opts}
+	})
 }
 
-func (c *Counter) GetKey(unused float64) string {
-	return ""
+func (c *Counter) Traverse(s traverser.Sample) error {
+	*c.Values.of(s, c.Opts.Period) += 1
+	return nil
 }
 
-func (c *Counter) Result() string {
-	return c.Name() + "\n\t" + c.reportValue("Counter: ", c.Value)
+func (c *Counter) GetKey(s traverser.Sample) string {
+	return s.Group
+}
+
+func (c *Counter) Result() (*report.Table, error) {
+	return c.Values.table(traverser.TypeName(c), "Counter", &c.Opts, func(n *uint64) any { return *n }), nil
 }
 
 func (c *Counter) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *Counter) Sort(array []float64) {
-	// Nothing to do
+func (c *Counter) Order() traverser.Ordering {
+	return traverser.Unsorted
 }
 
-func (c *Counter) reportValue(msg string, v float64) string {
-	return fmt.Sprintf("%s%f", msg, v)
+func (c *Counter) State() any {
+	return &c.Values
 }
@@ -1,9 +1,13 @@
 package stats
 
 import (
+	"bytes"
 	"testing"
 
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
@@ -11,12 +15,11 @@ func TestCounter(t *testing.T) {
 	tests := []struct {
 		name       string
 		inputs     []float64
-		wantCount  float64
 		wantResult string
 	}{
-		{"Empty", []float64{}, 0, "stats.Counter\n\tCounter: 0.000000"},
-		{"Single", []float64{1.0}, 1, "stats.Counter\n\tCounter: 1.000000"},
-		{"Multiple", []float64{1.0, 2.0, 3.0}, 3, "stats.Counter\n\tCounter: 3.000000"},
+		{"Empty", []float64{}, "stats.Counter\n\nCounter\n0\n"},
+		{"Single", []float64{1.0}, "stats.Counter\n\nCounter\n1\n"},
+		{"Multiple", []float64{1.0, 2.0, 3.0}, "stats.Counter\n\nCounter\n3\n"},
 	}
 
 	colors.ColorsOff()
@@ -24,14 +27,64 @@ func TestCounter(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &Counter{Opts: traverser.Options{}}
 			for _, val := range tt.inputs {
-				c.Traverse(val)
+				c.Traverse(traverser.Sample{Value: val})
 			}
-			if c.Value != tt.wantCount {
-				t.Errorf("Traverse failed: got %f, want %f", c.Value, tt.wantCount)
+			if got := render(t, c); got != tt.wantResult {
+				t.Errorf("Result failed: got %q, want %q", got, tt.wantResult)
 			}
-			if got := c.Result(); got != tt.wantResult {
+		})
+	}
+}
+
+func TestGrouped(t *testing.T) {
+	alice, bob := base.HexToAddress("0x1"), base.HexToAddress("0x2")
+	opts := traverser.Options{
+		GroupBy: "account",
+		Names:   map[base.Address]types.Name{alice: {Address: alice, Name: "Alice"}},
+	}
+	samples := []traverser.Sample{{Value: 4, Group: bob.Hex()}, {Value: 1, Group: alice.Hex()}, {Value: 3, Group: alice.Hex()}}
+
+	tests := []struct {
+		name       string
+		traverser  traverser.Traverser[traverser.Sample]
+		wantResult string
+	}{
+		{"Counter", &Counter{Opts: opts}, "stats.Counter\n\nAccount,Name,Counter\n" + alice.Hex() + ",Alice,2\n" + bob.Hex() + ",Unknown,1\n"},
+		{"Total", &Total{Opts: opts}, "stats.Total\n\nAccount,Name,Total\n" + alice.Hex() + ",Alice,4\n" + bob.Hex() + ",Unknown,4\n"},
+		{"Average", &Average{Opts: opts}, "stats.Average\n\nAccount,Name,Average\n" + alice.Hex() + ",Alice,2\n" + bob.Hex() + ",Unknown,4\n"},
+		{"Min", &Min{Opts: opts}, "stats.Min\n\nAccount,Name,Min\n" + alice.Hex() + ",Alice,1\n" + bob.Hex() + ",Unknown,4\n"},
+		{"Max", &Max{Opts: opts}, "stats.Max\n\nAccount,Name,Max\n" + alice.Hex() + ",Alice,3\n" + bob.Hex() + ",Unknown,4\n"},
+	}
+
+	colors.ColorsOff()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, s := range samples {
+				tt.traverser.Traverse(s)
+			}
+			if got := render(t, tt.traverser); got != tt.wantResult {
 				t.Errorf("Result failed: got %q, want %q", got, tt.wantResult)
 			}
 		})
 	}
 }
+
+func TestMinWithoutSamples(t *testing.T) {
+	colors.ColorsOff()
+	if got, want := render(t, &Min{}), "stats.Min\n\nMin\n\n"; got != want {
+		t.Errorf("Result failed: got %q, want %q", got, want)
+	}
+}
+
+func render(t *testing.T, c traverser.Traverser[traverser.Sample]) string {
+	t.Helper()
+	var buf bytes.Buffer
+	table, err := c.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Render(&buf, "csv", table); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
@@ -0,0 +1,70 @@
+package stats
+
+import (
+	"math"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+func TestDistributions(t *testing.T) {
+	samples := func(values ...float64) []traverser.Sample {
+		ret := make([]traverser.Sample, 0, len(values))
+		for _, v := range values {
+			ret = append(ret, traverser.Sample{Value: v})
+		}
+		return ret
+	}
+	oneToTen := samples(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+
+	tests := []struct {
+		name       string
+		traverser  traverser.Traverser[traverser.Sample]
+		samples    []traverser.Sample
+		wantResult string
+	}{
+		{"StdDev", &StdDev{}, samples(2, 4, 4, 4, 5, 5, 7, 9), "stats.StdDev\n\nCount,Mean,Variance,StdDev\n8,5,4.571428571428571,2.138089935299395\n"},
+		{"StdDevSingle", &StdDev{}, samples(3), "stats.StdDev\n\nCount,Mean,Variance,StdDev\n1,3,,\n"},
+		{"StdDevEmpty", &StdDev{}, nil, "stats.StdDev\n\nCount,Mean,Variance,StdDev\n0,,,\n"},
+		{"Quantiles", &ExactQuantiles{}, oneToTen, "stats.ExactQuantiles\n\nCount,Median,P90,P99\n10,5.5,9.1,9.91\n"},
+		{"QuantilesEmpty", &ExactQuantiles{}, nil, "stats.ExactQuantiles\n\nCount,Median,P90,P99\n0,,,\n"},
+		{"Histogram", &Histogram{Opts: traverser.Options{Bins: 3}}, samples(0, 1, 2, 3, 4, 5, 6), "stats.Histogram\n\nFrom,To,Count\n0,2,2\n2,4,2\n4,6,3\n"},
+		{"HistogramSame", &Histogram{}, samples(5, 5), "stats.Histogram\n\nFrom,To,Count\n5,5,2\n"},
+		{"LogHistogram", &LogHistogram{}, samples(-50, 0, 0.5, 1, 9, 10, 1000, 1e18), "stats.LogHistogram\n\nFrom,To,Count\n-100,-10,1\n0,0,1\n0.1,1,1\n1,10,2\n10,100,1\n1000,10000,1\n1000000000000000000,10000000000000000000,1\n"},
+	}
+
+	colors.ColorsOff()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, s := range tt.samples {
+				tt.traverser.Traverse(s)
+			}
+			if got := render(t, tt.traverser); got != tt.wantResult {
+				t.Errorf("Result failed: got %q, want %q", got, tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestSketch(t *testing.T) {
+	var s sketch
+	if got := s.quantile(0.5); got != nil {
+		t.Errorf("quantile of nothing: got %v, want nil", got)
+	}
+
+	// Amounts spanning many orders of magnitude, of both signs
+	sorted := []float64{}
+	for i := -1000; i <= 3000; i++ {
+		v := math.Copysign(math.Pow(1.01, math.Abs(float64(i))), float64(i))
+		s.add(v)
+		sorted = append(sorted, v)
+	}
+	for _, q := range []float64{0, 0.1, 0.5, 0.9, 0.99, 1} {
+		want := quantile(sorted, q).(float64)
+		got := s.quantile(q).(float64)
+		if math.Abs(got-want) > SketchAccuracy*math.Abs(want) {
+			t.Errorf("quantile %v: got %v, want %v within %v%%", q, got, want, SketchAccuracy*100)
+		}
+	}
+}
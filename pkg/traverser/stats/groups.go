@@ -0,0 +1,138 @@
+package stats
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+// --------------------------------
+// groups holds an accumulator for each group of samples and, if bucketed by period, for
+// each period, keyed by its start. Ungrouped samples all fall in the empty group, and
+// samples not bucketed in the period starting at zero.
+type groups[A any] map[string]map[base.Timestamp]*A
+
+// of returns the accumulator of the sample's group and period, creating it if need be.
+func (g *groups[A]) of(s traverser.Sample, period string) *A {
+	if *g == nil {
+		*g = groups[A]{}
+	}
+	periods, ok := (*g)[s.Group]
+	if !ok {
+		periods = map[base.Timestamp]*A{}
+		(*g)[s.Group] = periods
+	}
+	start := base.Timestamp(0)
+	if period != "" {
+		start = base.Timestamp(periodStart(period, s.Time).Unix())
+	}
+	a, ok := periods[start]
+	if !ok {
+		a = new(A)
+		periods[start] = a
+	}
+	return a
+}
+
+// table reports the value of each group's accumulator in the named column (see rows).
+func (g groups[A]) table(name, column string, opts *traverser.Options, value func(a *A) any) *report.Table {
+	return g.rows(name, opts, []string{column}, func(a *A) [][]any {
+		return [][]any{{value(a)}}
+	})
+}
+
+// rows reports the rows of each group's accumulator under the given columns. Grouped
+// samples are reported by asset or account, with its name, in address order, or by
+// entity in the order of the entities' names. Ungrouped
+// samples are reported on their own, even if there were none. Samples bucketed by period
+// are reported for every period from the first to the last sample of any group, including
+// the periods without samples, so that every group has the same series of periods.
+func (g groups[A]) rows(name string, opts *traverser.Options, columns []string, rows func(a *A) [][]any) *report.Table {
+	if opts.Period != "" {
+		columns = append([]string{"Period"}, columns...)
+	}
+	keys := []string{""}
+	switch opts.GroupBy {
+	case "":
+	case "entity":
+		keys = slices.Sorted(maps.Keys(g))
+		columns = append([]string{"Entity"}, columns...)
+	default:
+		keys = slices.Sorted(maps.Keys(g))
+		columns = append([]string{strings.ToUpper(opts.GroupBy[:1]) + opts.GroupBy[1:], "Name"}, columns...)
+	}
+	periods := g.periods(opts.Period)
+
+	t := report.NewTable(name)
+	section := t.AddSection("", columns...)
+	for _, key := range keys {
+		for _, start := range periods {
+			a := g[key][start]
+			if a == nil {
+				a = new(A)
+			}
+			prefix := []any{}
+			switch opts.GroupBy {
+			case "":
+			case "entity":
+				prefix = append(prefix, key)
+			default:
+				addr := base.HexToAddress(key)
+				prefix = append(prefix, addr, opts.NameOf(addr))
+			}
+			if opts.Period != "" {
+				prefix = append(prefix, periodLabel(opts.Period, time.Unix(int64(start), 0).UTC()))
+			}
+			for _, row := range rows(a) {
+				section.Append(slices.Concat(prefix, row)...)
+			}
+		}
+	}
+	return t
+}
+
+// periods returns the start of every period from the first to the last one holding
+// samples, or the single period starting at zero if the samples are not bucketed.
+func (g groups[A]) periods(period string) []base.Timestamp {
+	if period == "" {
+		return []base.Timestamp{0}
+	}
+	first, last := base.Timestamp(math.MaxInt64), base.Timestamp(math.MinInt64)
+	for _, periods := range g {
+		for start := range periods {
+			first, last = min(first, start), max(last, start)
+		}
+	}
+	ret := []base.Timestamp{}
+	for t := time.Unix(int64(first), 0).UTC(); first <= last && base.Timestamp(t.Unix()) <= last; t = nextPeriod(period, t) {
+		ret = append(ret, base.Timestamp(t.Unix()))
+	}
+	return ret
+}
+
+// --------------------------------
+// extreme is the smallest or largest sample seen, if any.
+type extreme struct {
+	Value float64
+	Seen  bool
+}
+
+// keep replaces the value with the sample's if none was seen yet or if better says so.
+func (e *extreme) keep(v float64, better func(v, than float64) bool) {
+	if !e.Seen || better(v, e.Value) {
+		e.Value, e.Seen = v, true
+	}
+}
+
+func (e *extreme) result() any {
+	if !e.Seen {
+		return nil
+	}
+	return e.Value
+}
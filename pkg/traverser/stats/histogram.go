@@ -0,0 +1,169 @@
+package stats
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+var histogramColumns = []string{"From", "To", "Count"}
+
+// --------------------------------
+// Histogram counts the samples in bins of equal width spanning the smallest to the largest
+// sample. The bounds are only known at the end, so every sample is kept.
+type Histogram struct {
+	Opts   traverser.Options
+	Values groups[[]float64]
+}
+
+func init() {
+	register("histogram", "Counts the samples in bins of equal width (see --bins)", func(opts traverser.Options) traverser.Traverser[traverser.Sample] {
+		return &Histogram{Opts: opts}
+	})
+}
+
+func (c *Histogram) Traverse(s traverser.Sample) error {
+	values := c.Values.of(s, c.Opts.Period)
+	*values = append(*values, s.Value)
+	return nil
+}
+
+func (c *Histogram) GetKey(s traverser.Sample) string {
+	return s.Group
+}
+
+// Result reports one row per bin, each including its lower bound. The last bin also
+// includes its upper bound, the largest sample.
+func (c *Histogram) Result() (*report.Table, error) {
+	bins := cmp.Or(c.Opts.Bins, traverser.DefaultBins)
+	return c.Values.rows(traverser.TypeName(c), &c.Opts, histogramColumns, func(values *[]float64) [][]any {
+		if len(*values) == 0 {
+			return nil
+		}
+		lo, hi := slices.Min(*values), slices.Max(*values)
+		if lo == hi {
+			return [][]any{{lo, hi, uint64(len(*values))}}
+		}
+		width := (hi - lo) / float64(bins)
+		counts := make([]uint64, bins)
+		for _, v := range *values {
+			counts[min(int((v-lo)/width), bins-1)]++
+		}
+		ret := make([][]any, 0, bins)
+		for i, n := range counts {
+			to := lo + float64(i+1)*width
+			if i == bins-1 {
+				to = hi
+			}
+			ret = append(ret, []any{lo + float64(i)*width, to, n})
+		}
+		return ret
+	}), nil
+}
+
+func (c *Histogram) Name() string {
+	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
+}
+
+func (c *Histogram) Order() traverser.Ordering {
+	return traverser.Unsorted
+}
+
+func (c *Histogram) State() any {
+	return &c.Values
+}
+
+// --------------------------------
+// decades counts samples by the power of ten of their magnitude, positive and negative
+// samples separately.
+type decades struct {
+	Zeros uint64
+	Pos   map[int]uint64
+	Neg   map[int]uint64
+}
+
+func (d *decades) add(v float64) {
+	switch {
+	case v > 0:
+		if d.Pos == nil {
+			d.Pos = map[int]uint64{}
+		}
+		d.Pos[decadeOf(v)]++
+	case v < 0:
+		if d.Neg == nil {
+			d.Neg = map[int]uint64{}
+		}
+		d.Neg[decadeOf(-v)]++
+	default:
+		d.Zeros++
+	}
+}
+
+// decadeOf returns k such that 10^k <= v < 10^(k+1), correcting for the rounding of Log10.
+func decadeOf(v float64) int {
+	k := int(math.Floor(math.Log10(v)))
+	if math.Pow10(k+1) <= v {
+		k++
+	} else if math.Pow10(k) > v {
+		k--
+	}
+	return k
+}
+
+// --------------------------------
+// LogHistogram counts the samples by power of ten, which suits amounts spanning many
+// orders of magnitude, without keeping them.
+type LogHistogram struct {
+	Opts   traverser.Options
+	Values groups[decades]
+}
+
+func init() {
+	register("log_histogram", "Counts the samples by power of ten", func(opts traverser.Options) traverser.Traverser[traverser.Sample] {
+		return &LogHistogram{Opts: opts}
+	})
+}
+
+func (c *LogHistogram) Traverse(s traverser.Sample) error {
+	c.Values.of(s, c.Opts.Period).add(s.Value)
+	return nil
+}
+
+func (c *LogHistogram) GetKey(s traverser.Sample) string {
+	return s.Group
+}
+
+// Result reports the bins in ascending order, each including the bound nearer zero.
+func (c *LogHistogram) Result() (*report.Table, error) {
+	return c.Values.rows(traverser.TypeName(c), &c.Opts, histogramColumns, func(d *decades) [][]any {
+		ret := [][]any{}
+		for _, k := range slices.Backward(slices.Sorted(maps.Keys(d.Neg))) {
+			ret = append(ret, []any{-math.Pow10(k + 1), -math.Pow10(k), d.Neg[k]})
+		}
+		if d.Zeros > 0 {
+			ret = append(ret, []any{0., 0., d.Zeros})
+		}
+		for _, k := range slices.Sorted(maps.Keys(d.Pos)) {
+			ret = append(ret, []any{math.Pow10(k), math.Pow10(k + 1), d.Pos[k]})
+		}
+		return ret
+	}), nil
+}
+
+func (c *LogHistogram) Name() string {
+	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
+}
+
+func (c *LogHistogram) Order() traverser.Ordering {
+	return traverser.Unsorted
+}
+
+func (c *LogHistogram) State() any {
+	return &c.Values
+}
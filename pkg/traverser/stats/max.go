@@ -1,41 +1,46 @@
 package stats
 
 import (
-	"fmt"
 	"reflect"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
 // --------------------------------
 type Max struct {
-	Opts  traverser.Options
-	Value float64
+	Opts   traverser.Options
+	Values groups[extreme]
 }
 
-func (c *Max) Traverse(val float64) {
-	if val > c.Value {
-		c.Value = val
-	}
+func init() {
+	register("max", "Reports the largest sample", func(opts traverser.Options) traverser.Traverser[traverser.Sample] {
+		return &Max{Opts: opts}
+	})
 }
 
-func (c *Max) GetKey(unused float64) string {
-	return ""
+func (c *Max) Traverse(s traverser.Sample) error {
+	c.Values.of(s, c.Opts.Period).keep(s.Value, func(v, than float64) bool { return v > than })
+	return nil
 }
 
-func (c *Max) Result() string {
-	return c.Name() + "\n\t" + c.reportValue("Max: ", c.Value)
+func (c *Max) GetKey(s traverser.Sample) string {
+	return s.Group
+}
+
+func (c *Max) Result() (*report.Table, error) {
+	return c.Values.table(traverser.TypeName(c), "Max", &c.Opts, (*extreme).result), nil
 }
 
 func (c *Max) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *Max) Sort(array []float64) {
-	// Nothing to do
+func (c *Max) Order() traverser.Ordering {
+	return traverser.Unsorted
 }
 
-func (c *Max) reportValue(msg string, v float64) string {
-	return fmt.Sprintf("%s%f", msg, v)
+func (c *Max) State() any {
+	return &c.Values
 }
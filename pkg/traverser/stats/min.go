@@ -1,41 +1,46 @@
 package stats
 
 import (
-	"fmt"
 	"reflect"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
 // --------------------------------
 type Min struct {
-	Opts  traverser.Options
-	Value float64
+	Opts   traverser.Options
+	Values groups[extreme]
 }
 
-func (c *Min) Traverse(val float64) {
-	if val < c.Value {
-		c.Value = val
-	}
+func init() {
+	register("min", "Reports the smallest sample", func(opts traverser.Options) traverser.Traverser[traverser.Sample] {
+		return &Min{Opts: opts}
+	})
 }
 
-func (c *Min) GetKey(unused float64) string {
-	return ""
+func (c *Min) Traverse(s traverser.Sample) error {
+	c.Values.of(s, c.Opts.Period).keep(s.Value, func(v, than float64) bool { return v < than })
+	return nil
 }
 
-func (c *Min) Result() string {
-	return c.Name() + "\n\t" + c.reportValue("Min: ", c.Value)
+func (c *Min) GetKey(s traverser.Sample) string {
+	return s.Group
+}
+
+func (c *Min) Result() (*report.Table, error) {
+	return c.Values.table(traverser.TypeName(c), "Min", &c.Opts, (*extreme).result), nil
 }
 
 func (c *Min) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *Min) Sort(array []float64) {
-	// Nothing to do
+func (c *Min) Order() traverser.Ordering {
+	return traverser.Unsorted
 }
 
-func (c *Min) reportValue(msg string, v float64) string {
-	return fmt.Sprintf("%s%f", msg, v)
+func (c *Min) State() any {
+	return &c.Values
 }
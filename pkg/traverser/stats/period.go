@@ -0,0 +1,64 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+)
+
+// periodStart returns the start (in UTC) of the period holding the timestamp. Weeks start
+// on Monday. Samples carry no block number, so they cannot be bucketed blockly.
+func periodStart(period string, ts base.Timestamp) time.Time {
+	t := time.Unix(int64(ts), 0).UTC()
+	switch period {
+	case "hourly":
+		return t.Truncate(time.Hour)
+	case "daily":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case "weekly":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(t.Weekday())+6)%7)
+	case "monthly":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case "quarterly":
+		return time.Date(t.Year(), t.Month()-(t.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// nextPeriod returns the start of the period following the one starting at t.
+func nextPeriod(period string, t time.Time) time.Time {
+	switch period {
+	case "hourly":
+		return t.Add(time.Hour)
+	case "daily":
+		return t.AddDate(0, 0, 1)
+	case "weekly":
+		return t.AddDate(0, 0, 7)
+	case "monthly":
+		return t.AddDate(0, 1, 0)
+	case "quarterly":
+		return t.AddDate(0, 3, 0)
+	default:
+		return t.AddDate(1, 0, 0)
+	}
+}
+
+// periodLabel names the period starting at t (2024-06-10 13:00, 2024-06-10, 2024-06,
+// 2024-Q2 or 2024). A week is named by its Monday.
+func periodLabel(period string, t time.Time) string {
+	switch period {
+	case "hourly":
+		return t.Format("2006-01-02 15:04")
+	case "daily", "weekly":
+		return t.Format("2006-01-02")
+	case "monthly":
+		return t.Format("2006-01")
+	case "quarterly":
+		return fmt.Sprintf("%d-Q%d", t.Year(), (t.Month()+2)/3)
+	default:
+		return t.Format("2006")
+	}
+}
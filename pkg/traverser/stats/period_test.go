@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+func TestPeriods(t *testing.T) {
+	ts := base.Timestamp(time.Date(2024, 5, 15, 13, 45, 10, 0, time.UTC).Unix()) // a Wednesday
+	tests := []struct {
+		period    string
+		wantStart string
+		wantNext  string
+	}{
+		{"hourly", "2024-05-15 13:00", "2024-05-15 14:00"},
+		{"daily", "2024-05-15", "2024-05-16"},
+		{"weekly", "2024-05-13", "2024-05-20"},
+		{"monthly", "2024-05", "2024-06"},
+		{"quarterly", "2024-Q2", "2024-Q3"},
+		{"annually", "2024", "2025"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			start := periodStart(tt.period, ts)
+			if got := periodLabel(tt.period, start); got != tt.wantStart {
+				t.Errorf("start differs: got %s, want %s", got, tt.wantStart)
+			}
+			if got := periodLabel(tt.period, nextPeriod(tt.period, start)); got != tt.wantNext {
+				t.Errorf("next differs: got %s, want %s", got, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestBucketed(t *testing.T) {
+	month := func(m time.Month) base.Timestamp {
+		return base.Timestamp(time.Date(2024, m, 10, 0, 0, 0, 0, time.UTC).Unix())
+	}
+	alice, bob := base.HexToAddress("0x1"), base.HexToAddress("0x2")
+	names := map[base.Address]types.Name{alice: {Address: alice, Name: "Alice"}, bob: {Address: bob, Name: "Bob"}}
+	samples := []traverser.Sample{
+		{Value: 5, Group: alice.Hex(), Time: month(1)},
+		{Value: 2, Group: alice.Hex(), Time: month(1)},
+		{Value: 7, Group: bob.Hex(), Time: month(3)},
+	}
+	ungrouped := []traverser.Sample{{Value: 5, Time: month(1)}, {Value: 2, Time: month(1)}, {Value: 7, Time: month(3)}}
+
+	tests := []struct {
+		name       string
+		traverser  traverser.Traverser[traverser.Sample]
+		samples    []traverser.Sample
+		wantResult string
+	}{
+		{"Counter", &Counter{Opts: traverser.Options{Period: "monthly"}}, ungrouped,
+			"stats.Counter\n\nPeriod,Counter\n2024-01,2\n2024-02,0\n2024-03,1\n"},
+		{"Total", &Total{Opts: traverser.Options{Period: "quarterly"}}, ungrouped,
+			"stats.Total\n\nPeriod,Total\n2024-Q1,14\n"},
+		{"MinByAccount", &Min{Opts: traverser.Options{Period: "monthly", GroupBy: "account", Names: names}}, samples,
+			"stats.Min\n\nAccount,Name,Period,Min\n" +
+				alice.Hex() + ",Alice,2024-01,2\n" + alice.Hex() + ",Alice,2024-02,\n" + alice.Hex() + ",Alice,2024-03,\n" +
+				bob.Hex() + ",Bob,2024-01,\n" + bob.Hex() + ",Bob,2024-02,\n" + bob.Hex() + ",Bob,2024-03,7\n"},
+		{"TotalByEntity", &Total{Opts: traverser.Options{Period: "quarterly", GroupBy: "entity"}}, []traverser.Sample{
+			{Value: 5, Group: "Operations", Time: month(1)}, {Value: 7, Group: bob.Hex(), Time: month(3)}, {Value: 2, Group: "Operations", Time: month(2)},
+		}, "stats.Total\n\nEntity,Period,Total\n" + bob.Hex() + ",2024-Q1,7\nOperations,2024-Q1,7\n"},
+		{"Empty", &Counter{Opts: traverser.Options{Period: "monthly"}}, nil, "stats.Counter\n\nPeriod,Counter\n"},
+	}
+
+	colors.ColorsOff()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, s := range tt.samples {
+				tt.traverser.Traverse(s)
+			}
+			if got := render(t, tt.traverser); got != tt.wantResult {
+				t.Errorf("Result failed:\n got %q\nwant %q", got, tt.wantResult)
+			}
+		})
+	}
+}
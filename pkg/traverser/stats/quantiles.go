@@ -0,0 +1,91 @@
+package stats
+
+import (
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+// Quantiles are the quantiles reported by the quantiles and approx_quantiles traversers.
+var Quantiles = []struct {
+	Column string
+	Q      float64
+}{
+	{"Median", 0.5},
+	{"P90", 0.9},
+	{"P99", 0.99},
+}
+
+// quantileColumns returns the columns of a quantiles report.
+func quantileColumns() []string {
+	ret := []string{"Count"}
+	for _, q := range Quantiles {
+		ret = append(ret, q.Column)
+	}
+	return ret
+}
+
+// --------------------------------
+// ExactQuantiles keeps every sample, so its memory grows with the number of records. See
+// ApproxQuantiles for a bounded alternative.
+type ExactQuantiles struct {
+	Opts   traverser.Options
+	Values groups[[]float64]
+}
+
+func init() {
+	register("quantiles", "Reports the median, 90th and 99th percentiles of the samples", func(opts traverser.Options) traverser.Traverser[traverser.Sample] {
+		return &ExactQuantiles{Opts: opts}
+	})
+}
+
+func (c *ExactQuantiles) Traverse(s traverser.Sample) error {
+	values := c.Values.of(s, c.Opts.Period)
+	*values = append(*values, s.Value)
+	return nil
+}
+
+func (c *ExactQuantiles) GetKey(s traverser.Sample) string {
+	return s.Group
+}
+
+func (c *ExactQuantiles) Result() (*report.Table, error) {
+	return c.Values.rows(traverser.TypeName(c), &c.Opts, quantileColumns(), func(values *[]float64) [][]any {
+		sorted := slices.Sorted(slices.Values(*values))
+		row := []any{uint64(len(sorted))}
+		for _, q := range Quantiles {
+			row = append(row, quantile(sorted, q.Q))
+		}
+		return [][]any{row}
+	}), nil
+}
+
+func (c *ExactQuantiles) Name() string {
+	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
+}
+
+func (c *ExactQuantiles) Order() traverser.Ordering {
+	return traverser.Unsorted
+}
+
+func (c *ExactQuantiles) State() any {
+	return &c.Values
+}
+
+// quantile interpolates linearly between the closest ranks of the sorted values. It
+// returns nil if there are none.
+func quantile(sorted []float64, q float64) any {
+	if len(sorted) == 0 {
+		return nil
+	}
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	if lo+1 >= len(sorted) {
+		return sorted[lo]
+	}
+	return sorted[lo] + (pos-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
@@ -0,0 +1,126 @@
+package stats
+
+import (
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+// SketchAccuracy is the relative error of the quantiles reported by ApproxQuantiles.
+const SketchAccuracy = 0.01
+
+var sketchGamma = (1 + SketchAccuracy) / (1 - SketchAccuracy)
+
+// --------------------------------
+// sketch counts the samples in buckets whose bounds grow geometrically, so that any
+// quantile can be estimated within SketchAccuracy of its value (relative to the value)
+// while the number of buckets grows only with the logarithm of the range of the samples.
+// Positive and negative samples are counted separately by the bucket of their magnitude.
+type sketch struct {
+	Count uint64
+	Zeros uint64
+	Pos   map[int]uint64
+	Neg   map[int]uint64
+}
+
+func (s *sketch) add(v float64) {
+	s.Count++
+	switch {
+	case v > 0:
+		if s.Pos == nil {
+			s.Pos = map[int]uint64{}
+		}
+		s.Pos[bucketOf(v)]++
+	case v < 0:
+		if s.Neg == nil {
+			s.Neg = map[int]uint64{}
+		}
+		s.Neg[bucketOf(-v)]++
+	default:
+		s.Zeros++
+	}
+}
+
+// bucketOf returns the bucket holding magnitudes in (gamma^(i-1), gamma^i].
+func bucketOf(v float64) int {
+	return int(math.Ceil(math.Log(v) / math.Log(sketchGamma)))
+}
+
+// valueOf returns the estimate for the magnitudes in a bucket.
+func valueOf(i int) float64 {
+	return 2 * math.Pow(sketchGamma, float64(i)) / (sketchGamma + 1)
+}
+
+// quantile returns the estimate of the q quantile, or nil if there are no samples.
+func (s *sketch) quantile(q float64) any {
+	if s.Count == 0 {
+		return nil
+	}
+	rank := uint64(q * float64(s.Count-1))
+	seen := uint64(0)
+	// The most negative samples come first, so negative buckets are visited by descending magnitude
+	for _, i := range slices.Backward(slices.Sorted(maps.Keys(s.Neg))) {
+		if seen += s.Neg[i]; seen > rank {
+			return -valueOf(i)
+		}
+	}
+	if seen += s.Zeros; seen > rank {
+		return 0.
+	}
+	pos := slices.Sorted(maps.Keys(s.Pos))
+	for _, i := range pos {
+		if seen += s.Pos[i]; seen > rank {
+			return valueOf(i)
+		}
+	}
+	return valueOf(pos[len(pos)-1])
+}
+
+// --------------------------------
+// ApproxQuantiles estimates the quantiles in bounded memory (see sketch).
+type ApproxQuantiles struct {
+	Opts   traverser.Options
+	Values groups[sketch]
+}
+
+func init() {
+	register("approx_quantiles", "Estimates the median, 90th and 99th percentiles of the samples to within 1%", func(opts traverser.Options) traverser.Traverser[traverser.Sample] {
+		return &ApproxQuantiles{Opts: opts}
+	})
+}
+
+func (c *ApproxQuantiles) Traverse(s traverser.Sample) error {
+	c.Values.of(s, c.Opts.Period).add(s.Value)
+	return nil
+}
+
+func (c *ApproxQuantiles) GetKey(s traverser.Sample) string {
+	return s.Group
+}
+
+func (c *ApproxQuantiles) Result() (*report.Table, error) {
+	return c.Values.rows(traverser.TypeName(c), &c.Opts, quantileColumns(), func(s *sketch) [][]any {
+		row := []any{s.Count}
+		for _, q := range Quantiles {
+			row = append(row, s.quantile(q.Q))
+		}
+		return [][]any{row}
+	}), nil
+}
+
+func (c *ApproxQuantiles) Name() string {
+	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
+}
+
+func (c *ApproxQuantiles) Order() traverser.Ordering {
+	return traverser.Unsorted
+}
+
+func (c *ApproxQuantiles) State() any {
+	return &c.Values
+}
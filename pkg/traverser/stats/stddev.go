@@ -0,0 +1,71 @@
+package stats
+
+import (
+	"math"
+	"reflect"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+// --------------------------------
+type StdDev struct {
+	Opts   traverser.Options
+	Values groups[moments]
+}
+
+// moments is the running count, mean and sum of squared differences from the mean of the
+// samples of a group, updated with Welford's method so that no samples are kept.
+type moments struct {
+	Count float64
+	Mean  float64
+	M2    float64
+}
+
+func init() {
+	register("stddev", "Reports the mean, variance and standard deviation of the samples", func(opts traverser.Options) traverser.Traverser[traverser.Sample] {
+		return &StdDev{Opts: opts}
+	})
+}
+
+func (c *StdDev) Traverse(s traverser.Sample) error {
+	m := c.Values.of(s, c.Opts.Period)
+	m.Count += 1
+	delta := s.Value - m.Mean
+	m.Mean += delta / m.Count
+	m.M2 += delta * (s.Value - m.Mean)
+	return nil
+}
+
+func (c *StdDev) GetKey(s traverser.Sample) string {
+	return s.Group
+}
+
+// Result reports the sample variance, which needs at least two samples.
+func (c *StdDev) Result() (*report.Table, error) {
+	columns := []string{"Count", "Mean", "Variance", "StdDev"}
+	return c.Values.rows(traverser.TypeName(c), &c.Opts, columns, func(m *moments) [][]any {
+		if m.Count < 2 {
+			var mean any
+			if m.Count == 1 {
+				mean = m.Mean
+			}
+			return [][]any{{uint64(m.Count), mean, nil, nil}}
+		}
+		variance := m.M2 / (m.Count - 1)
+		return [][]any{{uint64(m.Count), m.Mean, variance, math.Sqrt(variance)}}
+	}), nil
+}
+
+func (c *StdDev) Name() string {
+	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
+}
+
+func (c *StdDev) Order() traverser.Ordering {
+	return traverser.Unsorted
+}
+
+func (c *StdDev) State() any {
+	return &c.Values
+}
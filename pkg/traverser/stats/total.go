@@ -1,39 +1,46 @@
 package stats
 
 import (
-	"fmt"
 	"reflect"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
 )
 
 // --------------------------------
 type Total struct {
-	Opts  traverser.Options
-	Value float64
+	Opts   traverser.Options
+	Values groups[float64]
 }
 
-func (c *Total) Traverse(val float64) {
-	c.Value += val
+func init() {
+	register("total", "Sums the samples", func(opts traverser.Options) traverser.Traverser[traverser.Sample] {
+		return &Total{Opts: opts}
+	})
 }
 
-func (c *Total) GetKey(unused float64) string {
-	return ""
+func (c *Total) Traverse(s traverser.Sample) error {
+	*c.Values.of(s, c.Opts.Period) += s.Value
+	return nil
 }
 
-func (c *Total) Result() string {
-	return c.Name() + "\n\t" + c.reportValue("Total: ", c.Value)
+func (c *Total) GetKey(s traverser.Sample) string {
+	return s.Group
+}
+
+func (c *Total) Result() (*report.Table, error) {
+	return c.Values.table(traverser.TypeName(c), "Total", &c.Opts, func(v *float64) any { return *v }), nil
 }
 
 func (c *Total) Name() string {
 	return colors.Green + reflect.TypeOf(c).Elem().String() + colors.Off
 }
 
-func (c *Total) Sort(array []float64) {
-	// Nothing to do
+func (c *Total) Order() traverser.Ordering {
+	return traverser.Unsorted
 }
 
-func (c *Total) reportValue(msg string, v float64) string {
-	return fmt.Sprintf("%s%f", msg, v)
+func (c *Total) State() any {
+	return &c.Values
 }
@@ -0,0 +1,13 @@
+package traces
+
+import (
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+const Family = "traces"
+
+func init() {
+	traverser.RegisterFamily[*types.Trace](Family, "reports over the traces of the transactions of each account")
+	traverser.RegisterCounter[*types.Trace](Family, "traces")
+}
@@ -0,0 +1,72 @@
+package traces
+
+import (
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+// --------------------------------
+// GroupBy counts the traces by type, recipient or error.
+type GroupBy = traverser.GroupCounter[*types.Trace, groupKey]
+
+// groupKey holds the type, the recipient, the function or the error of a trace, as the
+// way of counting has it.
+type groupKey struct {
+	Type     string       `json:"type"`
+	CallType string       `json:"callType"`
+	To       base.Address `json:"to"`
+	Name     string       `json:"name"`
+	Function string       `json:"function"`
+	IsError  bool         `json:"isError"`
+	Error    string       `json:"error"`
+}
+
+func init() {
+	traverser.RegisterGroupCounters(Family, "Traces",
+		traverser.CountBy[*types.Trace, groupKey]{
+			Name:        "by_type",
+			Description: "Counts the traces of each type and call type",
+			Columns:     []string{"Type", "Call Type"},
+			Key: func(opts *traverser.Options, r *types.Trace) groupKey {
+				return groupKey{Type: r.TraceType, CallType: action(r).CallType}
+			},
+			Row: func(k groupKey) []any { return []any{k.Type, k.CallType} },
+		},
+		traverser.CountBy[*types.Trace, groupKey]{
+			Name:        "by_to",
+			Description: "Counts the traces calling each address",
+			Columns:     []string{"To", "Name"},
+			Key: func(opts *traverser.Options, r *types.Trace) groupKey {
+				return groupKey{To: action(r).To, Name: opts.NameOf(action(r).To)}
+			},
+			Row: func(k groupKey) []any { return []any{k.To, k.Name} },
+		},
+		traverser.CountBy[*types.Trace, groupKey]{
+			Name:        "by_function",
+			Description: "Counts the traces calling each function",
+			Columns:     []string{"Function"},
+			Key: func(opts *traverser.Options, r *types.Trace) groupKey {
+				return groupKey{Function: traverser.FunctionName(r.ArticulatedTrace, action(r).Input)}
+			},
+			Row: func(k groupKey) []any { return []any{k.Function} },
+		},
+		traverser.CountBy[*types.Trace, groupKey]{
+			Name:        "by_error",
+			Description: "Counts the traces that succeeded and failed, by error",
+			Columns:     []string{"Is Error", "Error"},
+			Key: func(opts *traverser.Options, r *types.Trace) groupKey {
+				return groupKey{IsError: r.Error != "", Error: r.Error}
+			},
+			Row: func(k groupKey) []any { return []any{k.IsError, k.Error} },
+		},
+	)
+}
+
+// action returns the action of a trace, or an empty one if it has none.
+func action(r *types.Trace) *types.TraceAction {
+	if r.Action != nil {
+		return r.Action
+	}
+	return &types.TraceAction{}
+}
@@ -0,0 +1,13 @@
+package transactions
+
+import (
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+const Family = "transactions"
+
+func init() {
+	traverser.RegisterFamily[*types.Transaction](Family, "reports over the transactions of each account")
+	traverser.RegisterCounter[*types.Transaction](Family, "transactions")
+}
@@ -0,0 +1,52 @@
+package transactions
+
+import (
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+// --------------------------------
+// GroupBy counts the transactions by function, recipient or error status.
+type GroupBy = traverser.GroupCounter[*types.Transaction, groupKey]
+
+// groupKey holds the function, the recipient or the status of a transaction, as the way
+// of counting has it.
+type groupKey struct {
+	Function string       `json:"function"`
+	To       base.Address `json:"to"`
+	Name     string       `json:"name"`
+	IsError  bool         `json:"isError"`
+}
+
+func init() {
+	traverser.RegisterGroupCounters(Family, "Transactions",
+		traverser.CountBy[*types.Transaction, groupKey]{
+			Name:        "by_function",
+			Description: "Counts the transactions calling each function",
+			Columns:     []string{"Function"},
+			Key: func(opts *traverser.Options, r *types.Transaction) groupKey {
+				return groupKey{Function: traverser.FunctionName(r.ArticulatedTx, r.Input)}
+			},
+			Row: func(k groupKey) []any { return []any{k.Function} },
+		},
+		traverser.CountBy[*types.Transaction, groupKey]{
+			Name:        "by_to",
+			Description: "Counts the transactions sent to each address",
+			Columns:     []string{"To", "Name"},
+			Key: func(opts *traverser.Options, r *types.Transaction) groupKey {
+				return groupKey{To: r.To, Name: opts.NameOf(r.To)}
+			},
+			Row: func(k groupKey) []any { return []any{k.To, k.Name} },
+		},
+		traverser.CountBy[*types.Transaction, groupKey]{
+			Name:        "by_status",
+			Description: "Counts the transactions that succeeded and failed",
+			Columns:     []string{"Is Error"},
+			Key: func(opts *traverser.Options, r *types.Transaction) groupKey {
+				return groupKey{IsError: r.IsError}
+			},
+			Row: func(k groupKey) []any { return []any{k.IsError} },
+		},
+	)
+}
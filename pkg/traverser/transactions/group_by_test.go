@@ -0,0 +1,57 @@
+package transactions
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+func TestGroupBy(t *testing.T) {
+	dai := base.HexToAddress("0x6b175474e89094c44da98b954eedeac495271d0f")
+	opts := traverser.Options{Names: map[base.Address]types.Name{dai: {Name: "Dai"}}}
+	txs := []*types.Transaction{
+		{BlockNumber: 30, To: dai, Input: "0xa9059cbb0000", ArticulatedTx: &types.Function{Name: "transfer"}},
+		{BlockNumber: 10, To: dai, Input: "0x095ea7b30000", IsError: true},
+		{BlockNumber: 20, To: base.HexToAddress("0x1"), Input: "0x"},
+	}
+
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{"by_function", "Count,Function,First Block,Last Block\n2,transfer,20,30\n1,0x095ea7b3,10,10\n"},
+		{"by_to", "Count,To,Name,First Block,Last Block\n2,0x6b175474e89094c44da98b954eedeac495271d0f,Dai,10,30\n1,0x0000000000000000000000000000000000000001,Unknown,20,20\n"},
+		{"by_status", "Count,Is Error,First Block,Last Block\n2,false,20,30\n1,true,10,10\n"},
+	}
+
+	colors.ColorsOff()
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			regs, err := traverser.Resolve(Family, []string{tt.mode})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := regs[0].New(opts).(*GroupBy)
+			for _, tx := range txs {
+				c.Traverse(tx)
+			}
+			var buf bytes.Buffer
+			table, err := c.Result()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := report.Render(&buf, "csv", table); err != nil {
+				t.Fatal(err)
+			}
+			want := "transactions.GroupBy." + tt.mode + "\nNumber of Transactions: 3\nNumber of Groups: 2\n\n" + tt.want
+			if got := buf.String(); got != want {
+				t.Errorf("Result differs:\n got %q\nwant %q", got, want)
+			}
+		})
+	}
+}
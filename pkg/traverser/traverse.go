@@ -1,140 +1,232 @@
 package traverser
 
 import (
-	"errors"
+	"cmp"
+	"fmt"
 	"log"
-	"os"
-	"path/filepath"
-	"strconv"
-	"strings"
+	"reflect"
+	"slices"
+	"time"
 
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/file"
-	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/logger"
-	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/names"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/config"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/expr"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 )
 
 type Traversable interface {
-	float64 | int64 | *types.Statement | *types.Log
+	float64 | int64 | Sample | *types.Statement | *types.Log | *types.Transaction | *types.Trace | *types.Receipt | *types.Appearance
 }
 
+// Traverser accumulates the records it is fed and reports on them. An error returned by
+// Traverse concerns the one record, while an error returned by Result means there is no
+// report. What happens next depends on the Policy of the run.
 type Traverser[T Traversable] interface {
-	Traverse(t T)
+	Traverse(t T) error
 	GetKey(t T) string
-	Result() string
+	Result() (*report.Table, error)
 	Name() string
-	Sort(array []T)
+	Order() Ordering
+}
+
+// Initializer is implemented by traversers that must prepare (for example, open a file)
+// before the first record. A traverser that fails to initialize is fed no records.
+type Initializer interface {
+	Init() error
+}
+
+// TypeName returns the package qualified name of a traverser's type (e.g. accounting.Excel).
+func TypeName(t any) string {
+	return reflect.TypeOf(t).Elem().String()
 }
 
 type Options struct {
-	Period      string
-	Denom       string
-	Verbose     int
-	AddrFilters map[base.Address]bool
-	DateFilters []base.DateTime
-	Names       map[base.Address]types.Name
-	Accounts    map[base.Address]types.Name
+	Period        string
+	Denom         string
+	Verbose       int
+	Chain         string
+	Tags          []string
+	AddressesPath string
+	FiltersPath   string
+	OutputPath    string
+	Filters       Filters
+	Names         map[base.Address]types.Name
+	Accounts      map[base.Address]types.Name
+	Source        string
+	InputPath     string
+	Seed          uint64
+	Format        string
+	Parallel      bool
+	ConfigPath    string
+	Where         string
+	Entities      map[base.Address]string
+	Checkpoint    string
+	FailFast      bool
+	Progress      *Progress
+	ChainAccounts map[string]map[base.Address]types.Name
+	Field         string
+	Of            string
+	PerTx         bool
+	GroupBy       string
+	Bins          int
+	Watch         time.Duration
+	runChain      string
 }
 
-func GetOptions() Options {
-	ret := Options{}
-	if len(os.Args) > 1 {
-		for i, a := range os.Args {
-			if i > 0 {
-				if a == "--nocolor" {
-					colors.ColorsOff()
-				} else if a == "--verbose" {
-					ret.Verbose++
-				} else if a == "units" || a == "usd" || a == "wei" {
-					ret.Denom = a
-				} else if base.IsValidPeriod(a) {
-					ret.Period = a
-				}
-			}
+// DefaultTags are the account tags processed when neither the command line nor the
+// config file name any.
+var DefaultTags = []string{"00-Active", "11-Retired", "12-Empty", "14-Other", "17-Unused", "19-Dead"}
+
+// LoadOptions reads the config file (or, without one, the addresses and filters files)
+// and the names database for the chain into the options produced by the command line.
+// Values given on the command line take precedence over those in the config file.
+func LoadOptions(opts Options) (Options, error) {
+	cfg, err := loadConfig(opts)
+	if err != nil {
+		return opts, err
+	}
+
+	ret := opts
+	ret.Chain = cmp.Or(ret.Chain, cfg.Chain, "mainnet")
+	ret.Period = cmp.Or(ret.Period, cfg.Period)
+	ret.Denom = cmp.Or(ret.Denom, cfg.Denom)
+	ret.OutputPath = cmp.Or(ret.OutputPath, cfg.Output.Path)
+	ret.Format = cmp.Or(ret.Format, cfg.Output.Format, "csv")
+	if len(ret.Tags) == 0 {
+		ret.Tags = cfg.Tags
+	}
+	if len(ret.Tags) == 0 {
+		ret.Tags = DefaultTags
+	}
+
+	ret.ChainAccounts = make(map[string]map[base.Address]types.Name)
+	ret.Entities = make(map[base.Address]string)
+	for _, account := range cfg.Accounts {
+		name := types.Name{
+			Tags:    account.Tags,
+			Address: base.HexToAddress(account.Address),
+			Name:    account.Name,
+		}
+		if name.Tags < "20" {
+			name.IsCustom = true
+		}
+		chain := cmp.Or(account.Chain, ret.Chain)
+		if ret.ChainAccounts[chain] == nil {
+			ret.ChainAccounts[chain] = make(map[base.Address]types.Name)
+		}
+		ret.ChainAccounts[chain][name.Address] = name
+		if account.Entity != "" {
+			ret.Entities[name.Address] = account.Entity
 		}
 	}
+	log.Println(colors.Yellow+"Loaded", len(cfg.Accounts), "addresses...", colors.Off)
 
-	ret.Names, _ = names.LoadNamesMap("mainnet", types.Regular|types.Custom|types.Prefund, []string{})
-	ret.Names[base.HexToAddress("0x")] = types.Name{Name: "Creation/Mint"}
-	log.Println(colors.Yellow+"Loaded", len(ret.Names), "names...", colors.Off)
-	ret.Accounts = make(map[base.Address]types.Name)
+	ret.runChain = ret.Chain
+	ret.Accounts = ret.ChainAccounts[ret.Chain]
+	if ret.Accounts == nil {
+		ret.Accounts = make(map[base.Address]types.Name)
+	}
+	ret.Names = ret.loadNames()
 
-	rootFolder, _ := os.Getwd()
-	addressFn := filepath.Join(rootFolder, "addresses.csv")
-	if !file.FileExists(addressFn) {
-		log.Println(Usage("{0} not found.", addressFn))
-		os.Exit(0)
+	ret.Filters = Filters{
+		Assets:         addressSet(cfg.Filters.Assets),
+		Accounts:       addressSet(cfg.Filters.Accounts),
+		Counterparties: addressSet(cfg.Filters.Counterparties),
+		FirstBlock:     base.Blknum(cfg.Filters.FirstBlock),
+		LastBlock:      base.Blknum(cfg.Filters.LastBlock),
 	}
-	lines := file.AsciiFileToLines(addressFn)
-	for _, line := range lines {
-		if !strings.HasPrefix(line, "#") && len(line) > 0 {
-			parts := strings.Split(line, ",")
-			if len(parts) > 2 {
-				name := types.Name{}
-				name.Tags = parts[0]
-				name.Address = base.HexToAddress(parts[1])
-				name.Name = parts[2]
-				if name.Tags < "20" {
-					name.IsCustom = true
-				}
-				ret.Names[name.Address] = name
-				ret.Accounts[name.Address] = name
-			}
+	first, last := cfg.Filters.Range()
+	ret.Filters.FirstTs, ret.Filters.LastTs = base.Timestamp(first), base.Timestamp(last)
+	if where := cmp.Or(ret.Where, cfg.Filters.Where); where != "" {
+		if ret.Filters.Where, err = expr.Compile(where, ret.Names); err != nil {
+			return opts, fmt.Errorf("invalid expression %q: %w", where, err)
 		}
 	}
-	log.Println(colors.Yellow+"Loaded", len(lines), "addresses...", colors.Off)
+	log.Println(colors.Yellow+"Loaded", len(ret.Filters.Assets), "asset filters...", colors.Off)
+
+	return ret, nil
+}
 
-	filterFn := filepath.Join(rootFolder, "filters.csv")
-	if !file.FileExists(filterFn) {
-		log.Println(Usage("{0} not found.", filterFn))
-		os.Exit(0)
+// loadConfig reads the named config file, or the default one if it exists. Otherwise the
+// addresses and filters files are imported as before config files existed.
+func loadConfig(opts Options) (*config.Config, error) {
+	if opts.ConfigPath != "" {
+		return config.Load(opts.ConfigPath)
 	}
-	lines = file.AsciiFileToLines(filterFn)
-	if len(lines) > 0 {
-		ret.AddrFilters = make(map[base.Address]bool)
-		ret.DateFilters = make([]base.DateTime, 0, 2)
-		for _, line := range lines {
-			if strings.HasPrefix(line, "#") {
-				continue
-			} else if strings.HasPrefix(line, "0x") {
-				parts := strings.Split(line, ",")
-				if len(parts) != 2 {
-					log.Fatal("Invalid filter line: ", line)
-					os.Exit(1)
-				}
-				ret.AddrFilters[base.HexToAddress(parts[0])] = true
-			} else if strings.HasSuffix(line, "Date") {
-				if len(ret.DateFilters) == 2 {
-					logger.Fatal("At most two date filters are allowed:", line)
-				}
-				parts := strings.Split(line, ",")
-				if len(parts) != 2 {
-					log.Fatal("Invalid filter line: ", line)
-					os.Exit(1)
-				}
-				dt := base.DateTime{}
-				if err := dt.UnmarshalCSV(parts[0]); err != nil {
-					logger.Fatal("invalid date filter:", line, err)
-				}
-				ret.DateFilters = append(ret.DateFilters, dt)
-			} else {
-				logger.Fatal("Invalid filter line:", line)
-			}
-		}
+	if opts.AddressesPath == "" && opts.FiltersPath == "" && file.FileExists(config.DefaultPath) {
+		return config.Load(config.DefaultPath)
 	}
-	log.Println(colors.Yellow+"Loaded", len(ret.AddrFilters), "address filters...", colors.Off)
-	log.Println(colors.Yellow+"Loaded", len(ret.DateFilters), "date filters...", colors.Off)
+	return config.FromCSV(cmp.Or(opts.AddressesPath, "addresses.csv"), cmp.Or(opts.FiltersPath, "filters.csv"))
+}
 
+func addressSet(addrs []string) map[base.Address]bool {
+	ret := make(map[base.Address]bool, len(addrs))
+	for _, addr := range addrs {
+		ret[base.HexToAddress(addr)] = true
+	}
 	return ret
 }
 
-func Usage(msg string, values ...string) error {
-	ret := msg
-	for index, val := range values {
-		rep := "{" + strconv.FormatInt(int64(index), 10) + "}"
-		ret = strings.Replace(ret, rep, val, -1)
+// NameOf returns the name of an address, or Unknown if it has none.
+func (opts *Options) NameOf(addr base.Address) string {
+	return cmp.Or(opts.Names[addr].Name, "Unknown")
+}
+
+// EntityOf returns the entity an account belongs to (see config.Account), or the
+// account's address if it belongs to none, so that it is reported on its own.
+func (opts *Options) EntityOf(addr base.Address) string {
+	return cmp.Or(opts.Entities[addr], addr.Hex())
+}
+
+// FunctionName returns the name of an articulated function, or the four byte selector
+// of the input if it could not be articulated. Plain transfers of value have no input.
+func FunctionName(f *types.Function, input string) string {
+	switch {
+	case f != nil && f.Name != "":
+		return f.Name
+	case len(input) >= 10:
+		return input[:10]
+	default:
+		return "transfer"
+	}
+}
+
+// IsOfInterest returns true if the account's tag is one of the selected tags.
+func (opts *Options) IsOfInterest(tag string) bool {
+	return slices.Contains(opts.Tags, tag)
+}
+
+// AccountsOfInterest returns the accounts with one of the selected tags in the order of
+// their addresses.
+func (opts *Options) AccountsOfInterest() []types.Name {
+	ret := make([]types.Name, 0, len(opts.Accounts))
+	for _, account := range opts.Accounts {
+		if opts.IsOfInterest(account.Tags) {
+			ret = append(ret, account)
+		}
+	}
+	slices.SortFunc(ret, func(a, b types.Name) int {
+		return cmp.Compare(a.Address.Hex(), b.Address.Hex())
+	})
+	return ret
+}
+
+// RunOptions returns the options that decide which records a run reads and how they are
+// reported, as written with the results of the run (see the sql traversers).
+func (opts *Options) RunOptions() map[string]any {
+	accounts := []string{}
+	for _, account := range opts.AccountsOfInterest() {
+		accounts = append(accounts, account.Address.Hex())
+	}
+	return map[string]any{
+		"source":   opts.Source,
+		"period":   opts.Period,
+		"denom":    opts.Denom,
+		"tags":     opts.Tags,
+		"where":    opts.Where,
+		"accounts": accounts,
 	}
-	return errors.New(ret)
 }
@@ -1,32 +1,36 @@
 package traverser
 
 import (
-	"strconv"
 	"testing"
+
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 )
 
 type mockTraverser struct {
 	count int
 }
 
-func (m *mockTraverser) Traverse(t float64) {
+func (m *mockTraverser) Traverse(t float64) error {
 	m.count++
+	return nil
 }
 
 func (m *mockTraverser) GetKey(t float64) string {
 	return "test"
 }
 
-func (m *mockTraverser) Result() string {
-	return "Count: " + strconv.Itoa(m.count) // Fix: Use strconv.Itoa to convert int to string
+func (m *mockTraverser) Result() (*report.Table, error) {
+	t := report.NewTable("mockTraverser")
+	t.AddSection("", "Count").Append(m.count)
+	return t, nil
 }
 
 func (m *mockTraverser) Name() string {
 	return "MockTraverser"
 }
 
-func (m *mockTraverser) Sort(array []float64) {
-	// No-op for simplicity
+func (m *mockTraverser) Order() Ordering {
+	return Unsorted
 }
 
 func TestTraverserInterface(t *testing.T) {
@@ -40,13 +44,18 @@ func TestTraverserInterface(t *testing.T) {
 	if key := tr.GetKey(1.0); key != "test" {
 		t.Errorf("GetKey failed: got %s, want 'test'", key)
 	}
-	if result := tr.Result(); result != "Count: 2" {
-		t.Errorf("Result failed: got %q, want %q", result, "Count: 2")
+	table, err := tr.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := table.Sections[0].Rows[0][0]; result != 2 {
+		t.Errorf("Result failed: got %v, want 2", result)
 	}
 	if name := tr.Name(); name != "MockTraverser" {
 		t.Errorf("Name failed: got %s, want 'MockTraverser'", name)
 	}
-	// Sort is no-op, so just call it to ensure no panic
-	tr.Sort([]float64{1.0, 2.0})
+	if order := tr.Order(); order != Unsorted {
+		t.Errorf("Order failed: got %s, want unsorted", order)
+	}
 }
 
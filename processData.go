@@ -1,128 +1,255 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"iter"
+	"os"
+	"reflect"
 	"slices"
 
-	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
 	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
-	sdk "github.com/TrueBlocks/trueblocks-sdk/v5"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
 	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
-	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser/accounting"
-	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser/logs"
-	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser/stats"
 )
 
-func processData() {
-	opts := traverser.GetOptions()
-	statTraversers := stats.GetTraversers(opts)
-	reconTraversers := accounting.GetTraversers(opts)
-	logTraversers := logs.GetTraversers(opts)
-	sorted := map[string]bool{}
+func processData(cmd *command) error {
+	r, err := newRunner(cmd, traverser.NewSource)
+	if err != nil {
+		return err
+	}
+	return r.write(r.pass())
+}
+
+// --------------------------------
+// runner runs the traversers of a command over the accounts of every chain. It keeps the
+// traversers, and the sources of their records, from one pass to the next, so that each
+// pass after the first (see watch) feeds them only the records that are new.
+type runner struct {
+	opts   traverser.Options
+	regs   []traverser.Registration
+	chains []string
+	runs   []*chainRun
+}
+
+// chainRun runs the traversers over the records of the accounts on one chain. The tables
+// of a pass are in the order of the registrations, with nil for those that failed.
+type chainRun struct {
+	opts   *traverser.Options
+	source *newsSource
+	seen   map[base.Address]base.Blknum
+	pass   func() ([]*report.Table, error)
+}
 
-	statements, err := getStatements(&opts)
+func newRunner(cmd *command, newSource func(traverser.Options) (traverser.Source, error)) (*runner, error) {
+	opts, err := traverser.LoadOptions(cmd.Opts)
 	if err != nil {
-		log.Fatalf("Error in getStatements: %v", err)
+		return nil, err
 	}
-	for _, stmt := range statements {
-		for _, a := range statTraversers {
-			a.Traverse(float64(stmt.BlockNumber))
-		}
-		for _, a := range reconTraversers {
-			if !sorted[a.Name()] {
-				a.Sort(statements)
-				sorted[a.Name()] = true
-			}
-			a.Traverse(stmt)
+
+	// The stats traversers are fed samples of the records chosen with --of that pass the filters
+	fam, _ := traverser.LookupFamily(cmd.Family)
+	filtered := fam.Consumes
+	if filtered == reflect.TypeFor[traverser.Sample]() {
+		switch opts.Of {
+		case "logs":
+			filtered = reflect.TypeFor[*types.Log]()
+		case "transactions":
+			filtered = reflect.TypeFor[*types.Transaction]()
+		case "receipts":
+			filtered = reflect.TypeFor[*types.Receipt]()
+		default:
+			filtered = reflect.TypeFor[*types.Statement]()
 		}
 	}
+	if err := opts.Filters.Check(filtered); err != nil {
+		return nil, err
+	}
 
-	logs, err := getLogs(&opts)
-	if err != nil {
-		log.Fatalf("Error in getLogs: %v", err)
+	// Each chain is processed on its own, and the reports of every chain merged
+	r := &runner{opts: opts, regs: cmd.Registrations, chains: opts.Chains()}
+	for _, chain := range r.chains {
+		chainOpts, err := opts.ForChain(chain)
+		if err != nil {
+			return nil, err
+		}
+		if chainOpts.Checkpoint != "" || chainOpts.Watch > 0 {
+			chainOpts.Progress = traverser.NewProgress()
+		}
+		source, err := newSource(chainOpts)
+		if err != nil {
+			return nil, err
+		}
+		run := &chainRun{opts: &chainOpts, source: &newsSource{Source: source}, seen: map[base.Address]base.Blknum{}}
+		if run.pass, err = newPass(fam, r.regs, run.opts, run.source); err != nil {
+			return nil, err
+		}
+		r.runs = append(r.runs, run)
 	}
-	for _, l := range logs {
-		for _, a := range logTraversers {
-			if !sorted[a.Name()] {
-				a.Sort(logs)
-				sorted[a.Name()] = true
+	return r, nil
+}
+
+// pass runs the traversers of every chain and merges their tables.
+func (r *runner) pass() ([]*report.Table, error) {
+	results := make([][]*report.Table, len(r.regs))
+	errs := []error{}
+	for c, run := range r.runs {
+		tables, err := run.pass()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.chains[c], err))
+		}
+		for i, table := range tables {
+			if results[i] == nil {
+				results[i] = make([]*report.Table, len(r.chains))
 			}
-			a.Traverse(l)
+			results[i][c] = table
 		}
 	}
 
-	for _, a := range statTraversers {
-		fmt.Println(a.Result())
+	tables := make([]*report.Table, 0, len(results))
+	for _, perChain := range results {
+		if table := report.Merge("Chain", r.chains, perChain); table != nil {
+			tables = append(tables, table)
+		}
 	}
+	return tables, errors.Join(errs...)
+}
 
-	for _, a := range reconTraversers {
-		fmt.Println(a.Result())
+// write renders the tables to the output file, which is replaced only once completely
+// written, or to stdout. What the healthy traversers produced is written even if others
+// failed, and err is returned along with any error writing it.
+func (r *runner) write(tables []*report.Table, err error) error {
+	render := func(w io.Writer) error {
+		return report.Render(w, r.opts.Format, tables...)
 	}
-
-	for _, a := range logTraversers {
-		fmt.Println(a.Result())
+	var wErr error
+	if r.opts.OutputPath != "" {
+		wErr = report.WriteFile(r.opts.OutputPath, render)
+	} else {
+		wErr = render(os.Stdout)
 	}
+	if wErr != nil {
+		return errors.Join(err, wErr)
+	}
+	return err
 }
 
-func getStatements(opts *traverser.Options) ([]*types.Statement, error) {
-	ret := make([]*types.Statement, 0, 2000)
-
-	for _, account := range opts.Accounts {
-		if !isOfInterest(account.Tags) {
-			continue
-		}
-		log.Println(colors.Yellow+"Fetching statements for", account.Address.Hex(), account.Tags, account.Name, colors.Off)
-		exportOptions := sdk.ExportOptions{
-			Addrs:      []string{account.Address.Hex()},
-			Articulate: true,
-			Globals: sdk.Globals{
-				Cache: true,
-			},
-		}
-		if statements, _, err := exportOptions.ExportStatements(); err != nil {
-			log.Fatalf("Error in export statements: %v", err)
-			return nil, err
-		} else {
-			for _, s := range statements {
-				ret = append(ret, &s)
+// newPass returns a pass of the traversers over the records of the accounts on one chain.
+func newPass(fam traverser.Family, regs []traverser.Registration, opts *traverser.Options, source traverser.Source) (func() ([]*report.Table, error), error) {
+	stage := traverser.NewFilterStage(opts.Filters)
+	switch fam.Consumes {
+	case reflect.TypeFor[traverser.Sample]():
+		return traverse(regs, opts, func() (iter.Seq2[traverser.Sample, error], error) {
+			switch opts.Of {
+			case "logs":
+				return traverser.Samples(opts, traverser.Logs(source, opts), stage)
+			case "transactions":
+				return traverser.Samples(opts, traverser.Transactions(source, opts), stage)
+			case "receipts":
+				return traverser.Samples(opts, traverser.Receipts(source, opts), stage)
+			default:
+				return traverser.Samples(opts, traverser.Statements(source, opts), stage)
 			}
-		}
-	}
+		}, stage), nil
+
+	case reflect.TypeFor[*types.Statement]():
+		return traverse(regs, opts, stream(traverser.Statements(source, opts)), stage), nil
+
+	case reflect.TypeFor[*types.Log]():
+		return traverse(regs, opts, stream(traverser.Logs(source, opts)), stage), nil
+
+	case reflect.TypeFor[*types.Transaction]():
+		return traverse(regs, opts, stream(traverser.Transactions(source, opts)), stage), nil
 
-	log.Println(colors.Yellow+"Loaded", len(ret), "statements", colors.Off)
-	return ret, nil
+	case reflect.TypeFor[*types.Trace]():
+		return traverse(regs, opts, stream(traverser.Traces(source, opts)), stage), nil
+
+	case reflect.TypeFor[*types.Receipt]():
+		return traverse(regs, opts, stream(traverser.Receipts(source, opts)), stage), nil
+
+	case reflect.TypeFor[*types.Appearance]():
+		return traverse(regs, opts, stream(traverser.Appearances(source, opts)), stage), nil
+
+	default:
+		return nil, fmt.Errorf("%s: no data is loaded for %s records", fam.Name, fam.Consumes)
+	}
 }
 
-func getLogs(opts *traverser.Options) ([]*types.Log, error) {
-	ret := make([]*types.Log, 0, 100)
+// stream returns the records for each pass. Sources are queried anew every time the
+// records are iterated.
+func stream[T traverser.Traversable](records iter.Seq2[T, error]) func() (iter.Seq2[T, error], error) {
+	return func() (iter.Seq2[T, error], error) {
+		return records, nil
+	}
+}
 
-	for _, account := range opts.Accounts {
-		if !isOfInterest(account.Tags) {
-			continue
+// traverse returns a pass running the traversers over the records that pass the filter
+// stage, sequentially or each on its own goroutine, and returning their tables, with nil
+// for those that failed. The first pass instantiates the traversers, resuming them from
+// the checkpoint if there is one, and later passes feed the same traversers the records
+// traversed by none of the passes before. The checkpoint is rewritten after every pass in
+// which no traverser failed.
+func traverse[T traverser.Traversable](regs []traverser.Registration, opts *traverser.Options, records func() (iter.Seq2[T, error], error), stage *traverser.FilterStage) func() ([]*report.Table, error) {
+	fingerprint := traverser.Fingerprint(regs, opts)
+	var traversers []traverser.Traverser[T]
+	var failed []*traverser.Failure
+	return func() ([]*report.Table, error) {
+		stream, err := records()
+		if err != nil {
+			return nil, err
 		}
-		log.Println(colors.Yellow+"Fetching logs for", account.Address.Hex(), account.Name, colors.Off)
-		exportOptions := sdk.ExportOptions{
-			Addrs:      []string{account.Address.Hex()},
-			Articulate: true,
-			Globals: sdk.Globals{
-				Cache: true,
-			},
+
+		if traversers == nil {
+			instances, err := traverser.Instantiate[T](regs, *opts)
+			if err != nil {
+				return nil, err
+			}
+			if opts.Checkpoint != "" {
+				cp, err := traverser.LoadCheckpoint(opts.Checkpoint)
+				if err != nil {
+					return nil, err
+				}
+				if _, err := traverser.Resume(cp, fingerprint, opts, regs, instances, opts.Progress); err != nil {
+					return nil, err
+				}
+			}
+			traversers = instances
+			if opts.Parallel {
+				failed, err = traverser.RunParallel(stream, traversers, stage, traverser.ChannelDepth, opts.Policy())
+			} else {
+				failed, err = traverser.Run(stream, traversers, stage, opts.Policy())
+			}
+		} else if opts.Parallel {
+			err = traverser.FeedParallel(stream, traversers, failed, stage, traverser.ChannelDepth, opts.Policy())
+		} else {
+			err = traverser.Feed(stream, traversers, failed, stage, opts.Policy())
 		}
-		logs, _, err := exportOptions.ExportLogs()
+		stage.Log()
 		if err != nil {
-			log.Fatalf("Error in export logs: %v", err)
-		}
-		for _, l := range logs {
-			ret = append(ret, &l)
+			return nil, err
 		}
-	}
+		opts.Progress.Advance()
 
-	log.Println(colors.Yellow+"Loaded", len(ret), "logs", colors.Off)
-	return ret, nil
-}
+		if opts.Checkpoint != "" && !slices.ContainsFunc(failed, func(f *traverser.Failure) bool { return f != nil }) {
+			if err := traverser.SaveCheckpoint(opts.Checkpoint, fingerprint, regs, traversers, opts.Progress); err != nil {
+				return nil, err
+			}
+		}
 
-func isOfInterest(tag string) bool {
-	tags := []string{"00-Active", "11-Retired", "12-Empty", "14-Other", "17-Unused", "19-Dead"}
-	return slices.Contains(tags, tag)
+		// A traverser that returned errors may still have a result
+		tables := make([]*report.Table, len(traversers))
+		errs := []error{}
+		for i, t := range traversers {
+			table, err := traverser.Results([]traverser.Traverser[T]{t}, failed[i:i+1])
+			if len(table) > 0 {
+				tables[i] = table[0]
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return tables, errors.Join(errs...)
+	}
 }
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+func TestFilterSummary(t *testing.T) {
+	withoutNames(t)
+	var buf bytes.Buffer
+	w := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(w) })
+
+	// Every family reports what its filters dropped
+	for _, args := range [][]string{
+		{"recons", "counter"},
+		{"logs", "counter"},
+		{"stats", "counter"},
+		{"stats", "counter", "--of", "transactions"},
+	} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			buf.Reset()
+			cmd, err := parseArgs(append(args,
+				"--config", filepath.Join("testdata", "config.yaml"),
+				"--source", "json",
+				"--input", filepath.Join("testdata", "input"),
+				"--output", filepath.Join(t.TempDir(), "report.csv"),
+				"--where", "blockNumber < 1",
+				"--nocolor",
+			))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := processData(cmd); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); !strings.Contains(got, "Filter where dropped") || !strings.Contains(got, "Kept 0 of") {
+				t.Errorf("got log %q, want the records the where filter dropped", got)
+			}
+		})
+	}
+}
+
+func TestEveryTraverserCheckpoints(t *testing.T) {
+	for _, r := range traverser.Registrations("") {
+		t.Run(r.Family+"/"+r.Name, func(t *testing.T) {
+			c, ok := r.New(traverser.Options{}).(traverser.Checkpointer)
+			if !ok {
+				if r.Name == "by_function_by_account" {
+					t.Skip("combinators cannot be checkpointed")
+				}
+				t.Fatal("cannot be checkpointed, so no run including it can be")
+			}
+			data, err := json.Marshal(c.State())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(data, r.New(traverser.Options{}).(traverser.Checkpointer).State()); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
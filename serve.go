@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/report"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+// DefaultAddr is where the server listens if --addr is not given.
+const DefaultAddr = "localhost:8080"
+
+// runParams are the query parameters of /run and /results, passed on as the flags of the
+// same names. Every other option is the server's.
+var runParams = []string{"period", "denom", "where", "tags", "field", "of", "per-tx", "group-by", "bins"}
+
+// serve answers requests on the address chosen with --addr until the context is done.
+func serve(ctx context.Context, cmd *command) error {
+	colors.ColorsOff()
+	srv := &http.Server{Addr: cmd.Addr, Handler: newServer(cmd.Opts, traverser.NewSource)}
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+	log.Println("Serving on http://" + cmd.Addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// --------------------------------
+// server runs traversers on request over the records of the source and options it was
+// started with, and keeps the result of each distinct request. Each run reads its own
+// source into its own traversers, so runs proceed side by side; only the results kept are
+// shared. Traversers that write a file are refused, lest a request write to the server's
+// working directory.
+//
+//	GET /traversers                       every registered traverser
+//	GET /run/{family}/{name}?period=...   run a traverser (or group) and keep its result
+//	GET /results                          the results kept so far
+//	GET /results/{family}/{name}?...      the result kept for the same request
+type server struct {
+	opts      traverser.Options
+	newSource func(traverser.Options) (traverser.Source, error)
+	mutex     sync.Mutex
+	results   map[string]*result
+}
+
+// result is the outcome of a run, kept for later requests.
+type result struct {
+	Family string     `json:"family"`
+	Name   string     `json:"name"`
+	Params url.Values `json:"params"`
+	Ran    time.Time  `json:"ran"`
+	body   []byte
+}
+
+func newServer(opts traverser.Options, newSource func(traverser.Options) (traverser.Source, error)) http.Handler {
+	s := &server{opts: opts, newSource: newSource, results: map[string]*result{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /traversers", s.traversers)
+	mux.HandleFunc("GET /run/{family}/{name}", s.run)
+	mux.HandleFunc("GET /results", s.list)
+	mux.HandleFunc("GET /results/{family}/{name}", s.cached)
+	return mux
+}
+
+// registration describes a traverser in /traversers.
+type registration struct {
+	Family      string   `json:"family"`
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases"`
+	Groups      []string `json:"groups"`
+	Consumes    string   `json:"consumes"`
+	Description string   `json:"description"`
+	Writes      string   `json:"writes,omitempty"`
+}
+
+func (s *server) traversers(w http.ResponseWriter, r *http.Request) {
+	ret := []registration{}
+	for _, reg := range traverser.Registrations(r.URL.Query().Get("family")) {
+		ret = append(ret, registration{
+			Family:      reg.Family,
+			Name:        reg.Name,
+			Aliases:     append([]string{}, reg.Aliases...),
+			Groups:      append([]string{}, reg.Groups...),
+			Consumes:    reg.Consumes.String(),
+			Description: reg.Description,
+			Writes:      reg.Writes,
+		})
+	}
+	writeJson(w, http.StatusOK, ret)
+}
+
+func (s *server) run(w http.ResponseWriter, r *http.Request) {
+	family, name := r.PathValue("family"), r.PathValue("name")
+	if _, ok := traverser.LookupFamily(family); !ok {
+		writeError(w, http.StatusNotFound, errors.New("unknown family "+family))
+		return
+	}
+	params, err := queryParams(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	args := []string{family, name}
+	for _, key := range slices.Sorted(maps.Keys(params)) {
+		for _, v := range params[key] {
+			args = append(args, "--"+key+"="+v)
+		}
+	}
+	cmd, err := parseArgs(args)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	for _, reg := range cmd.Registrations {
+		if reg.Writes != "" {
+			writeError(w, http.StatusForbidden, errors.New(reg.Family+" "+reg.Name+" writes "+reg.Writes+" and cannot be run by the server"))
+			return
+		}
+	}
+
+	// What to read, and from where, is the server's to say. The options not given in the
+	// request are those the server was started with.
+	opts := s.opts
+	for key := range params {
+		switch key {
+		case "period":
+			opts.Period = cmd.Opts.Period
+		case "denom":
+			opts.Denom = cmd.Opts.Denom
+		case "where":
+			opts.Where = cmd.Opts.Where
+		case "tags":
+			opts.Tags = cmd.Opts.Tags
+		case "field":
+			opts.Field = cmd.Opts.Field
+		case "of":
+			opts.Of = cmd.Opts.Of
+		case "per-tx":
+			opts.PerTx = cmd.Opts.PerTx
+		case "group-by":
+			opts.GroupBy = cmd.Opts.GroupBy
+		case "bins":
+			opts.Bins = cmd.Opts.Bins
+		}
+	}
+	opts.OutputPath, opts.Checkpoint, opts.Watch = "", "", 0
+	cmd.Opts = opts
+
+	runner, err := newRunner(cmd, s.newSource)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	tables, err := runner.pass()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := report.Render(&buf, "json", tables...); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.mutex.Lock()
+	s.results[resultKey(family, name, params)] = &result{Family: family, Name: name, Params: params, Ran: time.Now().UTC(), body: buf.Bytes()}
+	s.mutex.Unlock()
+	writeBody(w, buf.Bytes())
+}
+
+func (s *server) list(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	ret := make([]*result, 0, len(s.results))
+	for _, res := range s.results {
+		ret = append(ret, res)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return resultKey(ret[i].Family, ret[i].Name, ret[i].Params) < resultKey(ret[j].Family, ret[j].Name, ret[j].Params)
+	})
+	writeJson(w, http.StatusOK, ret)
+}
+
+func (s *server) cached(w http.ResponseWriter, r *http.Request) {
+	family, name := r.PathValue("family"), r.PathValue("name")
+	params, err := queryParams(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.mutex.Lock()
+	res, ok := s.results[resultKey(family, name, params)]
+	s.mutex.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("no result for this request (run it with /run first)"))
+		return
+	}
+	writeBody(w, res.body)
+}
+
+// queryParams returns the parameters of a run, refusing those that are not run options.
+func queryParams(query url.Values) (url.Values, error) {
+	for key := range query {
+		if !slices.Contains(runParams, key) {
+			return nil, errors.New("unknown parameter " + key + " (one of " + strings.Join(runParams, ", ") + ")")
+		}
+	}
+	return query, nil
+}
+
+// resultKey identifies a request, whatever the order of its parameters.
+func resultKey(family, name string, params url.Values) string {
+	return family + "/" + name + "?" + params.Encode()
+}
+
+func writeBody(w http.ResponseWriter, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+func writeJson(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+func TestServe(t *testing.T) {
+	withoutNames(t)
+
+	cmd, err := parseArgs([]string{"serve", "--config", filepath.Join("testdata", "config.yaml"), "--source", "json", "--input", filepath.Join("testdata", "input"), "--period", "monthly", "--nocolor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(newServer(cmd.Opts, traverser.NewSource))
+	defer srv.Close()
+
+	get := func(path string, wantStatus int) string {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != wantStatus {
+			t.Errorf("GET %s: got status %d, want %d (%s)", path, resp.StatusCode, wantStatus, body)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("GET %s: got content type %q", path, ct)
+		}
+		return string(body)
+	}
+
+	var regs []registration
+	if err := json.Unmarshal([]byte(get("/traversers?family=stats", http.StatusOK)), &regs); err != nil {
+		t.Fatal(err)
+	}
+	if len(regs) == 0 || regs[0].Family != "stats" || regs[0].Consumes == "" {
+		t.Errorf("Traversers differ: got %v", regs)
+	}
+
+	get("/results/stats/counter?period=monthly", http.StatusNotFound)
+	ran := get("/run/stats/counter?period=monthly&tags=00-Active", http.StatusOK)
+	var tables []struct {
+		Name     string
+		Sections []struct {
+			Rows []map[string]any
+		}
+	}
+	if err := json.Unmarshal([]byte(ran), &tables); err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0].Name != "stats.Counter" || len(tables[0].Sections) != 1 || len(tables[0].Sections[0].Rows) == 0 {
+		t.Errorf("Run differs: got %s", ran)
+	}
+	if _, ok := tables[0].Sections[0].Rows[0]["period"]; !ok {
+		t.Errorf("Run differs: got no period in %v", tables[0].Sections[0].Rows[0])
+	}
+
+	// The result is kept for the same request, whatever the order of its parameters
+	if cached := get("/results/stats/counter?tags=00-Active&period=monthly", http.StatusOK); cached != ran {
+		t.Errorf("Cached result differs: got %s, want %s", cached, ran)
+	}
+	get("/results/stats/counter", http.StatusNotFound)
+	if list := get("/results", http.StatusOK); !strings.Contains(list, `"name":"counter"`) || !strings.Contains(list, `"period":["monthly"]`) {
+		t.Errorf("Results differ: got %s", list)
+	}
+
+	// The options not in the request are the server's, here its period
+	tables = nil
+	if err := json.Unmarshal([]byte(get("/run/stats/counter?tags=00-Active", http.StatusOK)), &tables); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tables[0].Sections[0].Rows[0]["period"]; !ok {
+		t.Errorf("Run differs: got no period in %v", tables[0].Sections[0].Rows[0])
+	}
+
+	get("/run/recons/excel", http.StatusForbidden)
+	get("/run/recons/sql", http.StatusForbidden)
+	get("/run/stats/counter?output=report.csv", http.StatusBadRequest)
+	get("/run/stats/counter?period=fortnightly", http.StatusBadRequest)
+	get("/run/recons/no_such_traverser", http.StatusBadRequest)
+	get("/run/list/counter", http.StatusNotFound)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/colors"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+// clock waits between the passes of a watch. Tests use a fake one.
+type clock interface {
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// watch runs the command, then every --watch interval queries the appearances of each
+// account of interest and, if any appeared in a newer block than at the last query, feeds
+// the traversers the new records of those accounts and rewrites the output. It runs until
+// the context is done. Errors are logged and the watch carries on, unless --fail-fast.
+func watch(ctx context.Context, cmd *command, clk clock, newSource func(traverser.Options) (traverser.Source, error)) error {
+	r, err := newRunner(cmd, newSource)
+	if err != nil {
+		return err
+	}
+
+	// The first pass traverses every account, whether or not it has appearances
+	if _, err := r.poll(); err != nil {
+		return err
+	}
+	for _, run := range r.runs {
+		run.source.news = nil
+	}
+
+	for {
+		if err := r.write(r.pass()); err != nil {
+			if r.opts.FailFast {
+				return err
+			}
+			log.Println("Error:", err)
+		}
+
+		for news := 0; news == 0; {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-clk.After(r.opts.Watch):
+			}
+			if news, err = r.poll(); err != nil {
+				if r.opts.FailFast {
+					return err
+				}
+				log.Println("Error:", err)
+			}
+		}
+	}
+}
+
+// poll queries the appearances of the accounts of interest on each chain, and has the
+// source of the chain deliver the records of only those that appeared in a newer block
+// than at the last poll. It returns how many did. A chain is left as it was if querying
+// one of its accounts fails, so that its news are found again at the next poll.
+func (r *runner) poll() (int, error) {
+	n := 0
+	for _, run := range r.runs {
+		seen := map[base.Address]base.Blknum{}
+		for _, account := range run.opts.Accounts {
+			if !run.opts.IsOfInterest(account.Tags) {
+				continue
+			}
+			apps, err := run.source.Source.Appearances(account)
+			if err != nil {
+				return n, err
+			}
+			last, ok := run.seen[account.Address]
+			for _, app := range apps {
+				if block := base.Blknum(app.BlockNumber); !ok || block > last {
+					last, ok = block, true
+					seen[account.Address] = block
+				}
+			}
+		}
+
+		news := make(map[base.Address]bool, len(seen))
+		for account, block := range seen {
+			run.seen[account] = block
+			news[account] = true
+		}
+		if len(news) > 0 {
+			log.Println(colors.Yellow+"Found new appearances of", len(news), "accounts on", run.opts.Chain, colors.Off)
+		}
+		run.source.news = news
+		n += len(news)
+	}
+	return n, nil
+}
+
+// --------------------------------
+// newsSource delivers the records of the accounts with news, and none for the others.
+// Without news (nil), it delivers the records of every account.
+type newsSource struct {
+	traverser.Source
+	news map[base.Address]bool
+}
+
+func (s *newsSource) quiet(account types.Name) bool {
+	return s.news != nil && !s.news[account.Address]
+}
+
+func (s *newsSource) Statements(account types.Name) ([]*types.Statement, error) {
+	if s.quiet(account) {
+		return nil, nil
+	}
+	return s.Source.Statements(account)
+}
+
+func (s *newsSource) Logs(account types.Name) ([]*types.Log, error) {
+	if s.quiet(account) {
+		return nil, nil
+	}
+	return s.Source.Logs(account)
+}
+
+func (s *newsSource) Transactions(account types.Name) ([]*types.Transaction, error) {
+	if s.quiet(account) {
+		return nil, nil
+	}
+	return s.Source.Transactions(account)
+}
+
+func (s *newsSource) Traces(account types.Name) ([]*types.Trace, error) {
+	if s.quiet(account) {
+		return nil, nil
+	}
+	return s.Source.Traces(account)
+}
+
+func (s *newsSource) Receipts(account types.Name) ([]*types.Receipt, error) {
+	if s.quiet(account) {
+		return nil, nil
+	}
+	return s.Source.Receipts(account)
+}
+
+func (s *newsSource) Appearances(account types.Name) ([]*types.Appearance, error) {
+	if s.quiet(account) {
+		return nil, nil
+	}
+	return s.Source.Appearances(account)
+}
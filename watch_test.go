@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/base"
+	"github.com/TrueBlocks/trueblocks-core/src/apps/chifra/pkg/types"
+	"github.com/TrueBlocks/trueblocks-traversers/pkg/traverser"
+)
+
+// fakeClock hands the channel of each wait to the test, which ticks it when it pleases.
+type fakeClock struct {
+	waits chan chan time.Time
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.waits <- ch
+	return ch
+}
+
+// fakeSource holds the statements and appearances of each account in memory, and counts
+// how many times the statements of each account were fetched. Querying the appearances
+// of an account fails with its error, if it has one.
+type fakeSource struct {
+	traverser.JsonSource
+	statements  map[base.Address][]*types.Statement
+	appearances map[base.Address][]*types.Appearance
+	errs        map[base.Address]error
+	fetched     map[base.Address]int
+}
+
+func (s *fakeSource) add(account base.Address, block base.Blknum) {
+	s.statements[account] = append(s.statements[account], &types.Statement{AccountedFor: account, BlockNumber: block})
+	s.appearances[account] = append(s.appearances[account], &types.Appearance{Address: account, BlockNumber: uint32(block)})
+}
+
+func (s *fakeSource) Statements(account types.Name) ([]*types.Statement, error) {
+	s.fetched[account.Address]++
+	return s.statements[account.Address], nil
+}
+
+func (s *fakeSource) Appearances(account types.Name) ([]*types.Appearance, error) {
+	return s.appearances[account.Address], s.errs[account.Address]
+}
+
+func TestWatch(t *testing.T) {
+	withoutNames(t)
+
+	treasury := base.HexToAddress("0xf503017d7baf7fbc0fff7492b751025c6a78179b")
+	payroll := base.HexToAddress("0x054993ab0f2b1acc0fdc65405ee203b4271bebe6")
+	src := &fakeSource{
+		statements:  map[base.Address][]*types.Statement{},
+		appearances: map[base.Address][]*types.Appearance{},
+		fetched:     map[base.Address]int{},
+	}
+	src.add(treasury, 10)
+	src.add(payroll, 11)
+
+	folder := t.TempDir()
+	output := filepath.Join(folder, "report.csv")
+	cmd, err := parseArgs([]string{"stats", "counter", "--config", filepath.Join("testdata", "config.yaml"), "--output", output, "--watch", "1m", "--nocolor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clk := &fakeClock{waits: make(chan chan time.Time)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- watch(ctx, cmd, clk, func(traverser.Options) (traverser.Source, error) { return src, nil })
+	}()
+
+	// check waits for the watch to be done with a pass and checks what it wrote
+	check := func(step string, want string, fetched int) chan time.Time {
+		t.Helper()
+		tick := <-clk.waits
+		data, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(data)); !strings.HasSuffix(got, "\nmainnet,"+want) {
+			t.Errorf("%s: got report %q, want a count of %s", step, got, want)
+		}
+		if src.fetched[treasury] != fetched || src.fetched[payroll] != 1 {
+			t.Errorf("%s: fetched %d and %d times, want %d and 1", step, src.fetched[treasury], src.fetched[payroll], fetched)
+		}
+		return tick
+	}
+
+	tick := check("first pass", "2", 1)
+
+	// Without new appearances, nothing is fetched and the report stays as it was
+	tick <- time.Now()
+	tick = check("no news", "2", 1)
+
+	// Only the new statement of the account with news is traversed
+	src.add(treasury, 20)
+	tick <- time.Now()
+	tick = check("news", "3", 2)
+
+	// A poll failing after finding news leaves them to the next poll
+	src.add(treasury, 30)
+	src.errs = map[base.Address]error{payroll: errors.New("unreachable")}
+	tick <- time.Now()
+	tick = check("failed poll", "3", 2)
+	src.errs = nil
+	tick <- time.Now()
+	check("news after a failed poll", "4", 3)
+
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("got %d files in the output folder, want only the report", len(entries))
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}